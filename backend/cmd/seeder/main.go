@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"time"
 )
 
@@ -28,23 +29,19 @@ type CreateTaskRequest struct {
 }
 
 func main() {
-	// 1. Login to get token
-	token, err := login("admin@example.com", "admin123")
-	if err != nil {
-		fmt.Printf("Login failed (trying register next): %v\n", err)
-		// Try to register if login fails (first run)
-		err = register("admin@example.com", "admin123", "Admin User")
-		if err != nil {
-			fmt.Printf("Register failed: %v\n", err)
-			return
-		}
-		token, err = login("admin@example.com", "admin123")
+	// 1. Use a write-scoped personal access token if provided, otherwise log in
+	token := os.Getenv("TASKFLOW_TOKEN")
+	if token != "" {
+		fmt.Println("Using personal access token from TASKFLOW_TOKEN")
+	} else {
+		var err error
+		token, err = loginOrRegister("admin@example.com", "admin123", "Admin User")
 		if err != nil {
-			fmt.Printf("Login after register failed: %v\n", err)
+			fmt.Println(err)
 			return
 		}
+		fmt.Println("Successfully logged in/registered")
 	}
-	fmt.Println("Successfully logged in/registered")
 
 	// 2. Create 25 tasks
 	priorities := []string{"low", "medium", "high", "urgent"}
@@ -75,6 +72,24 @@ func main() {
 	fmt.Println("Done seeding tasks!")
 }
 
+func loginOrRegister(email, password, name string) (string, error) {
+	token, err := login(email, password)
+	if err == nil {
+		return token, nil
+	}
+	fmt.Printf("Login failed (trying register next): %v\n", err)
+
+	// Try to register if login fails (first run)
+	if err := register(email, password, name); err != nil {
+		return "", fmt.Errorf("register failed: %w", err)
+	}
+	token, err = login(email, password)
+	if err != nil {
+		return "", fmt.Errorf("login after register failed: %w", err)
+	}
+	return token, nil
+}
+
 func login(email, password string) (string, error) {
 	reqBody, _ := json.Marshal(LoginRequest{Email: email, Password: password})
 	resp, err := http.Post(baseURL+"/auth/login", "application/json", bytes.NewBuffer(reqBody))
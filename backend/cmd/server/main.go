@@ -1,14 +1,22 @@
 package main
 
 import (
+	"context"
 	"log"
+	_ "time/tzdata" // Embed timezone data for user timezone validation
 
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/database"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/eventbus"
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/handlers"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/jwtkeys"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/mailer"
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/middleware"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/oidc"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/push"
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/repository"
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/storage"
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/websocket"
 	"github.com/gin-gonic/gin"
 
@@ -43,6 +51,30 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Load JWT signing keys
+	keys, err := jwtkeys.Load(cfg.JWT)
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+
+	// Initialize outgoing email
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		log.Fatalf("Failed to configure mailer: %v", err)
+	}
+
+	// Initialize mobile push notifications
+	pushSender, err := push.New(cfg.Push)
+	if err != nil {
+		log.Fatalf("Failed to configure push notifications: %v", err)
+	}
+
+	// Initialize file storage for uploads
+	files, err := storage.New(cfg.Storage, cfg.Server.PublicURL)
+	if err != nil {
+		log.Fatalf("Failed to configure storage: %v", err)
+	}
+
 	// Set Gin mode
 	gin.SetMode(cfg.Server.Mode)
 
@@ -59,20 +91,96 @@ func main() {
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(database.DB)
 	taskRepo := repository.NewTaskRepository(database.DB)
+	tokenRepo := repository.NewPersonalAccessTokenRepository(database.DB)
+	identityRepo := repository.NewIdentityRepository(database.DB)
+	accountRepo := repository.NewAccountRepository(database.DB)
+	statsRepo := repository.NewStatsRepository(database.DB)
+	invitationRepo := repository.NewInvitationRepository(database.DB)
+	webhookRepo := repository.NewWebhookRepository(database.DB)
+	outboxRepo := repository.NewOutboxRepository(database.DB)
+	notificationRepo := repository.NewNotificationRepository(database.DB)
+	reminderRepo := repository.NewReminderRepository(database.DB)
+	deviceRepo := repository.NewDeviceRepository(database.DB)
+	digestRepo := repository.NewDigestRepository(database.DB)
+	challengeRepo := repository.NewTwoFactorChallengeRepository(database.DB)
+	presenceRepo := repository.NewPresenceRepository(database.DB)
+
+	// Grant admin to the configured accounts
+	if err := userRepo.PromoteAdmins(cfg.Admin.Emails); err != nil {
+		log.Fatalf("Failed to promote admins: %v", err)
+	}
 
 	// Initialize services
-	authService := services.NewAuthService(userRepo, cfg)
+	invitationService := services.NewInvitationService(invitationRepo, userRepo, mail, cfg)
+	authService := services.NewAuthService(userRepo, challengeRepo, keys, invitationService, files.URL, cfg)
 	taskService := services.NewTaskService(taskRepo, userRepo)
-	userService := services.NewUserService(userRepo)
 
-	// Initialize WebSocket hub
-	hub := websocket.NewHub()
+	// Initialize the event bus shared by every instance and the WebSocket hub
+	bus, err := eventbus.New(cfg.EventBus, database.DB, cfg.GetDSN())
+	if err != nil {
+		log.Fatalf("Failed to configure event bus: %v", err)
+	}
+	if pgBus, ok := bus.(*eventbus.PostgresBus); ok {
+		go pgBus.Listen(context.Background())
+	}
+	hub := websocket.NewHub(cfg.WebSocket, taskService, bus)
 	go hub.Run()
 
+	// With several instances, a user is online if connected to any of them
+	var presence services.PresenceChecker = hub
+	if _, local := bus.(*eventbus.LocalBus); !local {
+		sharedPresence := services.NewSharedPresence(hub, presenceRepo, cfg.EventBus.PresenceInterval)
+		go sharedPresence.Run(context.Background())
+		presence = sharedPresence
+	}
+
+	userService := services.NewUserService(userRepo, mail, files, cfg)
+	tokenService := services.NewPersonalAccessTokenService(tokenRepo)
+	accountService := services.NewAccountService(accountRepo, userRepo, tokenRepo, files)
+	adminService := services.NewAdminService(userRepo, tokenRepo, webhookRepo, statsRepo, hub)
+
+	var oidcProviders []*oidc.Provider
+	for _, providerCfg := range cfg.OIDC.Providers {
+		oidcProviders = append(oidcProviders, oidc.NewProvider(providerCfg, nil))
+	}
+	oidcService := services.NewOIDCService(oidcProviders, identityRepo, userRepo, authService)
+
+	// Deliver queued webhook events in the background
+	webhookService := services.NewWebhookService(webhookRepo, nil, cfg.Webhook)
+	go webhookService.Run(context.Background())
+
+	// Send notifications to connected clients, and to the phones of users who
+	// are offline
+	pushService := services.NewPushService(deviceRepo, userRepo, pushSender, presence, cfg.Push)
+	go pushService.Run(context.Background())
+	notificationService := services.NewNotificationService(notificationRepo, userRepo, hub, pushService)
+
+	// Publish task events recorded with each change to the hub, webhooks and
+	// notification center
+	dispatcher := services.NewOutboxDispatcher(outboxRepo, cfg.Outbox, hub, webhookService, notificationService)
+	go dispatcher.Run(context.Background())
+
+	// Remind users of tasks that are due soon or overdue
+	reminderService := services.NewReminderService(reminderRepo, notificationService, cfg.Reminder)
+	go reminderService.Run(context.Background())
+
+	// Email daily or weekly task digests to users who opted in
+	digestService := services.NewDigestService(digestRepo, userRepo, mail, cfg.Digest)
+	go digestService.Run(context.Background())
+
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService)
 	taskHandler := handlers.NewTaskHandler(taskService, hub)
-	userHandler := handlers.NewUserHandler(userService)
+	userHandler := handlers.NewUserHandler(userService, files.URL)
+	tokenHandler := handlers.NewPersonalAccessTokenHandler(tokenService)
+	oidcHandler := handlers.NewOIDCHandler(oidcService)
+	accountHandler := handlers.NewAccountHandler(accountService)
+	adminHandler := handlers.NewAdminHandler(adminService, files.URL)
+	invitationHandler := handlers.NewInvitationHandler(invitationService, files.URL)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	deviceHandler := handlers.NewDeviceHandler(pushService)
+	outboxHandler := handlers.NewOutboxHandler(dispatcher)
 
 	// Setup router
 	router := gin.Default()
@@ -85,6 +193,14 @@ func main() {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	// Uploaded files stored on the local disk
+	if local, ok := files.(*storage.LocalStorage); ok {
+		router.Static(storage.LocalURLPath, local.Dir())
+	}
+
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
+
 	// Swagger documentation
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -93,16 +209,32 @@ func main() {
 	{
 		// Auth routes (public)
 		auth := v1.Group("/auth")
+		auth.Use(middleware.RateLimitMiddleware(cfg.RateLimit.Auth))
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/refresh", authHandler.RefreshToken)
+			auth.POST("/2fa/verify", authHandler.VerifyTwoFactor)
+			auth.GET("/verify-email", userHandler.VerifyEmail)
+			auth.GET("/invitations/preview", invitationHandler.Preview)
+			auth.GET("/oidc/providers", oidcHandler.Providers)
+			auth.GET("/oidc/:provider/authorize", oidcHandler.Authorize)
+			auth.GET("/oidc/:provider/callback", oidcHandler.Callback)
 		}
 
 		// Protected routes
 		protected := v1.Group("")
-		protected.Use(middleware.AuthMiddleware(cfg))
+		protected.Use(middleware.AuthMiddleware(keys, tokenService, authService), middleware.RateLimitMiddleware(cfg.RateLimit.API))
 		{
+			// Two-factor authentication management
+			twoFactor := protected.Group("/auth/2fa")
+			{
+				twoFactor.POST("/setup", authHandler.SetupTwoFactor)
+				twoFactor.POST("/confirm", authHandler.ConfirmTwoFactor)
+				twoFactor.POST("/disable", authHandler.DisableTwoFactor)
+				twoFactor.POST("/recovery-codes", authHandler.RegenerateRecoveryCodes)
+			}
+
 			// Task routes
 			tasks := protected.Group("/tasks")
 			{
@@ -115,15 +247,87 @@ func main() {
 				tasks.POST("/:id/assign", taskHandler.AssignTask)
 			}
 
+			// Personal access token routes
+			tokens := protected.Group("/tokens")
+			{
+				tokens.GET("", tokenHandler.List)
+				tokens.POST("", tokenHandler.Create)
+				tokens.DELETE("/:id", tokenHandler.Revoke)
+			}
+
+			// Webhook subscriptions and delivery logs
+			webhooks := protected.Group("/webhooks")
+			{
+				webhooks.GET("", webhookHandler.List)
+				webhooks.POST("", webhookHandler.Create)
+				webhooks.DELETE("/:id", webhookHandler.Delete)
+				webhooks.GET("/:id/deliveries", webhookHandler.Deliveries)
+				webhooks.POST("/:id/deliveries/:delivery_id/redeliver", webhookHandler.Redeliver)
+			}
+
+			// Notification center
+			notifications := protected.Group("/notifications")
+			{
+				notifications.GET("", notificationHandler.List)
+				notifications.POST("/read-all", notificationHandler.MarkAllRead)
+				notifications.POST("/:id/read", notificationHandler.MarkRead)
+				notifications.GET("/preferences", notificationHandler.Preferences)
+				notifications.PATCH("/preferences", notificationHandler.UpdatePreferences)
+			}
+
+			// Push notification devices
+			devices := protected.Group("/devices")
+			{
+				devices.POST("", deviceHandler.Register)
+				devices.DELETE("/:token", deviceHandler.Unregister)
+			}
+
+			// Current user profile
+			protected.GET("/me", userHandler.Me)
+			protected.PATCH("/me", userHandler.UpdateMe)
+			protected.PUT("/me/avatar", userHandler.UploadAvatar)
+			protected.DELETE("/me/avatar", userHandler.DeleteAvatar)
+			protected.GET("/me/export", accountHandler.Export)
+			protected.DELETE("/me", accountHandler.Delete)
+
 			// User routes
 			users := protected.Group("/users")
 			{
 				users.GET("", userHandler.List)
 			}
+
+			// Who is online and viewing which task
+			protected.GET("/presence", taskHandler.Presence)
+
+			// Team invitations
+			invitations := protected.Group("/invitations")
+			{
+				invitations.GET("", invitationHandler.List)
+				invitations.POST("", invitationHandler.Create)
+				invitations.POST("/accept", invitationHandler.Accept)
+				invitations.DELETE("/:id", invitationHandler.Revoke)
+			}
+
+			// System administration
+			admin := protected.Group("/admin", middleware.RequireAdmin(adminService))
+			{
+				admin.GET("/users", adminHandler.ListUsers)
+				admin.GET("/users/:id", adminHandler.GetUser)
+				admin.PATCH("/users/:id", adminHandler.UpdateUser)
+				admin.POST("/users/:id/reset-password", adminHandler.ResetPassword)
+				admin.POST("/users/:id/logout", adminHandler.ForceLogout)
+				admin.POST("/users/:id/deactivate", adminHandler.DeactivateUser)
+				admin.POST("/users/:id/reactivate", adminHandler.ReactivateUser)
+				admin.GET("/stats", adminHandler.Stats)
+				admin.GET("/outbox/failed", outboxHandler.ListFailed)
+				admin.POST("/outbox/:id/retry", outboxHandler.Retry)
+			}
 		}
 
-		// WebSocket endpoint (protected)
-		v1.GET("/ws", middleware.AuthMiddleware(cfg), taskHandler.WebSocket)
+		// Real-time endpoints (protected). /events is a Server-Sent Events
+		// fallback for networks that block WebSocket upgrades.
+		v1.GET("/ws", middleware.AuthMiddleware(keys, tokenService, authService), taskHandler.WebSocket)
+		v1.GET("/events", middleware.AuthMiddleware(keys, tokenService, authService), taskHandler.Events)
 	}
 
 	// Start server
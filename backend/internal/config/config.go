@@ -4,22 +4,39 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	CORS     CORSConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	JWT       JWTConfig
+	CORS      CORSConfig
+	RateLimit RateLimitConfig
+	TwoFactor TwoFactorConfig
+	OIDC      OIDCConfig
+	Mail      MailConfig
+	Storage   StorageConfig
+	Admin     AdminConfig
+	Invite    InvitationConfig
+	WebSocket WebSocketConfig
+	EventBus  EventBusConfig
+	Webhook   WebhookConfig
+	Outbox    OutboxConfig
+	Reminder  ReminderConfig
+	Push      PushConfig
+	Digest    DigestConfig
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	Port string
-	Mode string
+	Port      string
+	Mode      string
+	PublicURL string // Base URL used in links sent to users
 }
 
 // DatabaseConfig holds database configuration
@@ -37,6 +54,8 @@ type JWTConfig struct {
 	Secret                 string
 	ExpirationHours        int
 	RefreshExpirationHours int
+	Keys                   string // Asymmetric keys as "kid=path[@verify-until],..."
+	ActiveKeyID            string // Key used to sign new tokens (defaults to the first key)
 }
 
 // CORSConfig holds CORS configuration
@@ -44,6 +63,129 @@ type CORSConfig struct {
 	AllowedOrigins string
 }
 
+// RateLimitConfig holds rate limiting configuration per route group
+type RateLimitConfig struct {
+	Auth RateLimitRule // Public auth endpoints, limited per client IP
+	API  RateLimitRule // Protected endpoints, limited per authenticated user
+}
+
+// RateLimitRule configures a token bucket. A non-positive rate disables limiting.
+type RateLimitRule struct {
+	RequestsPerMinute int
+	Burst             int
+}
+
+// TwoFactorConfig holds TOTP two-factor authentication configuration
+type TwoFactorConfig struct {
+	Issuer           string // Shown by authenticator apps
+	ChallengeMinutes int    // Lifetime of the login challenge token
+}
+
+// StorageConfig holds file storage configuration for uploads
+type StorageConfig struct {
+	Driver         string // "local"
+	LocalDir       string // Directory for the local driver, served under /uploads
+	AvatarMaxBytes int    // Maximum accepted avatar upload size
+}
+
+// AdminConfig holds system administration configuration
+type AdminConfig struct {
+	Emails []string // Users promoted to system admin at startup (lowercased)
+}
+
+// InvitationConfig holds team invitation configuration
+type InvitationConfig struct {
+	URL             string // Link sent by email; the token is appended as a query parameter
+	ExpirationHours int    // Default lifetime of an invitation
+}
+
+// WebSocketConfig holds WebSocket connection keepalive settings
+type WebSocketConfig struct {
+	PingInterval    time.Duration // How often the server pings each client
+	PongTimeout     time.Duration // Connections silent for longer are closed; must exceed PingInterval
+	WriteTimeout    time.Duration // Deadline for writing a single message
+	MaxMessageBytes int64         // Larger inbound messages close the connection
+	BacklogSize     int           // Events kept per user for replay on reconnect
+	BacklogTTL      time.Duration // Backlogs without new events for longer are dropped; 0 keeps them
+	QueueSize       int           // Messages queued per client before it is disconnected as too slow
+}
+
+// WebhookConfig holds outbound webhook delivery settings
+type WebhookConfig struct {
+	Timeout        time.Duration // Deadline for one delivery request
+	MaxAttempts    int           // Attempts before a delivery is marked failed
+	RetryBaseDelay time.Duration // Delay before the first retry; doubles after each failure
+	PollInterval   time.Duration // How often the worker looks for due deliveries
+	AllowPrivate   bool          // Allow URLs on loopback, private and link-local addresses; for development
+}
+
+// OutboxConfig holds task event outbox settings
+type OutboxConfig struct {
+	PollInterval   time.Duration // How often pending events are dispatched
+	Retention      time.Duration // Dispatched events are deleted after this long; 0 keeps them
+	MaxAttempts    int           // Failed attempts before an event is given up; 0 retries forever
+	RetryBaseDelay time.Duration // Delay before retrying a failed event; doubles after each failure
+}
+
+// ReminderConfig holds due date reminder scheduler settings
+type ReminderConfig struct {
+	PollInterval  time.Duration // How often tasks are checked for due date reminders
+	EscalateAfter time.Duration // Overdue urgent tasks are escalated to their creator after this long; 0 disables
+}
+
+// PushConfig holds mobile push notification settings
+type PushConfig struct {
+	Driver          string        // log or expo
+	ExpoURL         string        // Base URL of the Expo push service
+	ExpoAccessToken string        // Only needed when the Expo project enforces push security
+	BatchInterval   time.Duration // Queued notifications are sent together every interval
+	QueueSize       int           // Pushes queued in memory; newer ones are dropped when full
+	ReceiptDelay    time.Duration // How long to wait before checking delivery receipts
+}
+
+// DigestConfig holds email digest scheduler settings
+type DigestConfig struct {
+	PollInterval time.Duration // How often users are checked for a due digest
+	Hour         int           // Local hour of the day from which digests are sent
+}
+
+// EventBusConfig selects how task events reach every backend instance
+type EventBusConfig struct {
+	Driver  string // "local" (single instance) or "postgres" (LISTEN/NOTIFY)
+	Channel string // NOTIFY channel for the postgres driver
+
+	// How often each instance records its connected users for the others
+	// when several instances share the postgres bus
+	PresenceInterval time.Duration
+}
+
+// OIDCConfig holds the configured OpenID Connect identity providers
+type OIDCConfig struct {
+	Providers []OIDCProviderConfig
+}
+
+// OIDCProviderConfig configures login through one OpenID Connect issuer
+type OIDCProviderConfig struct {
+	Name          string
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        string
+	AutoProvision bool // Create users that do not exist yet
+}
+
+// MailConfig holds outgoing email configuration
+type MailConfig struct {
+	Driver       string // log, smtp or file
+	From         string
+	FileDir      string // Directory the file driver writes .eml files to
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	// Load .env file if exists (ignore error if not found)
@@ -51,8 +193,9 @@ func Load() (*Config, error) {
 
 	config := &Config{
 		Server: ServerConfig{
-			Port: getEnv("SERVER_PORT", "8080"),
-			Mode: getEnv("GIN_MODE", "debug"),
+			Port:      getEnv("SERVER_PORT", "8080"),
+			Mode:      getEnv("GIN_MODE", "debug"),
+			PublicURL: getEnv("PUBLIC_URL", "http://localhost:8080"),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -66,15 +209,124 @@ func Load() (*Config, error) {
 			Secret:                 getEnv("JWT_SECRET", ""),
 			ExpirationHours:        getEnvAsInt("JWT_EXPIRATION_HOURS", 24),
 			RefreshExpirationHours: getEnvAsInt("JWT_REFRESH_EXPIRATION_HOURS", 168),
+			Keys:                   getEnv("JWT_KEYS", ""),
+			ActiveKeyID:            getEnv("JWT_ACTIVE_KEY_ID", ""),
 		},
 		CORS: CORSConfig{
 			AllowedOrigins: getEnv("ALLOWED_ORIGINS", "http://localhost:19006,http://localhost:8081"),
 		},
+		RateLimit: RateLimitConfig{
+			Auth: RateLimitRule{
+				RequestsPerMinute: getEnvAsInt("RATE_LIMIT_AUTH_PER_MINUTE", 20),
+				Burst:             getEnvAsInt("RATE_LIMIT_AUTH_BURST", 10),
+			},
+			API: RateLimitRule{
+				RequestsPerMinute: getEnvAsInt("RATE_LIMIT_API_PER_MINUTE", 600),
+				Burst:             getEnvAsInt("RATE_LIMIT_API_BURST", 100),
+			},
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "log"),
+			From:         getEnv("MAIL_FROM", "TaskFlow <no-reply@taskflow.local>"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			FileDir:      getEnv("MAIL_FILE_DIR", "./mail"),
+		},
+		Storage: StorageConfig{
+			Driver:         getEnv("STORAGE_DRIVER", "local"),
+			LocalDir:       getEnv("STORAGE_LOCAL_DIR", "./uploads"),
+			AvatarMaxBytes: getEnvAsInt("AVATAR_MAX_BYTES", 5<<20),
+		},
+		Invite: InvitationConfig{
+			URL:             getEnv("INVITE_URL", "taskflow://invite"),
+			ExpirationHours: getEnvAsInt("INVITE_EXPIRATION_HOURS", 72),
+		},
+		WebSocket: WebSocketConfig{
+			PingInterval:    time.Duration(getEnvAsInt("WS_PING_INTERVAL_SECONDS", 30)) * time.Second,
+			PongTimeout:     time.Duration(getEnvAsInt("WS_PONG_TIMEOUT_SECONDS", 60)) * time.Second,
+			WriteTimeout:    time.Duration(getEnvAsInt("WS_WRITE_TIMEOUT_SECONDS", 10)) * time.Second,
+			MaxMessageBytes: int64(getEnvAsInt("WS_MAX_MESSAGE_BYTES", 4096)),
+			BacklogSize:     getEnvAsInt("WS_BACKLOG_SIZE", 100),
+			BacklogTTL:      time.Duration(getEnvAsInt("WS_BACKLOG_TTL_MINUTES", 60)) * time.Minute,
+			QueueSize:       getEnvAsInt("WS_QUEUE_SIZE", 256),
+		},
+		EventBus: EventBusConfig{
+			Driver:  getEnv("EVENT_BUS_DRIVER", "local"),
+			Channel: getEnv("EVENT_BUS_CHANNEL", "taskflow_events"),
+
+			PresenceInterval: time.Duration(getEnvAsInt("EVENT_BUS_PRESENCE_INTERVAL_SECONDS", 15)) * time.Second,
+		},
+		Webhook: WebhookConfig{
+			Timeout:        time.Duration(getEnvAsInt("WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second,
+			MaxAttempts:    getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 8),
+			RetryBaseDelay: time.Duration(getEnvAsInt("WEBHOOK_RETRY_BASE_SECONDS", 30)) * time.Second,
+			PollInterval:   time.Duration(getEnvAsInt("WEBHOOK_POLL_INTERVAL_SECONDS", 5)) * time.Second,
+			AllowPrivate:   getEnvAsBool("WEBHOOK_ALLOW_PRIVATE", false),
+		},
+		Outbox: OutboxConfig{
+			PollInterval:   time.Duration(getEnvAsInt("OUTBOX_POLL_INTERVAL_MS", 200)) * time.Millisecond,
+			Retention:      time.Duration(getEnvAsInt("OUTBOX_RETENTION_HOURS", 72)) * time.Hour,
+			MaxAttempts:    getEnvAsInt("OUTBOX_MAX_ATTEMPTS", 20),
+			RetryBaseDelay: time.Duration(getEnvAsInt("OUTBOX_RETRY_BASE_MS", 1000)) * time.Millisecond,
+		},
+		Push: PushConfig{
+			Driver:          getEnv("PUSH_DRIVER", "log"),
+			ExpoURL:         getEnv("EXPO_PUSH_URL", "https://exp.host"),
+			ExpoAccessToken: getEnv("EXPO_ACCESS_TOKEN", ""),
+			BatchInterval:   time.Duration(getEnvAsInt("PUSH_BATCH_INTERVAL_MS", 1000)) * time.Millisecond,
+			QueueSize:       getEnvAsInt("PUSH_QUEUE_SIZE", 10000),
+			ReceiptDelay:    time.Duration(getEnvAsInt("PUSH_RECEIPT_DELAY_MINUTES", 15)) * time.Minute,
+		},
+		Reminder: ReminderConfig{
+			PollInterval:  time.Duration(getEnvAsInt("REMINDER_POLL_INTERVAL_SECONDS", 60)) * time.Second,
+			EscalateAfter: time.Duration(getEnvAsInt("REMINDER_ESCALATION_HOURS", 24)) * time.Hour,
+		},
+		Digest: DigestConfig{
+			PollInterval: time.Duration(getEnvAsInt("DIGEST_POLL_INTERVAL_MINUTES", 15)) * time.Minute,
+			Hour:         getEnvAsInt("DIGEST_HOUR", 7),
+		},
+		TwoFactor: TwoFactorConfig{
+			Issuer:           getEnv("TOTP_ISSUER", "TaskFlow"),
+			ChallengeMinutes: getEnvAsInt("TOTP_CHALLENGE_MINUTES", 5),
+		},
+	}
+
+	config.OIDC = loadOIDCConfig()
+
+	for _, email := range strings.Split(getEnv("ADMIN_EMAILS", ""), ",") {
+		if email = strings.TrimSpace(strings.ToLower(email)); email != "" {
+			config.Admin.Emails = append(config.Admin.Emails, email)
+		}
 	}
 
 	return config, nil
 }
 
+// loadOIDCConfig reads providers listed in OIDC_PROVIDERS, each configured with
+// OIDC_<NAME>_* variables
+func loadOIDCConfig() OIDCConfig {
+	var oidc OIDCConfig
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		oidc.Providers = append(oidc.Providers, OIDCProviderConfig{
+			Name:          name,
+			Issuer:        getEnv(prefix+"ISSUER", ""),
+			ClientID:      getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret:  getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:   getEnv(prefix+"REDIRECT_URL", ""),
+			Scopes:        getEnv(prefix+"SCOPES", "openid email profile"),
+			AutoProvision: getEnvAsBool(prefix+"AUTO_PROVISION", true),
+		})
+	}
+	return oidc
+}
+
 // GetDSN returns the database connection string
 func (c *Config) GetDSN() string {
 	return fmt.Sprintf(
@@ -96,6 +348,14 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
 func getEnvAsInt(key string, defaultValue int) int {
 	valueStr := getEnv(key, "")
 	if value, err := strconv.Atoi(valueStr); err == nil {
@@ -36,6 +36,20 @@ func Migrate() error {
 	err := DB.AutoMigrate(
 		&models.User{},
 		&models.Task{},
+		&models.PersonalAccessToken{},
+		&models.UserIdentity{},
+		&models.OIDCAuthRequest{},
+		&models.Invitation{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.OutboxEvent{},
+		&models.Notification{},
+		&models.NotificationPreferences{},
+		&models.TaskReminder{},
+		&models.DeviceToken{},
+		&models.DigestDelivery{},
+		&models.TwoFactorChallenge{},
+		&models.UserPresence{},
 	)
 	if err != nil {
 		return fmt.Errorf("migration failed: %w", err)
@@ -0,0 +1,73 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Envelope is a task event together with who should receive it. Sequence is
+// the event's position in the global order assigned where the event was
+// recorded (the outbox ID), so every instance gives it the same ID; it is 0
+// for events outside that order, such as notifications.
+type Envelope struct {
+	Sequence   uint64           `json:"sequence,omitempty"`
+	Recipients []uuid.UUID      `json:"recipients"`
+	Channels   []string         `json:"channels"`
+	Event      models.TaskEvent `json:"event"`
+}
+
+// Handler receives every envelope published on the bus
+type Handler func(Envelope)
+
+// Bus fans out task events to every backend instance, including the one that
+// published them
+type Bus interface {
+	Publish(ctx context.Context, envelope Envelope) error
+	Subscribe(handler Handler)
+}
+
+// New creates the bus selected by configuration. The postgres driver needs
+// the database connection to publish and its DSN to listen.
+func New(cfg config.EventBusConfig, db *gorm.DB, dsn string) (Bus, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocalBus(), nil
+	case "postgres":
+		return NewPostgresBus(db, dsn, cfg.Channel), nil
+	}
+	return nil, fmt.Errorf("unknown event bus driver %q", cfg.Driver)
+}
+
+// LocalBus delivers events within the process. Suitable for a single instance.
+type LocalBus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+// NewLocalBus creates an in-process bus
+func NewLocalBus() *LocalBus {
+	return &LocalBus{}
+}
+
+// Publish hands the envelope to every subscriber synchronously
+func (b *LocalBus) Publish(ctx context.Context, envelope Envelope) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		handler(envelope)
+	}
+	return nil
+}
+
+// Subscribe registers a handler for published envelopes
+func (b *LocalBus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
@@ -0,0 +1,121 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// maxNotifyPayload stays below PostgreSQL's 8000 byte NOTIFY payload limit
+const maxNotifyPayload = 7900
+
+// PostgresBus fans out events to every instance through PostgreSQL
+// LISTEN/NOTIFY. Each instance publishes with NOTIFY and receives all
+// notifications, its own included, on a dedicated listening connection.
+// Notifications sent while the listener is reconnecting are lost.
+type PostgresBus struct {
+	db      *gorm.DB
+	dsn     string
+	channel string
+
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+// NewPostgresBus creates a LISTEN/NOTIFY bus. Call Listen to start receiving.
+func NewPostgresBus(db *gorm.DB, dsn, channel string) *PostgresBus {
+	return &PostgresBus{db: db, dsn: dsn, channel: channel}
+}
+
+// Publish sends the envelope with NOTIFY. Envelopes too large for a
+// notification are sent without the task payload; clients still learn which
+// task changed.
+func (b *PostgresBus) Publish(ctx context.Context, envelope Envelope) error {
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayload && envelope.Event.Task != nil {
+		envelope.Event.Task = nil
+		if payload, err = json.Marshal(envelope); err != nil {
+			return err
+		}
+	}
+	return b.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", b.channel, string(payload)).Error
+}
+
+// Subscribe registers a handler for received envelopes
+func (b *PostgresBus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Listen receives notifications until ctx is done, reconnecting with backoff
+// when the connection drops
+func (b *PostgresBus) Listen(ctx context.Context) {
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// A connection that held for a while starts the backoff over
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		log.Printf("Event bus listener failed, retrying in %s: %v", backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// listen holds one listening connection until it fails
+func (b *PostgresBus) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, b.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.channel}.Sanitize()); err != nil {
+		return err
+	}
+	log.Printf("Listening for events on channel %s", b.channel)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var envelope Envelope
+		if err := json.Unmarshal([]byte(notification.Payload), &envelope); err != nil {
+			log.Printf("Discarding malformed event: %v", err)
+			continue
+		}
+		b.dispatch(envelope)
+	}
+}
+
+// dispatch hands a received envelope to every subscriber
+func (b *PostgresBus) dispatch(envelope Envelope) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		handler(envelope)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/middleware"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// AccountHandler handles data export and account deletion
+type AccountHandler struct {
+	accountService *services.AccountService
+}
+
+// NewAccountHandler creates a new account handler
+func NewAccountHandler(accountService *services.AccountService) *AccountHandler {
+	return &AccountHandler{accountService: accountService}
+}
+
+// Export downloads the current user's data
+// @Summary Export account data
+// @Description Download a JSON archive of the profile, tasks, tokens, linked identities, webhooks (without secrets), notifications, notification preferences, sent invitations and devices of the current user
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.AccountExport
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/me/export [get]
+func (h *AccountHandler) Export(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	export, err := h.accountService.Export(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("taskflow-export-%s.json", export.ExportedAt.Format("2006-01-02"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.JSON(http.StatusOK, export)
+}
+
+// Delete deletes the current user's account
+// @Summary Delete account
+// @Description Erase the current user's personal data and sign them out everywhere. Created tasks are reassigned or kept under an anonymized placeholder.
+// @Tags users
+// @Accept json
+// @Security BearerAuth
+// @Param request body services.DeleteAccountRequest true "Confirmation and task handling"
+// @Success 204
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/me [delete]
+func (h *AccountHandler) Delete(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if middleware.IsPersonalAccessToken(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Accounts cannot be deleted with a personal access token"})
+		return
+	}
+
+	var req services.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.accountService.DeleteAccount(c.Request.Context(), userID, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/middleware"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AdminHandler handles system administration endpoints
+type AdminHandler struct {
+	adminService *services.AdminService
+	avatarURL    models.AvatarURLResolver
+}
+
+// NewAdminHandler creates a new admin handler. AvatarURL resolves the
+// avatar URLs of returned users.
+func NewAdminHandler(adminService *services.AdminService, avatarURL models.AvatarURLResolver) *AdminHandler {
+	return &AdminHandler{
+		adminService: adminService,
+		avatarURL:    avatarURL,
+	}
+}
+
+// DeactivateUser deactivates a user account
+// @Summary Deactivate user
+// @Description Block a user from signing in, invalidate their sessions and disable their webhooks. Their tasks are kept.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/users/{id}/deactivate [post]
+func (h *AdminHandler) DeactivateUser(c *gin.Context) {
+	actorID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.adminService.DeactivateUser(actorID, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse(h.avatarURL))
+}
+
+// ReactivateUser reactivates a deactivated user account
+// @Summary Reactivate user
+// @Description Allow a deactivated user to sign in again
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/users/{id}/reactivate [post]
+func (h *AdminHandler) ReactivateUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.adminService.ReactivateUser(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse(h.avatarURL))
+}
+
+// ListUsers lists all users
+// @Summary List users (admin)
+// @Description Search all users, including deactivated accounts
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param q query string false "Name or email prefix"
+// @Param status query string false "active, deactivated or all" default(all)
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/users [get]
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	filter := models.UserFilter{
+		Query:              c.Query("q"),
+		IncludeDeactivated: true,
+		Page:               1,
+		PageSize:           20,
+	}
+	switch c.Query("status") {
+	case "", "all":
+	case "active":
+		filter.IncludeDeactivated = false
+	case "deactivated":
+		filter.OnlyDeactivated = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be active, deactivated or all"})
+		return
+	}
+	if page := c.Query("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil {
+			filter.Page = p
+		}
+	}
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		if ps, err := strconv.Atoi(pageSize); err == nil {
+			filter.PageSize = ps
+		}
+	}
+
+	users, total, err := h.adminService.ListUsers(&filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]models.UserResponse, 0, len(users))
+	for i := range users {
+		response = append(response, users[i].ToResponse(h.avatarURL))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":     response,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// GetUser gets a user
+// @Summary Get user (admin)
+// @Description Get any user by ID
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} models.UserResponse
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/users/{id} [get]
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.adminService.GetUser(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse(h.avatarURL))
+}
+
+// UpdateUser updates a user's admin flag
+// @Summary Update user (admin)
+// @Description Grant or revoke system admin access
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body services.UpdateUserRequest true "Admin flag"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/users/{id} [patch]
+func (h *AdminHandler) UpdateUser(c *gin.Context) {
+	actorID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req services.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.adminService.UpdateUser(actorID, userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse(h.avatarURL))
+}
+
+// ResetPassword resets a user's password
+// @Summary Reset password (admin)
+// @Description Replace the user's password with a temporary one, returned once, and sign them out everywhere
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} services.ResetPasswordResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/users/{id}/reset-password [post]
+func (h *AdminHandler) ResetPassword(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	response, err := h.adminService.ResetPassword(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ForceLogout signs a user out everywhere
+// @Summary Force logout (admin)
+// @Description Invalidate the user's access and refresh tokens, revoke their personal access tokens and close their WebSocket connections
+// @Tags admin
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/users/{id}/logout [post]
+func (h *AdminHandler) ForceLogout(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.adminService.ForceLogout(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Stats returns system statistics
+// @Summary System stats (admin)
+// @Description Counts of users, tasks by status and connected WebSocket clients
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SystemStats
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/stats [get]
+func (h *AdminHandler) Stats(c *gin.Context) {
+	stats, err := h.adminService.Stats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
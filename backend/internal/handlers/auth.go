@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
@@ -46,7 +47,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 // Login handles user login
 // @Summary Login
-// @Description Authenticate user and return JWT tokens
+// @Description Authenticate user and return JWT tokens. After an admin password reset, new_password must be sent along with the temporary password.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -54,6 +55,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // @Success 200 {object} services.AuthResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Router /api/v1/auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req services.LoginRequest
@@ -63,6 +65,10 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	response, err := h.authService.Login(req)
+	if errors.Is(err, services.ErrPasswordChangeRequired) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "password_change_required": true})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
@@ -100,3 +106,15 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"token": token})
 }
+
+// JWKS publishes the public signing keys
+// @Summary JSON Web Key Set
+// @Description Public keys used to verify TaskFlow access tokens, identified by kid
+// @Tags auth
+// @Produce json
+// @Success 200 {object} jwtkeys.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.JWKS())
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/middleware"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceHandler handles push device registration endpoints
+type DeviceHandler struct {
+	pushService *services.PushService
+}
+
+// NewDeviceHandler creates a new device handler
+func NewDeviceHandler(pushService *services.PushService) *DeviceHandler {
+	return &DeviceHandler{pushService: pushService}
+}
+
+// Register registers a device for push notifications
+// @Summary Register push device
+// @Description Save the Expo push token of the current device. A token registered by another account moves to the current user.
+// @Tags devices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.RegisterDeviceRequest true "Device token"
+// @Success 201 {object} models.DeviceToken
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/devices [post]
+func (h *DeviceHandler) Register(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req services.RegisterDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	device, err := h.pushService.RegisterDevice(userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, device)
+}
+
+// Unregister removes a device's push token
+// @Summary Unregister push device
+// @Description Stop sending push notifications to a device, e.g. on logout
+// @Tags devices
+// @Security BearerAuth
+// @Param token path string true "Expo push token"
+// @Success 204
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/devices/{token} [delete]
+func (h *DeviceHandler) Unregister(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.pushService.UnregisterDevice(userID, c.Param("token")); err != nil {
+		if errors.Is(err, services.ErrDeviceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/middleware"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// InvitationHandler handles team invitation endpoints
+type InvitationHandler struct {
+	invitationService *services.InvitationService
+	avatarURL         models.AvatarURLResolver
+}
+
+// NewInvitationHandler creates a new invitation handler. AvatarURL resolves the
+// avatar URLs of returned users.
+func NewInvitationHandler(invitationService *services.InvitationService, avatarURL models.AvatarURLResolver) *InvitationHandler {
+	return &InvitationHandler{
+		invitationService: invitationService,
+		avatarURL:         avatarURL,
+	}
+}
+
+// AcceptInvitationRequest represents a request to accept an invitation
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// Create invites someone by email
+// @Summary Create invitation
+// @Description Email a single-use invitation link. Only admins can invite with the admin role. If the email cannot be sent, no invitation is created and 502 is returned.
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.CreateInvitationRequest true "Invitation"
+// @Success 201 {object} models.InvitationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Router /api/v1/invitations [post]
+func (h *InvitationHandler) Create(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req services.CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitation, err := h.invitationService.Create(c.Request.Context(), userID, req)
+	if errors.Is(err, services.ErrInvitationNotSent) {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation.ToResponse())
+}
+
+// List lists the invitations sent by the current user
+// @Summary List invitations
+// @Description List invitations sent by the current user with their status
+// @Tags invitations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.InvitationResponse
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/invitations [get]
+func (h *InvitationHandler) List(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	invitations, err := h.invitationService.List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]models.InvitationResponse, 0, len(invitations))
+	for i := range invitations {
+		response = append(response, invitations[i].ToResponse())
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Revoke revokes a pending invitation
+// @Summary Revoke invitation
+// @Description Revoke a pending invitation sent by the current user (admins can revoke any)
+// @Tags invitations
+// @Security BearerAuth
+// @Param id path string true "Invitation ID"
+// @Success 204
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/invitations/{id} [delete]
+func (h *InvitationHandler) Revoke(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	invitationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	if err := h.invitationService.Revoke(userID, invitationID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Accept accepts an invitation as the current user
+// @Summary Accept invitation
+// @Description Redeem an invitation sent to the current user's email and receive its role
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body AcceptInvitationRequest true "Invitation token"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/invitations/accept [post]
+func (h *InvitationHandler) Accept(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.invitationService.Accept(userID, req.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse(h.avatarURL))
+}
+
+// Preview shows a pending invitation before signing up
+// @Summary Preview invitation
+// @Description Get the email, role and inviter of a pending invitation to prefill registration
+// @Tags invitations
+// @Produce json
+// @Param token query string true "Invitation token"
+// @Success 200 {object} services.InvitationPreview
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/auth/invitations/preview [get]
+func (h *InvitationHandler) Preview(c *gin.Context) {
+	preview, err := h.invitationService.Preview(c.Query("token"))
+	if errors.Is(err, services.ErrInvalidInvitation) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
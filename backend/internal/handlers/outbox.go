@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// OutboxHandler lets administrators inspect and retry the task events the
+// outbox dispatcher gave up on
+type OutboxHandler struct {
+	dispatcher *services.OutboxDispatcher
+}
+
+// NewOutboxHandler creates a new outbox handler
+func NewOutboxHandler(dispatcher *services.OutboxDispatcher) *OutboxHandler {
+	return &OutboxHandler{dispatcher: dispatcher}
+}
+
+// ListFailed lists failed outbox events
+// @Summary List failed outbox events (admin)
+// @Description Task events that could not be published after OUTBOX_MAX_ATTEMPTS attempts, oldest first, with the last error
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.OutboxEvent
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/admin/outbox/failed [get]
+func (h *OutboxHandler) ListFailed(c *gin.Context) {
+	events, err := h.dispatcher.FailedEvents()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// Retry queues a failed outbox event again
+// @Summary Retry failed outbox event (admin)
+// @Description Put a failed task event back in the outbox with its attempts reset. Sinks that already handled it are skipped.
+// @Tags admin
+// @Security BearerAuth
+// @Param id path int true "Outbox event ID"
+// @Success 204
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/outbox/{id}/retry [post]
+func (h *OutboxHandler) Retry(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	if err := h.dispatcher.RetryFailed(id); err != nil {
+		if errors.Is(err, services.ErrOutboxEventNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/middleware"
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
@@ -28,7 +30,8 @@ type TaskHandler struct {
 	hub         *ws.Hub
 }
 
-// NewTaskHandler creates a new task handler
+// NewTaskHandler creates a new task handler. Task events reach the hub through
+// the outbox, not from here.
 func NewTaskHandler(taskService *services.TaskService, hub *ws.Hub) *TaskHandler {
 	return &TaskHandler{
 		taskService: taskService,
@@ -67,14 +70,6 @@ func (h *TaskHandler) Create(c *gin.Context) {
 		return
 	}
 
-	// Broadcast task created event
-	h.hub.BroadcastTaskEvent(models.TaskEvent{
-		Type:   "created",
-		TaskID: task.ID,
-		Task:   task,
-		UserID: userID,
-	})
-
 	c.JSON(http.StatusCreated, task)
 }
 
@@ -209,14 +204,6 @@ func (h *TaskHandler) Update(c *gin.Context) {
 		return
 	}
 
-	// Broadcast task updated event
-	h.hub.BroadcastTaskEvent(models.TaskEvent{
-		Type:   "updated",
-		TaskID: task.ID,
-		Task:   task,
-		UserID: userID,
-	})
-
 	c.JSON(http.StatusOK, task)
 }
 
@@ -251,13 +238,6 @@ func (h *TaskHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	// Broadcast task deleted event
-	h.hub.BroadcastTaskEvent(models.TaskEvent{
-		Type:   "deleted",
-		TaskID: taskID,
-		UserID: userID,
-	})
-
 	c.Status(http.StatusNoContent)
 }
 
@@ -300,14 +280,6 @@ func (h *TaskHandler) UpdateStatus(c *gin.Context) {
 		return
 	}
 
-	// Broadcast status update event
-	h.hub.BroadcastTaskEvent(models.TaskEvent{
-		Type:   "updated",
-		TaskID: task.ID,
-		Task:   task,
-		UserID: userID,
-	})
-
 	c.JSON(http.StatusOK, task)
 }
 
@@ -356,14 +328,6 @@ func (h *TaskHandler) AssignTask(c *gin.Context) {
 		return
 	}
 
-	// Broadcast assignment event
-	h.hub.BroadcastTaskEvent(models.TaskEvent{
-		Type:   "assigned",
-		TaskID: task.ID,
-		Task:   task,
-		UserID: userID,
-	})
-
 	c.JSON(http.StatusOK, task)
 }
 
@@ -372,6 +336,7 @@ func (h *TaskHandler) AssignTask(c *gin.Context) {
 // @Description Establish WebSocket connection for real-time updates
 // @Tags websocket
 // @Security BearerAuth
+// @Param last_event_id query int false "ID of the last event received, to replay missed events"
 // @Router /ws [get]
 func (h *TaskHandler) WebSocket(c *gin.Context) {
 	userID, err := middleware.GetUserID(c)
@@ -386,12 +351,9 @@ func (h *TaskHandler) WebSocket(c *gin.Context) {
 		return
 	}
 
-	client := &ws.Client{
-		ID:     uuid.New(),
-		UserID: userID,
-		Conn:   conn,
-		Send:   make(chan []byte, 256),
-		Hub:    h.hub,
+	client := ws.NewClient(h.hub, userID, conn)
+	if lastEventID, err := strconv.ParseUint(c.Query("last_event_id"), 10, 64); err == nil {
+		client.LastEventID = &lastEventID
 	}
 
 	h.hub.Register <- client
@@ -400,3 +362,78 @@ func (h *TaskHandler) WebSocket(c *gin.Context) {
 	go client.WritePump()
 	go client.ReadPump()
 }
+
+// Events streams task events as Server-Sent Events
+// @Summary Task event stream
+// @Description Server-Sent Events fallback for clients that cannot use WebSockets. Streams the same payloads as /ws.
+// @Tags websocket
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param channels query string false "Comma-separated channels to follow (task:<id>, assignments)"
+// @Param last_event_id query int false "ID of the last event received; the Last-Event-ID header takes precedence"
+// @Success 200 {string} string "event stream"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/events [get]
+func (h *TaskHandler) Events(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	client := ws.NewClient(h.hub, userID, nil)
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	if id, err := strconv.ParseUint(lastEventID, 10, 64); err == nil {
+		client.LastEventID = &id
+	}
+
+	for _, channel := range strings.Split(c.Query("channels"), ",") {
+		if channel = strings.TrimSpace(channel); channel == "" {
+			continue
+		}
+		if err := client.Subscribe(channel); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, ws.ErrForbiddenChannel) {
+				status = http.StatusForbidden
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	h.hub.Register <- client
+	defer func() { h.hub.Unregister <- client }()
+
+	client.ServeSSE(c.Request.Context(), c.Writer, c.Writer.Flush)
+}
+
+// Presence returns who is online and who is viewing each task
+// @Summary Presence snapshot
+// @Description Users sharing a task with the caller who are connected to this instance and, for the tasks the caller can see, who is viewing them. partial is true when other instances share the event bus, since their clients are not counted. Live changes arrive over /ws.
+// @Tags websocket
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} ws.PresenceSnapshot
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/presence [get]
+func (h *TaskHandler) Presence(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.hub.Presence(userID))
+}
@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/middleware"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
 	"github.com/gin-gonic/gin"
 )
@@ -10,41 +14,227 @@ import (
 // UserHandler handles user endpoints
 type UserHandler struct {
 	userService *services.UserService
+	avatarURL   models.AvatarURLResolver
 }
 
-// NewUserHandler creates a new user handler
-func NewUserHandler(userService *services.UserService) *UserHandler {
+// NewUserHandler creates a new user handler. AvatarURL resolves the
+// avatar URLs of returned users.
+func NewUserHandler(userService *services.UserService, avatarURL models.AvatarURLResolver) *UserHandler {
 	return &UserHandler{
 		userService: userService,
+		avatarURL:   avatarURL,
 	}
 }
 
 // List lists users
 // @Summary List users
-// @Description Get a list of potential assignees
+// @Description Get a paginated list of potential assignees, optionally searched by name or email prefix. Every active user is listed, not only those sharing tasks with the caller.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {object} []map[string]interface{}
+// @Param q query string false "Name or email prefix"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
 // @Router /api/v1/users [get]
 func (h *UserHandler) List(c *gin.Context) {
-	users, err := h.userService.List()
+	filter := models.UserFilter{
+		Query:    c.Query("q"),
+		Page:     1,
+		PageSize: 20,
+	}
+	if page := c.Query("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil {
+			filter.Page = p
+		}
+	}
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		if ps, err := strconv.Atoi(pageSize); err == nil {
+			filter.PageSize = ps
+		}
+	}
+
+	users, total, err := h.userService.List(&filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Filter sensitive data
-	var response []map[string]interface{}
-	for _, u := range users {
-		response = append(response, map[string]interface{}{
-			"id":    u.ID,
-			"name":  u.Name,
-			"email": u.Email,
-		})
+	// Only expose the public summary of other users
+	summaries := make([]models.UserSummary, 0, len(users))
+	for i := range users {
+		summaries = append(summaries, users[i].ToSummary(h.avatarURL))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":     summaries,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// Me gets the current user's profile
+// @Summary Get current user
+// @Description Get the profile of the authenticated user
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.UserResponse
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/me [get]
+func (h *UserHandler) Me(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	user, err := h.userService.GetProfile(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse(h.avatarURL))
+}
+
+// UpdateMe updates the current user's profile
+// @Summary Update current user
+// @Description Update name, email (requires verification), password, timezone and locale
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.UpdateProfileRequest true "Profile update"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/me [patch]
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req services.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userService.UpdateProfile(c.Request.Context(), userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse(h.avatarURL))
+}
+
+// VerifyEmail confirms a pending email change
+// @Summary Verify email change
+// @Description Confirm a new email address with the token sent to it
+// @Tags users
+// @Produce json
+// @Param token query string true "Verification token"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/auth/verify-email [get]
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	user, err := h.userService.VerifyEmail(token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse(h.avatarURL))
+}
+
+// UploadAvatar sets the current user's avatar
+// @Summary Upload avatar
+// @Description Upload a JPEG, PNG or GIF avatar. It is cropped to a square and stored in several sizes.
+// @Tags users
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param avatar formData file true "Avatar image"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 413 {object} map[string]interface{}
+// @Router /api/v1/me/avatar [put]
+func (h *UserHandler) UploadAvatar(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.userService.AvatarRequestLimit())
+	file, err := c.FormFile("avatar")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrAvatarTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "avatar file is required"})
+		return
+	}
+	upload, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer upload.Close()
+
+	user, err := h.userService.UpdateAvatar(c.Request.Context(), userID, upload)
+	switch {
+	case errors.Is(err, services.ErrAvatarTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrUnsupportedImage), errors.Is(err, services.ErrAvatarDimensions):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse(h.avatarURL))
+}
+
+// DeleteAvatar removes the current user's avatar
+// @Summary Delete avatar
+// @Description Remove the current user's avatar
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.UserResponse
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/me/avatar [delete]
+func (h *UserHandler) DeleteAvatar(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	user, err := h.userService.DeleteAvatar(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, user.ToResponse(h.avatarURL))
 }
@@ -0,0 +1,250 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a signing or verification key identified by its kid
+type Key struct {
+	ID          string
+	Method      jwt.SigningMethod
+	Private     crypto.Signer // nil for verification-only keys
+	Public      crypto.PublicKey
+	VerifyUntil *time.Time // End of the grace period for retired keys
+}
+
+// KeySet signs tokens with the active key and verifies tokens signed by any
+// known key. Tokens without a kid are verified with the legacy HS256 secret
+// when one is configured.
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+	secret []byte
+}
+
+// ErrUnknownKey is returned when a token references a key that is not in the set
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// NewHMAC creates a key set that signs and verifies with an HS256 secret
+func NewHMAC(secret string) *KeySet {
+	return &KeySet{
+		keys:   make(map[string]*Key),
+		secret: []byte(secret),
+	}
+}
+
+// Load builds the key set from configuration. Without configured keys it falls
+// back to HS256 with the JWT secret.
+func Load(cfg config.JWTConfig) (*KeySet, error) {
+	set := &KeySet{keys: make(map[string]*Key)}
+	if cfg.Secret != "" {
+		set.secret = []byte(cfg.Secret)
+	}
+
+	if strings.TrimSpace(cfg.Keys) == "" {
+		if set.secret == nil {
+			return nil, errors.New("either JWT_SECRET or JWT_KEYS must be set")
+		}
+		return set, nil
+	}
+
+	for _, entry := range strings.Split(cfg.Keys, ",") {
+		key, err := parseKeyEntry(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := set.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate JWT key id %q", key.ID)
+		}
+		set.keys[key.ID] = key
+		if set.active == nil && cfg.ActiveKeyID == "" {
+			set.active = key
+		}
+	}
+
+	if cfg.ActiveKeyID != "" {
+		set.active = set.keys[cfg.ActiveKeyID]
+		if set.active == nil {
+			return nil, fmt.Errorf("active JWT key %q is not configured", cfg.ActiveKeyID)
+		}
+	}
+	if set.active.Private == nil {
+		return nil, fmt.Errorf("active JWT key %q has no private key", set.active.ID)
+	}
+	if set.active.VerifyUntil != nil {
+		return nil, fmt.Errorf("active JWT key %q cannot be retired", set.active.ID)
+	}
+
+	return set, nil
+}
+
+// Sign signs claims with the active key, setting the kid header
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if s.active == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	}
+
+	token := jwt.NewWithClaims(s.active.Method, claims)
+	token.Header["kid"] = s.active.ID
+	return token.SignedString(s.active.Private)
+}
+
+// Parse verifies a token against the key named by its kid header
+func (s *KeySet) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, s.keyFunc)
+}
+
+func (s *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if s.secret == nil || token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+			return nil, ErrUnknownKey
+		}
+		return s.secret, nil
+	}
+
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	if key.VerifyUntil != nil && time.Now().After(*key.VerifyUntil) {
+		return nil, ErrUnknownKey
+	}
+	return key.Public, nil
+}
+
+// JWK is a public JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set document
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys that currently verify tokens. The HS256 secret
+// is never published.
+func (s *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	now := time.Now()
+
+	for _, key := range s.keys {
+		if key.VerifyUntil != nil && now.After(*key.VerifyUntil) {
+			continue
+		}
+
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+	return jwks
+}
+
+// parseKeyEntry parses "kid=path" or "kid=path@RFC3339" where the optional time
+// ends the verification grace period of a retired key
+func parseKeyEntry(entry string) (*Key, error) {
+	kid, rest, ok := strings.Cut(entry, "=")
+	if !ok || kid == "" || rest == "" {
+		return nil, fmt.Errorf("invalid JWT key entry %q, expected kid=path", entry)
+	}
+
+	path := rest
+	var verifyUntil *time.Time
+	if p, until, hasUntil := strings.Cut(rest, "@"); hasUntil {
+		parsed, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return nil, fmt.Errorf("invalid grace period for JWT key %q: %w", kid, err)
+		}
+		path = p
+		verifyUntil = &parsed
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key %q: %w", kid, err)
+	}
+
+	key, err := parsePEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT key %q: %w", kid, err)
+	}
+	key.ID = kid
+	key.VerifyUntil = verifyUntil
+	return key, nil
+}
+
+// parsePEM parses an RSA or Ed25519 private key, or a public key for
+// verification-only entries
+func parsePEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &Key{Method: jwt.SigningMethodRS256, Private: k, Public: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &Key{Method: jwt.SigningMethodEdDSA, Private: k, Public: k.Public()}, nil
+	case *rsa.PublicKey:
+		return &Key{Method: jwt.SigningMethodRS256, Public: k}, nil
+	case ed25519.PublicKey:
+		return &Key{Method: jwt.SigningMethodEdDSA, Public: k}, nil
+	}
+	return nil, errors.New("unsupported key type, expected RSA or Ed25519")
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AdminChecker reports whether a user is a system administrator
+type AdminChecker interface {
+	IsAdmin(userID uuid.UUID) (bool, error)
+}
+
+// RequireAdmin restricts a route group to system administrators signed in
+// interactively; personal access tokens are rejected. It must run after
+// AuthMiddleware.
+func RequireAdmin(admins AdminChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+		if IsPersonalAccessToken(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin routes cannot be used with a personal access token"})
+			c.Abort()
+			return
+		}
+
+		isAdmin, err := admins.IsAdmin(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		if !isAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
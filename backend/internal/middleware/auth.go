@@ -3,22 +3,47 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 
-	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/jwtkeys"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+// Token types carried in the token_type claim. Tokens issued before the claim
+// existed have an empty type and are treated as access or refresh tokens.
+const (
+	TokenTypeAccess             = "access"
+	TokenTypeRefresh            = "refresh"
+	TokenTypeTwoFactorChallenge = "2fa_challenge"
+)
+
 // Claims represents JWT claims
 type Claims struct {
-	UserID uuid.UUID `json:"user_id"`
-	Email  string    `json:"email"`
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	TokenType string    `json:"token_type,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// AuthMiddleware validates JWT tokens
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+// PersonalAccessTokenAuthenticator resolves personal access tokens to their record
+type PersonalAccessTokenAuthenticator interface {
+	AuthenticatePersonalAccessToken(token string) (*models.PersonalAccessToken, error)
+}
+
+// SessionValidator checks that a JWT issued at issuedAt still grants access,
+// e.g. that the account has not been deactivated since
+type SessionValidator interface {
+	ValidateSession(userID uuid.UUID, issuedAt time.Time) error
+}
+
+// AuthMiddleware validates JWT tokens and, when an authenticator is given,
+// personal access tokens. Read-scoped personal access tokens are limited to
+// safe HTTP methods. When sessions is given, JWT sessions are also checked
+// against the current account state.
+func AuthMiddleware(keys *jwtkeys.KeySet, tokens PersonalAccessTokenAuthenticator, sessions SessionValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var tokenString string
 
@@ -44,18 +69,49 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		if tokens != nil && strings.HasPrefix(tokenString, models.PersonalAccessTokenPrefix) {
+			pat, err := tokens.AuthenticatePersonalAccessToken(tokenString)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+				c.Abort()
+				return
+			}
+
+			if pat.Scope != models.TokenScopeWrite && !isSafeMethod(c.Request.Method) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Token does not have write scope"})
+				c.Abort()
+				return
+			}
+
+			c.Set("user_id", pat.UserID)
+			c.Set("user_email", pat.User.Email)
+			c.Set("token_id", pat.ID)
+			c.Next()
+			return
+		}
+
 		claims := &Claims{}
 
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(cfg.JWT.Secret), nil
-		})
+		token, err := keys.Parse(tokenString, claims)
 
-		if err != nil || !token.Valid {
+		if err != nil || !token.Valid || (claims.TokenType != "" && claims.TokenType != TokenTypeAccess) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
+		if sessions != nil {
+			var issuedAt time.Time
+			if claims.IssuedAt != nil {
+				issuedAt = claims.IssuedAt.Time
+			}
+			if err := sessions.ValidateSession(claims.UserID, issuedAt); err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				c.Abort()
+				return
+			}
+		}
+
 		// Set user info in context
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
@@ -64,6 +120,21 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 	}
 }
 
+// IsPersonalAccessToken reports whether the request was authenticated with a
+// personal access token rather than an interactive session
+func IsPersonalAccessToken(c *gin.Context) bool {
+	_, exists := c.Get("token_id")
+	return exists
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
 // GetUserID gets the user ID from the context
 func GetUserID(c *gin.Context) (uuid.UUID, error) {
 	userID, exists := c.Get("user_id")
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// bucket is a single token bucket
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// RateLimiter is an in-memory token bucket limiter keyed by an arbitrary string
+type RateLimiter struct {
+	rate      float64 // tokens added per second
+	burst     float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+// NewRateLimiter creates a rate limiter from a rule
+func NewRateLimiter(rule config.RateLimitRule) *RateLimiter {
+	burst := rule.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:      float64(rule.RequestsPerMinute) / 60,
+		burst:     float64(burst),
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// RateLimitStatus describes the state of a bucket after a request
+type RateLimitStatus struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // Time until the bucket is full again
+	RetryAfter time.Duration // Time until the next token is available (only when not allowed)
+}
+
+// Allow takes a token from the bucket identified by key
+func (l *RateLimiter) Allow(key string) RateLimitStatus {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, lastSeen: now}
+		l.buckets[key] = b
+	} else {
+		elapsed := now.Sub(b.lastSeen).Seconds()
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+		b.lastSeen = now
+	}
+
+	status := RateLimitStatus{Limit: int(l.burst)}
+	if b.tokens >= 1 {
+		b.tokens--
+		status.Allowed = true
+	} else {
+		status.RetryAfter = secondsToDuration((1 - b.tokens) / l.rate)
+	}
+	status.Remaining = int(b.tokens)
+	status.Reset = secondsToDuration((l.burst - b.tokens) / l.rate)
+
+	return status
+}
+
+// sweep drops buckets that have been idle long enough to be full again.
+// Dropping a bucket is then the same as keeping it, since a missing bucket
+// starts full; buckets still refilling are kept.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.lastSeen).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// RateLimitMiddleware limits requests per authenticated user, falling back to
+// the client IP for anonymous requests. It must run after AuthMiddleware to key
+// by user. A rule with a non-positive rate disables limiting.
+func RateLimitMiddleware(rule config.RateLimitRule) gin.HandlerFunc {
+	if rule.RequestsPerMinute <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	limiter := NewRateLimiter(rule)
+
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if userID, err := GetUserID(c); err == nil {
+			key = "user:" + userID.String()
+		}
+
+		status := limiter.Allow(key)
+
+		c.Header("RateLimit-Limit", strconv.Itoa(status.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(status.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(status.Reset)))
+
+		if !status.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(status.RetryAfter)))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
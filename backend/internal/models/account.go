@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AccountExport is the archive of everything a user created, returned by the
+// data export endpoint
+type AccountExport struct {
+	ExportedAt           time.Time               `json:"exported_at"`
+	User                 UserResponse            `json:"user"`
+	CreatedTasks         []Task                  `json:"created_tasks"`
+	AssignedTasks        []Task                  `json:"assigned_tasks"`
+	PersonalAccessTokens []PersonalAccessToken   `json:"personal_access_tokens"`
+	Identities           []UserIdentity          `json:"identities"`
+	Webhooks             []Webhook               `json:"webhooks"`
+	Notifications        []Notification          `json:"notifications"`
+	SentInvitations      []Invitation            `json:"sent_invitations"`
+	NotificationPrefs    NotificationPreferences `json:"notification_preferences"`
+	Devices              []DeviceToken           `json:"devices"`
+}
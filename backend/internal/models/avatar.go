@@ -0,0 +1,43 @@
+package models
+
+import "fmt"
+
+// AvatarSizes are the square sizes, in pixels, that uploaded avatars are resized to
+var AvatarSizes = map[string]int{
+	"small":  64,
+	"medium": 128,
+	"large":  256,
+}
+
+// AvatarURLs holds the public URL of each avatar size
+type AvatarURLs struct {
+	Small  string `json:"small"`
+	Medium string `json:"medium"`
+	Large  string `json:"large"`
+}
+
+// AvatarURLResolver maps avatar storage keys to public URLs, usually the
+// URL method of the configured storage
+type AvatarURLResolver func(key string) string
+
+// AvatarObjectKey returns the storage key of one size of an avatar version
+func AvatarObjectKey(version, size string) string {
+	return fmt.Sprintf("%s-%s.jpg", version, size)
+}
+
+// Avatar returns the URLs of the user's avatar, or nil if none was uploaded.
+// A nil resolver returns the storage keys unchanged.
+func (u *User) Avatar(resolve AvatarURLResolver) *AvatarURLs {
+	if u.AvatarKey == "" {
+		return nil
+	}
+	avatarURL := resolve
+	if avatarURL == nil {
+		avatarURL = func(key string) string { return key }
+	}
+	return &AvatarURLs{
+		Small:  avatarURL(AvatarObjectKey(u.AvatarKey, "small")),
+		Medium: avatarURL(AvatarObjectKey(u.AvatarKey, "medium")),
+		Large:  avatarURL(AvatarObjectKey(u.AvatarKey, "large")),
+	}
+}
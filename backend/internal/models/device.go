@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DeviceToken is an Expo push token of a device where the user is signed in
+type DeviceToken struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	UserID    uuid.UUID `json:"-" gorm:"type:uuid;not null;index"`
+	Token     string    `json:"token" gorm:"type:varchar(255);uniqueIndex;not null"`
+	Platform  string    `json:"platform" gorm:"type:varchar(10)"` // ios or android
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BeforeCreate hook generates UUID before creating device token
+func (d *DeviceToken) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
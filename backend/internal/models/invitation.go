@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InvitationRole is the role granted to the invited user
+type InvitationRole string
+
+const (
+	InvitationRoleMember InvitationRole = "member"
+	InvitationRoleAdmin  InvitationRole = "admin"
+)
+
+// IsValid checks if the role is valid
+func (r InvitationRole) IsValid() bool {
+	switch r {
+	case InvitationRoleMember, InvitationRoleAdmin:
+		return true
+	}
+	return false
+}
+
+// InvitationStatus is the derived state of an invitation
+type InvitationStatus string
+
+const (
+	InvitationStatusPending  InvitationStatus = "pending"
+	InvitationStatusAccepted InvitationStatus = "accepted"
+	InvitationStatusRevoked  InvitationStatus = "revoked"
+	InvitationStatusExpired  InvitationStatus = "expired"
+)
+
+// Invitation invites someone by email to join TaskFlow. The single-use token
+// is only sent by email; a SHA-256 hash of it is stored.
+type Invitation struct {
+	ID         uuid.UUID      `json:"id" gorm:"type:uuid;primary_key"`
+	Email      string         `json:"email" gorm:"type:varchar(255);not null;index"`
+	Role       InvitationRole `json:"role" gorm:"type:varchar(20);not null;default:'member'"`
+	InvitedBy  uuid.UUID      `json:"invited_by" gorm:"type:uuid;not null;index"`
+	TokenHash  string         `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	ExpiresAt  time.Time      `json:"expires_at" gorm:"not null"`
+	AcceptedAt *time.Time     `json:"accepted_at"`
+	AcceptedBy *uuid.UUID     `json:"accepted_by" gorm:"type:uuid"`
+	RevokedAt  *time.Time     `json:"revoked_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+	Inviter    *User          `json:"-" gorm:"foreignKey:InvitedBy"`
+}
+
+// BeforeCreate hook generates UUID before creating invitation
+func (i *Invitation) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
+
+// Status returns the state of the invitation at the given time
+func (i *Invitation) Status(now time.Time) InvitationStatus {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationStatusAccepted
+	case i.RevokedAt != nil:
+		return InvitationStatusRevoked
+	case !now.Before(i.ExpiresAt):
+		return InvitationStatusExpired
+	}
+	return InvitationStatusPending
+}
+
+// InvitationResponse represents an invitation returned in responses
+type InvitationResponse struct {
+	Invitation
+	Status InvitationStatus `json:"status"`
+}
+
+// ToResponse converts Invitation to InvitationResponse
+func (i *Invitation) ToResponse() InvitationResponse {
+	return InvitationResponse{Invitation: *i, Status: i.Status(time.Now())}
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NotificationType represents what a notification is about
+type NotificationType string
+
+const (
+	NotificationTypeAssigned  NotificationType = "assigned"
+	NotificationTypeMentioned NotificationType = "mentioned"
+	NotificationTypeDueSoon   NotificationType = "due_soon"
+	NotificationTypeOverdue   NotificationType = "overdue"
+	NotificationTypeEscalated NotificationType = "escalated" // An urgent task assigned to someone else is overdue
+)
+
+// DigestFrequency is how often a user gets the email digest of their tasks
+type DigestFrequency string
+
+const (
+	DigestOff    DigestFrequency = "off"
+	DigestDaily  DigestFrequency = "daily"
+	DigestWeekly DigestFrequency = "weekly" // Sent on Mondays
+)
+
+// IsValid checks if the digest frequency is valid
+func (f DigestFrequency) IsValid() bool {
+	switch f {
+	case DigestOff, DigestDaily, DigestWeekly:
+		return true
+	}
+	return false
+}
+
+// Limits on the due date reminder lead times a user can choose
+const (
+	MaxReminderLeadTimes   = 5
+	MaxReminderLeadMinutes = 30 * 24 * 60
+)
+
+// Notification is an entry in a user's notification center
+type Notification struct {
+	ID        uuid.UUID        `json:"id" gorm:"type:uuid;primary_key"`
+	EventID   *uint64          `json:"-" gorm:"uniqueIndex:idx_notifications_event,priority:1"`                                // Outbox event it was created for; nil for reminders
+	UserID    uuid.UUID        `json:"user_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_notifications_event,priority:2"` // Recipient
+	Type      NotificationType `json:"type" gorm:"type:varchar(20);not null;uniqueIndex:idx_notifications_event,priority:3"`
+	TaskID    *uuid.UUID       `json:"task_id" gorm:"type:uuid"`
+	TaskTitle string           `json:"task_title" gorm:"type:varchar(100)"` // Title when the notification was created
+	ActorID   *uuid.UUID       `json:"actor_id" gorm:"type:uuid"`
+	ReadAt    *time.Time       `json:"read_at"`
+	CreatedAt time.Time        `json:"created_at"`
+	Actor     *User            `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+}
+
+// BeforeCreate hook generates UUID before creating notification
+func (n *Notification) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	return nil
+}
+
+// NotificationFilter represents filters for querying notifications
+type NotificationFilter struct {
+	UserID     uuid.UUID
+	UnreadOnly bool
+	Page       int
+	PageSize   int
+}
+
+// NotificationPreferences holds which notifications a user wants. Users
+// without a saved row get DefaultNotificationPreferences.
+type NotificationPreferences struct {
+	UserID      uuid.UUID `json:"-" gorm:"type:uuid;primary_key"`
+	Assignments bool      `json:"assignments" gorm:"not null"`
+	Mentions    bool      `json:"mentions" gorm:"not null"`
+	DueDates    bool      `json:"due_dates" gorm:"not null"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Minutes before the due date at which to remind about a task
+	ReminderLeadMinutes []int `json:"reminder_lead_minutes" gorm:"serializer:json;type:text"`
+
+	// Email digest of the user's tasks; opt-in
+	Digest DigestFrequency `json:"digest" gorm:"type:varchar(10);not null;default:'off'"`
+}
+
+// DefaultNotificationPreferences returns the preferences of a user who has
+// not changed them: every notification enabled
+func DefaultNotificationPreferences(userID uuid.UUID) *NotificationPreferences {
+	return &NotificationPreferences{
+		UserID:      userID,
+		Assignments: true,
+		Mentions:    true,
+		DueDates:    true,
+
+		ReminderLeadMinutes: []int{24 * 60, 60},
+		Digest:              DigestOff,
+	}
+}
+
+// Allows reports whether the user wants notifications of the given type
+func (p *NotificationPreferences) Allows(notificationType NotificationType) bool {
+	switch notificationType {
+	case NotificationTypeAssigned:
+		return p.Assignments
+	case NotificationTypeMentioned:
+		return p.Mentions
+	case NotificationTypeDueSoon, NotificationTypeOverdue, NotificationTypeEscalated:
+		return p.DueDates
+	}
+	return false
+}
+
+// TaskReminder records a due date reminder that was sent, so each threshold
+// is only reminded once per due date. Changing the due date starts over.
+type TaskReminder struct {
+	TaskID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Threshold string    `gorm:"type:varchar(30);primaryKey"` // e.g. "due_soon:60", "overdue", "escalated"
+	DueDate   time.Time `gorm:"type:timestamp;primaryKey;index"`
+	SentAt    time.Time
+}
+
+// DigestDelivery records a digest that was sent, so each user gets one per
+// period even with several instances running
+type DigestDelivery struct {
+	UserID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Period string    `gorm:"type:varchar(20);primaryKey"` // e.g. "daily:2024-03-05", "weekly:2024-W10"
+	SentAt time.Time `gorm:"index"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxEvent is a task event stored in the same transaction as the change
+// that caused it, so it survives a crash right after the write. The outbox
+// dispatcher publishes events in ID order and marks them dispatched, or failed
+// once it gives up on them.
+type OutboxEvent struct {
+	ID           uint64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	Type         string     `json:"type" gorm:"type:varchar(20);not null"`
+	TaskID       uuid.UUID  `json:"task_id" gorm:"type:uuid;not null"`
+	ActorID      uuid.UUID  `json:"actor_id" gorm:"type:uuid;not null"`
+	Task         *Task      `json:"task" gorm:"column:task_snapshot;serializer:json;type:text"`         // State after the change; nil for deletions
+	Previous     *Task      `json:"previous" gorm:"column:previous_snapshot;serializer:json;type:text"` // State before the change; nil for creations
+	CreatedAt    time.Time  `json:"created_at"`
+	DispatchedAt *time.Time `json:"dispatched_at" gorm:"index"`
+
+	// Dispatch progress: sinks that already handled the event are skipped when
+	// it is retried
+	HandledBy     []string   `json:"handled_by" gorm:"serializer:json;type:text"`
+	Attempts      int        `json:"attempts" gorm:"not null;default:0"` // Failed dispatch attempts
+	NextAttemptAt *time.Time `json:"next_attempt_at"`                    // Earliest retry after a failure; later events wait too
+	LastError     string     `json:"last_error" gorm:"type:text"`
+	FailedAt      *time.Time `json:"failed_at" gorm:"index"` // Set when the dispatcher gave up; the event is kept until retried
+}
+
+// TaskEvent returns the event as sent to clients. Its ID is the outbox ID, so
+// every instance gives the event the same ID.
+func (e *OutboxEvent) TaskEvent() TaskEvent {
+	return TaskEvent{
+		ID:     e.ID,
+		Type:   e.Type,
+		TaskID: e.TaskID,
+		Task:   e.Task,
+		UserID: e.ActorID,
+	}
+}
+
+// Handled reports whether the named sink already handled the event
+func (e *OutboxEvent) Handled(sink string) bool {
+	for _, name := range e.HandledBy {
+		if name == sink {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PersonalAccessTokenPrefix identifies personal access tokens in Bearer headers
+const PersonalAccessTokenPrefix = "tfp_"
+
+// TokenScope represents the permissions granted to a personal access token
+type TokenScope string
+
+const (
+	TokenScopeRead  TokenScope = "read"
+	TokenScopeWrite TokenScope = "write"
+)
+
+// IsValid checks if the scope is valid
+func (s TokenScope) IsValid() bool {
+	switch s {
+	case TokenScopeRead, TokenScopeWrite:
+		return true
+	}
+	return false
+}
+
+// PersonalAccessToken is a long-lived token for scripts and integrations.
+// Only a SHA-256 hash of the token is stored.
+type PersonalAccessToken struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Name        string     `json:"name" gorm:"type:varchar(100);not null"`
+	Scope       TokenScope `json:"scope" gorm:"type:varchar(10);not null;default:'read'"`
+	TokenHash   string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	TokenPrefix string     `json:"token_prefix" gorm:"type:varchar(16);not null"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	User        *User      `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// BeforeCreate hook generates UUID before creating token
+func (t *PersonalAccessToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsExpired checks if the token has expired
+func (t *PersonalAccessToken) IsExpired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
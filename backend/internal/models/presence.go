@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserPresence records that a user had a real-time connection open on a
+// backend instance when it last reported its clients, at SeenAt
+type UserPresence struct {
+	InstanceID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID     uuid.UUID `gorm:"type:uuid;primaryKey;index"`
+	SeenAt     time.Time `gorm:"not null;index"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// SystemStats summarizes the state of the system for administrators
+type SystemStats struct {
+	Users            UserCounts           `json:"users"`
+	TasksByStatus    map[TaskStatus]int64 `json:"tasks_by_status"`
+	TasksTotal       int64                `json:"tasks_total"`
+	WebSocketClients int                  `json:"websocket_clients"`
+	Realtime         *RealtimeMetrics     `json:"realtime,omitempty"`
+}
+
+// RealtimeMetrics describes realtime delivery on this instance
+type RealtimeMetrics struct {
+	Clients            int    `json:"clients"`
+	SlowClientsDropped uint64 `json:"slow_clients_dropped"` // Disconnected because their queue filled up
+	MessagesCoalesced  uint64 `json:"messages_coalesced"`   // Pending task updates replaced by a newer one
+	PresenceDropped    uint64 `json:"presence_dropped"`     // Presence events skipped for clients with a full queue
+}
+
+// UserCounts counts users by account state
+type UserCounts struct {
+	Total       int64 `json:"total"`
+	Active      int64 `json:"active"`
+	Deactivated int64 `json:"deactivated"`
+	Admins      int64 `json:"admins"`
+}
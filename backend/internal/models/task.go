@@ -37,6 +37,8 @@ type Task struct {
 	DueDate     *time.Time `json:"due_date" gorm:"type:timestamp"`
 	CreatedBy   uuid.UUID  `json:"created_by" gorm:"type:uuid;not null"`
 	AssignedTo  *uuid.UUID `json:"assigned_to" gorm:"type:uuid"`
+	AssignedAt  *time.Time `json:"assigned_at"`  // When the current assignee was assigned
+	CompletedAt *time.Time `json:"completed_at"` // When the task was last marked completed
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	Creator     *User      `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
@@ -51,6 +53,27 @@ func (t *Task) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// Audience returns the users allowed to see the task: its creator and, if
+// set, its assignee
+func (t *Task) Audience() []uuid.UUID {
+	audience := []uuid.UUID{t.CreatedBy}
+	if t.AssignedTo != nil {
+		audience = append(audience, *t.AssignedTo)
+	}
+	return audience
+}
+
+// SetStatus changes the status, keeping CompletedAt in sync: it is set when
+// the task becomes completed and cleared when it is reopened
+func (t *Task) SetStatus(status TaskStatus, now time.Time) {
+	if status != TaskStatusCompleted {
+		t.CompletedAt = nil
+	} else if t.Status != TaskStatusCompleted || t.CompletedAt == nil {
+		t.CompletedAt = &now
+	}
+	t.Status = status
+}
+
 // IsValidStatus checks if the status is valid
 func (s TaskStatus) IsValid() bool {
 	switch s {
@@ -84,8 +107,31 @@ type TaskFilter struct {
 
 // TaskEvent represents a task event for WebSocket notifications
 type TaskEvent struct {
-	Type   string    `json:"type"` // created, updated, deleted, assigned
-	TaskID uuid.UUID `json:"task_id"`
-	Task   *Task     `json:"task,omitempty"`
-	UserID uuid.UUID `json:"user_id"` // User who triggered the event
+	ID           uint64        `json:"id,omitempty"` // Outbox ID, increasing; not set for notification events
+	Type         string        `json:"type"`         // created, updated, deleted, assigned, notification
+	TaskID       uuid.UUID     `json:"task_id"`
+	Task         *Task         `json:"task,omitempty"`
+	Notification *Notification `json:"notification,omitempty"` // Set for notification events
+	UserID       uuid.UUID     `json:"user_id"`                // User who triggered the event
+}
+
+// Task event types delivered to clients and webhooks
+const (
+	TaskEventCreated  = "created"
+	TaskEventUpdated  = "updated"
+	TaskEventDeleted  = "deleted"
+	TaskEventAssigned = "assigned"
+)
+
+// TaskEventNotification is sent over the WebSocket to the recipient of a new
+// notification. It is not a webhook event type.
+const TaskEventNotification = "notification"
+
+// IsValidTaskEventType checks if a task event type exists
+func IsValidTaskEventType(eventType string) bool {
+	switch eventType {
+	case TaskEventCreated, TaskEventUpdated, TaskEventDeleted, TaskEventAssigned:
+		return true
+	}
+	return false
 }
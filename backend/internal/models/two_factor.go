@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TwoFactorChallenge is a login challenge token waiting for its 2FA code. The
+// ID is the token's jti; a challenge can be used once and only takes a
+// limited number of wrong codes.
+type TwoFactorChallenge struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	UserID    uuid.UUID  `json:"-" gorm:"type:uuid;not null;index"`
+	Attempts  int        `json:"attempts" gorm:"not null;default:0"` // Wrong codes sent so far
+	UsedAt    *time.Time `json:"used_at"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	CreatedAt time.Time  `json:"created_at" gorm:"index"`
+}
+
+// BeforeCreate hook generates UUID before creating challenge
+func (c *TwoFactorChallenge) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,8 +15,35 @@ type User struct {
 	Email     string    `json:"email" gorm:"type:varchar(255);uniqueIndex;not null"`
 	Password  string    `json:"-" gorm:"type:varchar(255);not null"`
 	Name      string    `json:"name" gorm:"type:varchar(100);not null"`
+	Timezone  string    `json:"timezone" gorm:"type:varchar(64);not null;default:'UTC'"`
+	Locale    string    `json:"locale" gorm:"type:varchar(35);not null;default:'es'"`
+	AvatarKey string    `json:"-" gorm:"type:varchar(255)"`      // Storage key prefix of the current avatar version
+	IsAdmin   bool      `json:"-" gorm:"not null;default:false"` // Exposed only through UserResponse
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Offboarding: deactivated accounts cannot sign in; anonymized accounts
+	// were deleted by their owner and only remain as a placeholder for history
+	DeactivatedAt *time.Time `json:"-" gorm:"index"`
+	AnonymizedAt  *time.Time `json:"-"`
+
+	// Access and refresh tokens issued before this time are rejected (forced logout)
+	TokensValidAfter *time.Time `json:"-"`
+
+	// Set when an admin resets the password: the temporary one only signs in
+	// together with a new password
+	MustChangePassword bool `json:"-" gorm:"not null;default:false"`
+
+	// Pending email change awaiting verification
+	PendingEmail               string     `json:"-" gorm:"type:varchar(255)"`
+	EmailVerificationTokenHash string     `json:"-" gorm:"type:varchar(64);index"`
+	EmailVerificationExpiresAt *time.Time `json:"-"`
+
+	// Two-factor authentication (TOTP)
+	TOTPSecret    string `json:"-" gorm:"type:varchar(64)"`
+	TOTPEnabled   bool   `json:"-" gorm:"not null;default:false"` // Exposed only through UserResponse
+	TOTPLastStep  int64  `json:"-" gorm:"not null;default:0"`
+	RecoveryCodes string `json:"-" gorm:"type:text"` // Comma-separated SHA-256 hashes of unused codes
 }
 
 // BeforeCreate hook generates UUID before creating user
@@ -23,6 +51,12 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if u.ID == uuid.Nil {
 		u.ID = uuid.New()
 	}
+	if u.Timezone == "" {
+		u.Timezone = "UTC"
+	}
+	if u.Locale == "" {
+		u.Locale = "es"
+	}
 	return nil
 }
 
@@ -36,6 +70,17 @@ func (u *User) HashPassword() error {
 	return nil
 }
 
+// NormalizeEmail returns the form in which emails are stored and looked up, so
+// addresses that differ only in case belong to the same account
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// IsActive reports whether the account may sign in
+func (u *User) IsActive() bool {
+	return u.DeactivatedAt == nil
+}
+
 // CheckPassword checks if the provided password matches the user's password
 func (u *User) CheckPassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
@@ -44,18 +89,70 @@ func (u *User) CheckPassword(password string) bool {
 
 // UserResponse represents the user data returned in responses (without password)
 type UserResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	ID               uuid.UUID   `json:"id"`
+	Email            string      `json:"email"`
+	Name             string      `json:"name"`
+	Timezone         string      `json:"timezone"`
+	Locale           string      `json:"locale"`
+	Avatar           *AvatarURLs `json:"avatar"`
+	PendingEmail     string      `json:"pending_email,omitempty"`
+	TwoFactorEnabled bool        `json:"two_factor_enabled"`
+	IsAdmin          bool        `json:"is_admin"`
+	DeactivatedAt    *time.Time  `json:"deactivated_at,omitempty"`
+	CreatedAt        time.Time   `json:"created_at"`
+}
+
+// UserSummary is the public view of a user shown to other users, e.g. in the assignee picker
+type UserSummary struct {
+	ID     uuid.UUID   `json:"id"`
+	Name   string      `json:"name"`
+	Email  string      `json:"email"`
+	Avatar *AvatarURLs `json:"avatar"`
+}
+
+// UserFilter represents filters for querying users
+type UserFilter struct {
+	Query              string // Case-insensitive prefix of the name, any word of the name, or the email
+	IncludeDeactivated bool
+	OnlyDeactivated    bool
+	Page               int
+	PageSize           int
+}
+
+// Normalize defaults the page to 1 and the page size to 20 when they are out
+// of range
+func (f *UserFilter) Normalize() {
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.PageSize < 1 || f.PageSize > 100 {
+		f.PageSize = 20
+	}
+}
+
+// ToSummary converts User to UserSummary
+func (u *User) ToSummary(avatarURL AvatarURLResolver) UserSummary {
+	return UserSummary{
+		ID:     u.ID,
+		Name:   u.Name,
+		Email:  u.Email,
+		Avatar: u.Avatar(avatarURL),
+	}
 }
 
 // ToResponse converts User to UserResponse
-func (u *User) ToResponse() UserResponse {
+func (u *User) ToResponse(avatarURL AvatarURLResolver) UserResponse {
 	return UserResponse{
-		ID:        u.ID,
-		Email:     u.Email,
-		Name:      u.Name,
-		CreatedAt: u.CreatedAt,
+		ID:               u.ID,
+		Email:            u.Email,
+		Name:             u.Name,
+		Timezone:         u.Timezone,
+		Locale:           u.Locale,
+		Avatar:           u.Avatar(avatarURL),
+		PendingEmail:     u.PendingEmail,
+		TwoFactorEnabled: u.TOTPEnabled,
+		IsAdmin:          u.IsAdmin,
+		DeactivatedAt:    u.DeactivatedAt,
+		CreatedAt:        u.CreatedAt,
 	}
 }
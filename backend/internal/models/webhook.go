@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Webhook is a user's subscription to task events, delivered as signed POST
+// requests to URL. It receives events of the tasks its owner can see.
+type Webhook struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	UserID     uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	URL        string    `json:"url" gorm:"type:varchar(2048);not null"`
+	Secret     string    `json:"-" gorm:"type:varchar(100);not null"`          // HMAC key, shown only on creation
+	EventTypes []string  `json:"event_types" gorm:"serializer:json;type:text"` // Empty means every event
+	Active     bool      `json:"active" gorm:"not null;default:true"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// BeforeCreate hook generates UUID before creating webhook
+func (w *Webhook) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
+
+// Accepts reports whether the webhook subscribes to an event type
+func (w *Webhook) Accepts(eventType string) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryStatus is the state of a webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event sent, or to be sent, to a webhook. Payload is
+// the exact request body, so redeliveries send the same bytes.
+type WebhookDelivery struct {
+	ID             uuid.UUID             `json:"id" gorm:"type:uuid;primary_key"`
+	WebhookID      uuid.UUID             `json:"webhook_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_webhook_deliveries_event"`
+	EventID        *uint64               `json:"event_id,omitempty" gorm:"uniqueIndex:idx_webhook_deliveries_event"` // Outbox event; nil for test deliveries
+	EventType      string                `json:"event_type" gorm:"type:varchar(20);not null"`
+	Payload        string                `json:"payload" gorm:"type:text;not null"`
+	Status         WebhookDeliveryStatus `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	Attempts       int                   `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at" gorm:"index"`
+	LastAttemptAt  *time.Time            `json:"last_attempt_at"`
+	ResponseStatus int                   `json:"response_status"`
+	Error          string                `json:"error" gorm:"type:text"`
+	CreatedAt      time.Time             `json:"created_at"`
+	Webhook        *Webhook              `json:"-" gorm:"foreignKey:WebhookID"`
+}
+
+// BeforeCreate hook generates UUID before creating delivery
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
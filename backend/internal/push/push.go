@@ -0,0 +1,228 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+)
+
+// ErrorDeviceNotRegistered is the ticket or receipt error for a push token
+// that is no longer valid, e.g. because the app was uninstalled. Such tokens
+// should not be used again.
+const ErrorDeviceNotRegistered = "DeviceNotRegistered"
+
+// Limits of the Expo push API per request
+const (
+	expoSendBatchSize    = 100
+	expoReceiptBatchSize = 1000
+)
+
+// DefaultExpoURL is the base URL of the Expo push service
+const DefaultExpoURL = "https://exp.host"
+
+// Message is a push notification for a single device
+type Message struct {
+	To    string            `json:"to"` // Expo push token
+	Title string            `json:"title,omitempty"`
+	Body  string            `json:"body,omitempty"`
+	Data  map[string]string `json:"data,omitempty"`
+	Sound string            `json:"sound,omitempty"`
+}
+
+// Ticket is the push service's answer to one message. Accepted messages have
+// an ID whose receipt can be checked later; rejected ones have an Error.
+type Ticket struct {
+	ID      string
+	Error   string
+	Message string
+}
+
+// Receipt is the outcome of delivering a message to the device's platform
+type Receipt struct {
+	Error   string // Empty when delivered
+	Message string
+}
+
+// InvalidToken reports whether the token the message was sent to is no
+// longer valid
+func (t Ticket) InvalidToken() bool {
+	return t.Error == ErrorDeviceNotRegistered
+}
+
+// InvalidToken reports whether the token the message was sent to is no
+// longer valid
+func (r Receipt) InvalidToken() bool {
+	return r.Error == ErrorDeviceNotRegistered
+}
+
+// PushSender sends push notifications to devices
+type PushSender interface {
+	// Send sends the messages, splitting them into as many requests as the
+	// service requires. It returns one ticket per message, in order. On
+	// error, it returns the tickets of the messages sent before the failure.
+	Send(ctx context.Context, messages []Message) ([]Ticket, error)
+
+	// Receipts returns the receipts of the given ticket IDs. Receipts that
+	// are not ready yet are missing from the result.
+	Receipts(ctx context.Context, ticketIDs []string) (map[string]Receipt, error)
+}
+
+// New creates the push sender selected by configuration
+func New(cfg config.PushConfig) (PushSender, error) {
+	switch cfg.Driver {
+	case "", "log":
+		return &LogSender{}, nil
+	case "expo":
+		return NewExpoSender(cfg.ExpoURL, cfg.ExpoAccessToken, nil), nil
+	}
+	return nil, fmt.Errorf("unknown push driver %q", cfg.Driver)
+}
+
+// LogSender writes push notifications to the application log. Intended for
+// development.
+type LogSender struct{}
+
+// Send logs the messages and accepts them all
+func (s *LogSender) Send(ctx context.Context, messages []Message) ([]Ticket, error) {
+	for _, msg := range messages {
+		log.Printf("Push to %s: %s - %s", msg.To, msg.Title, msg.Body)
+	}
+	return make([]Ticket, len(messages)), nil
+}
+
+// Receipts returns no receipts; logged messages have no ticket IDs
+func (s *LogSender) Receipts(ctx context.Context, ticketIDs []string) (map[string]Receipt, error) {
+	return map[string]Receipt{}, nil
+}
+
+// ExpoSender sends push notifications through the Expo push service
+type ExpoSender struct {
+	baseURL     string
+	accessToken string
+	client      *http.Client
+}
+
+// NewExpoSender creates an Expo push sender. An empty base URL uses
+// DefaultExpoURL; the access token is only needed when the Expo project
+// enforces push security. A nil client uses one with a 30 second timeout.
+func NewExpoSender(baseURL, accessToken string, client *http.Client) *ExpoSender {
+	if baseURL == "" {
+		baseURL = DefaultExpoURL
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &ExpoSender{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		accessToken: accessToken,
+		client:      client,
+	}
+}
+
+// expoResult is a ticket or receipt as returned by Expo
+type expoResult struct {
+	Status  string `json:"status"` // "ok" or "error"
+	ID      string `json:"id"`
+	Message string `json:"message"`
+	Details struct {
+		Error string `json:"error"`
+	} `json:"details"`
+}
+
+// errorCode returns the error of a failed ticket or receipt, falling back to
+// the status when Expo gives no details
+func (e expoResult) errorCode() string {
+	if e.Status == "ok" {
+		return ""
+	}
+	if e.Details.Error != "" {
+		return e.Details.Error
+	}
+	return e.Status
+}
+
+// Send sends the messages in batches of 100
+func (s *ExpoSender) Send(ctx context.Context, messages []Message) ([]Ticket, error) {
+	tickets := make([]Ticket, 0, len(messages))
+	for start := 0; start < len(messages); start += expoSendBatchSize {
+		end := start + expoSendBatchSize
+		if end > len(messages) {
+			end = len(messages)
+		}
+		batch := messages[start:end]
+
+		var response struct {
+			Data []expoResult `json:"data"`
+		}
+		if err := s.post(ctx, "/--/api/v2/push/send", batch, &response); err != nil {
+			return tickets, err
+		}
+		if len(response.Data) != len(batch) {
+			return tickets, fmt.Errorf("expo returned %d tickets for %d messages", len(response.Data), len(batch))
+		}
+		for _, data := range response.Data {
+			tickets = append(tickets, Ticket{ID: data.ID, Error: data.errorCode(), Message: data.Message})
+		}
+	}
+	return tickets, nil
+}
+
+// Receipts fetches receipts in batches of 1000
+func (s *ExpoSender) Receipts(ctx context.Context, ticketIDs []string) (map[string]Receipt, error) {
+	receipts := make(map[string]Receipt, len(ticketIDs))
+	for start := 0; start < len(ticketIDs); start += expoReceiptBatchSize {
+		end := start + expoReceiptBatchSize
+		if end > len(ticketIDs) {
+			end = len(ticketIDs)
+		}
+
+		var response struct {
+			Data map[string]expoResult `json:"data"`
+		}
+		request := map[string][]string{"ids": ticketIDs[start:end]}
+		if err := s.post(ctx, "/--/api/v2/push/getReceipts", request, &response); err != nil {
+			return receipts, err
+		}
+		for id, data := range response.Data {
+			receipts[id] = Receipt{Error: data.errorCode(), Message: data.Message}
+		}
+	}
+	return receipts, nil
+}
+
+// post sends a JSON request to the Expo API and decodes the response into v
+func (s *ExpoSender) post(ctx context.Context, path string, body, v interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if s.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.accessToken)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("expo push service returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AccountRepository handles database operations spanning all data owned by a user
+type AccountRepository struct {
+	db *gorm.DB
+}
+
+// NewAccountRepository creates a new account repository
+func NewAccountRepository(db *gorm.DB) *AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+// ListCreatedTasks lists the tasks created by a user
+func (r *AccountRepository) ListCreatedTasks(userID uuid.UUID) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.db.Where("created_by = ?", userID).Order("created_at ASC").Find(&tasks).Error
+	return tasks, err
+}
+
+// ListAssignedTasks lists the tasks assigned to a user
+func (r *AccountRepository) ListAssignedTasks(userID uuid.UUID) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.db.Where("assigned_to = ?", userID).Order("created_at ASC").Find(&tasks).Error
+	return tasks, err
+}
+
+// ListIdentities lists the external identities linked to a user
+func (r *AccountRepository) ListIdentities(userID uuid.UUID) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	return identities, err
+}
+
+// ListWebhooks lists a user's webhooks
+func (r *AccountRepository) ListWebhooks(userID uuid.UUID) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// ListNotifications lists the notifications sent to a user
+func (r *AccountRepository) ListNotifications(userID uuid.UUID) ([]models.Notification, error) {
+	var notifications []models.Notification
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&notifications).Error
+	return notifications, err
+}
+
+// ListSentInvitations lists the invitations a user sent
+func (r *AccountRepository) ListSentInvitations(userID uuid.UUID) ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	err := r.db.Where("invited_by = ?", userID).Order("created_at ASC").Find(&invitations).Error
+	return invitations, err
+}
+
+// FindNotificationPreferences finds a user's saved notification preferences
+func (r *AccountRepository) FindNotificationPreferences(userID uuid.UUID) (*models.NotificationPreferences, error) {
+	var prefs models.NotificationPreferences
+	err := r.db.Where("user_id = ?", userID).First(&prefs).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// ListDevices lists the push devices a user registered
+func (r *AccountRepository) ListDevices(userID uuid.UUID) ([]models.DeviceToken, error) {
+	var devices []models.DeviceToken
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&devices).Error
+	return devices, err
+}
+
+// DeleteAccount removes a user's credentials, webhooks and other personal data
+// and saves the anonymized user in a single transaction. Their pending
+// invitations are revoked. Tasks they created move to reassignTo when given
+// and otherwise stay attributed to the anonymized user; tasks assigned to them
+// are unassigned.
+func (r *AccountRepository) DeleteAccount(user *models.User, reassignTo *uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if reassignTo != nil {
+			err := tx.Model(&models.Task{}).Where("created_by = ?", user.ID).Update("created_by", *reassignTo).Error
+			if err != nil {
+				return err
+			}
+		}
+		err := tx.Model(&models.Task{}).Where("assigned_to = ?", user.ID).Updates(map[string]interface{}{
+			"assigned_to": nil,
+			"assigned_at": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.PersonalAccessToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.Notification{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.NotificationPreferences{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.TaskReminder{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.DeviceToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.DigestDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.TwoFactorChallenge{}).Error; err != nil {
+			return err
+		}
+		webhooks := tx.Model(&models.Webhook{}).Select("id").Where("user_id = ?", user.ID)
+		if err := tx.Where("webhook_id IN (?)", webhooks).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.Webhook{}).Error; err != nil {
+			return err
+		}
+		err = tx.Model(&models.Invitation{}).
+			Where("invited_by = ? AND accepted_at IS NULL AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Save(user).Error
+	})
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeviceRepository handles database operations for push device tokens
+type DeviceRepository struct {
+	db *gorm.DB
+}
+
+// NewDeviceRepository creates a new device repository
+func NewDeviceRepository(db *gorm.DB) *DeviceRepository {
+	return &DeviceRepository{db: db}
+}
+
+// Register saves a device token for its user. A token registered before by
+// another user, e.g. after switching accounts on the device, moves to this
+// one. The device is reloaded with its stored ID.
+func (r *DeviceRepository) Register(device *models.DeviceToken) error {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "updated_at"}),
+	}).Create(device).Error
+	if err != nil {
+		return err
+	}
+	return r.db.Where("token = ?", device.Token).First(device).Error
+}
+
+// ListByUser lists a user's device tokens
+func (r *DeviceRepository) ListByUser(userID uuid.UUID) ([]models.DeviceToken, error) {
+	var devices []models.DeviceToken
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&devices).Error
+	return devices, err
+}
+
+// Delete removes one of a user's device tokens and reports whether it existed
+func (r *DeviceRepository) Delete(userID uuid.UUID, token string) (bool, error) {
+	result := r.db.Where("user_id = ? AND token = ?", userID, token).Delete(&models.DeviceToken{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteTokens removes device tokens the push service reported as invalid
+func (r *DeviceRepository) DeleteTokens(tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	return r.db.Where("token IN ?", tokens).Delete(&models.DeviceToken{}).Error
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DigestRepository handles database operations for email digests
+type DigestRepository struct {
+	db *gorm.DB
+}
+
+// NewDigestRepository creates a new digest repository
+func NewDigestRepository(db *gorm.DB) *DigestRepository {
+	return &DigestRepository{db: db}
+}
+
+// ListSubscribers lists the preferences of users who opted in to a digest
+func (r *DigestRepository) ListSubscribers() ([]models.NotificationPreferences, error) {
+	var prefs []models.NotificationPreferences
+	err := r.db.Where("digest <> ?", models.DigestOff).Find(&prefs).Error
+	return prefs, err
+}
+
+// ListDigestTasks lists the tasks a user created or is assigned that belong in
+// their digest: open tasks due before until, tasks someone else assigned to
+// them since since, and tasks completed since since
+func (r *DigestRepository) ListDigestTasks(userID uuid.UUID, since, until time.Time) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.db.
+		Preload("Creator").
+		Preload("Assignee").
+		Where("created_by = ? OR assigned_to = ?", userID, userID).
+		Where(r.db.
+			Where("status NOT IN ? AND due_date < ?", []models.TaskStatus{models.TaskStatusCompleted, models.TaskStatusCancelled}, until).
+			Or("assigned_to = ? AND created_by <> ? AND assigned_at >= ?", userID, userID, since).
+			Or("status = ? AND completed_at >= ?", models.TaskStatusCompleted, since)).
+		Order("due_date ASC NULLS LAST").
+		Order("created_at ASC").
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// ClaimDigest records a digest unless one was already sent for the period,
+// and reports whether this call recorded it. Concurrent schedulers claim each
+// digest only once.
+func (r *DigestRepository) ClaimDigest(delivery *models.DigestDelivery) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(delivery)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReleaseDigest removes a claimed digest, so it is sent again on the next
+// check
+func (r *DigestRepository) ReleaseDigest(delivery *models.DigestDelivery) error {
+	return r.db.Where("user_id = ? AND period = ?", delivery.UserID, delivery.Period).Delete(&models.DigestDelivery{}).Error
+}
+
+// DeleteDigestsSentBefore removes the records of digests sent before cutoff
+func (r *DigestRepository) DeleteDigestsSentBefore(cutoff time.Time) error {
+	return r.db.Where("sent_at < ?", cutoff).Delete(&models.DigestDelivery{}).Error
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdentityRepository handles database operations for external identities
+type IdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewIdentityRepository creates a new identity repository
+func NewIdentityRepository(db *gorm.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// FindIdentity finds the identity for a provider subject
+func (r *IdentityRepository) FindIdentity(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// CreateIdentity links an external identity to a user
+func (r *IdentityRepository) CreateIdentity(identity *models.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+// CreateAuthRequest stores a pending authorization request
+func (r *IdentityRepository) CreateAuthRequest(request *models.OIDCAuthRequest) error {
+	// Opportunistically purge abandoned requests
+	r.db.Where("expires_at < ?", time.Now()).Delete(&models.OIDCAuthRequest{})
+	return r.db.Create(request).Error
+}
+
+// ConsumeAuthRequest deletes and returns a pending request so each state is single-use
+func (r *IdentityRepository) ConsumeAuthRequest(state string) (*models.OIDCAuthRequest, error) {
+	var requests []models.OIDCAuthRequest
+	err := r.db.Clauses(clause.Returning{}).Where("state = ?", state).Delete(&requests).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		return nil, nil
+	}
+	return &requests[0], nil
+}
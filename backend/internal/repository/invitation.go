@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InvitationRepository handles database operations for invitations
+type InvitationRepository struct {
+	db *gorm.DB
+}
+
+// NewInvitationRepository creates a new invitation repository
+func NewInvitationRepository(db *gorm.DB) *InvitationRepository {
+	return &InvitationRepository{db: db}
+}
+
+// Create creates a new invitation
+func (r *InvitationRepository) Create(invitation *models.Invitation) error {
+	return r.db.Create(invitation).Error
+}
+
+// FindByID finds an invitation by ID
+func (r *InvitationRepository) FindByID(id uuid.UUID) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.Where("id = ?", id).First(&invitation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// FindByHash finds an invitation by its token hash, including the inviter
+func (r *InvitationRepository) FindByHash(hash string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.Preload("Inviter").Where("token_hash = ?", hash).First(&invitation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// ListByInviter lists the invitations sent by a user, newest first
+func (r *InvitationRepository) ListByInviter(userID uuid.UUID) ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	err := r.db.Where("invited_by = ?", userID).Order("created_at DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+// Revoke marks a pending invitation as revoked
+func (r *InvitationRepository) Revoke(id uuid.UUID, revokedAt time.Time) error {
+	return r.db.Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id).
+		Update("revoked_at", revokedAt).Error
+}
+
+// Delete removes an invitation
+func (r *InvitationRepository) Delete(id uuid.UUID) error {
+	return r.db.Where("id = ?", id).Delete(&models.Invitation{}).Error
+}
+
+// MarkAccepted marks a pending invitation as accepted and reports whether it
+// was still pending, so a token can only be redeemed once
+func (r *InvitationRepository) MarkAccepted(id, userID uuid.UUID, acceptedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"accepted_at": acceptedAt, "accepted_by": userID})
+	return result.RowsAffected > 0, result.Error
+}
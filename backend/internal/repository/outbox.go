@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxRepository handles database operations for the event outbox
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// DispatchNext locks the oldest pending event and passes it to fn in one
+// transaction. fn records its progress on the event (handled sinks, attempts,
+// dispatched or failed time), which is saved even when fn returns an error.
+// Other instances wait on the lock instead of skipping ahead, so events go out
+// one at a time in ID order. An event waiting for its next attempt after now
+// holds back the rest. It reports whether an event was dispatched.
+func (r *OutboxRepository) DispatchNext(now time.Time, fn func(event *models.OutboxEvent) error) (bool, error) {
+	found := false
+	var dispatchErr error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var event models.OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("dispatched_at IS NULL AND failed_at IS NULL").
+			Order("id").
+			First(&event).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if event.NextAttemptAt != nil && event.NextAttemptAt.After(now) {
+			return nil
+		}
+		found = true
+
+		dispatchErr = fn(&event)
+		return tx.Model(&event).
+			Select("handled_by", "attempts", "next_attempt_at", "last_error", "dispatched_at", "failed_at").
+			Updates(&event).Error
+	})
+	if err != nil {
+		return found, err
+	}
+	return found, dispatchErr
+}
+
+// DeleteDispatchedBefore removes events dispatched before cutoff. Failed events
+// are kept.
+func (r *OutboxRepository) DeleteDispatchedBefore(cutoff time.Time) error {
+	return r.db.Where("dispatched_at < ?", cutoff).Delete(&models.OutboxEvent{}).Error
+}
+
+// ListFailed lists the events the dispatcher gave up on, oldest first
+func (r *OutboxRepository) ListFailed(limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := r.db.Where("failed_at IS NOT NULL").Order("id").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// Retry puts a failed event back in the queue with its attempts reset. Sinks
+// that already handled it are still skipped. It reports whether a failed event
+// with that ID was found.
+func (r *OutboxRepository) Retry(id uint64) (bool, error) {
+	result := r.db.Model(&models.OutboxEvent{}).
+		Where("id = ? AND failed_at IS NOT NULL", id).
+		Updates(map[string]interface{}{"failed_at": nil, "attempts": 0, "next_attempt_at": nil})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PresenceRepository handles database operations for the presence shared
+// between backend instances
+type PresenceRepository struct {
+	db *gorm.DB
+}
+
+// NewPresenceRepository creates a new presence repository
+func NewPresenceRepository(db *gorm.DB) *PresenceRepository {
+	return &PresenceRepository{db: db}
+}
+
+// Sync replaces the users an instance reports as connected, seen at now, and
+// drops the reports of every instance older than staleBefore, such as those
+// of instances that stopped
+func (r *PresenceRepository) Sync(instanceID uuid.UUID, userIDs []uuid.UUID, now, staleBefore time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if len(userIDs) > 0 {
+			rows := make([]models.UserPresence, len(userIDs))
+			for i, userID := range userIDs {
+				rows[i] = models.UserPresence{InstanceID: instanceID, UserID: userID, SeenAt: now}
+			}
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "instance_id"}, {Name: "user_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"seen_at"}),
+			}).Create(&rows).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("instance_id = ? AND seen_at < ?", instanceID, now).Delete(&models.UserPresence{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("seen_at < ?", staleBefore).Delete(&models.UserPresence{}).Error
+	})
+}
+
+// IsOnline reports whether any instance reported the user as connected since
+// the given time
+func (r *PresenceRepository) IsOnline(userID uuid.UUID, since time.Time) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.UserPresence{}).Where("user_id = ? AND seen_at >= ?", userID, since).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReminderRepository handles database operations for due date reminders
+type ReminderRepository struct {
+	db *gorm.DB
+}
+
+// NewReminderRepository creates a new reminder repository
+func NewReminderRepository(db *gorm.DB) *ReminderRepository {
+	return &ReminderRepository{db: db}
+}
+
+// ListOpenTasksDueBetween lists the tasks that are neither completed nor
+// cancelled and are due between from and to, with their creator and assignee
+func (r *ReminderRepository) ListOpenTasksDueBetween(from, to time.Time) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.db.
+		Preload("Creator").
+		Preload("Assignee").
+		Where("due_date BETWEEN ? AND ?", from, to).
+		Where("status NOT IN ?", []models.TaskStatus{models.TaskStatusCompleted, models.TaskStatusCancelled}).
+		Order("due_date ASC").
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// ClaimReminder records a reminder unless it was already sent, and reports
+// whether this call recorded it. Concurrent schedulers claim each reminder
+// only once.
+func (r *ReminderRepository) ClaimReminder(reminder *models.TaskReminder) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(reminder)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReleaseReminder removes a claimed reminder, so it is sent again on the next
+// check
+func (r *ReminderRepository) ReleaseReminder(reminder *models.TaskReminder) error {
+	return r.db.
+		Where("task_id = ? AND user_id = ? AND threshold = ? AND due_date = ?", reminder.TaskID, reminder.UserID, reminder.Threshold, reminder.DueDate).
+		Delete(&models.TaskReminder{}).Error
+}
+
+// DeleteRemindersDueBefore removes the records of reminders for due dates
+// before cutoff
+func (r *ReminderRepository) DeleteRemindersDueBefore(cutoff time.Time) error {
+	return r.db.Where("due_date < ?", cutoff).Delete(&models.TaskReminder{}).Error
+}
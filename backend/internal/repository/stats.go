@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// StatsRepository computes system-wide aggregates
+type StatsRepository struct {
+	db *gorm.DB
+}
+
+// NewStatsRepository creates a new stats repository
+func NewStatsRepository(db *gorm.DB) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// UserCounts counts users by account state
+func (r *StatsRepository) UserCounts() (models.UserCounts, error) {
+	var counts models.UserCounts
+	err := r.db.Model(&models.User{}).Select(
+		"COUNT(*) AS total, " +
+			"COUNT(*) FILTER (WHERE deactivated_at IS NULL) AS active, " +
+			"COUNT(*) FILTER (WHERE deactivated_at IS NOT NULL) AS deactivated, " +
+			"COUNT(*) FILTER (WHERE is_admin) AS admins",
+	).Scan(&counts).Error
+	return counts, err
+}
+
+// TaskCountsByStatus counts tasks grouped by status
+func (r *StatsRepository) TaskCountsByStatus() (map[models.TaskStatus]int64, error) {
+	var rows []struct {
+		Status models.TaskStatus
+		Count  int64
+	}
+	err := r.db.Model(&models.Task{}).Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[models.TaskStatus]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
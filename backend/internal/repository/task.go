@@ -3,10 +3,12 @@ package repository
 import (
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // TaskRepository handles database operations for tasks
@@ -19,32 +21,33 @@ func NewTaskRepository(db *gorm.DB) *TaskRepository {
 	return &TaskRepository{db: db}
 }
 
-// Create creates a new task
-func (r *TaskRepository) Create(task *models.Task) error {
-	return r.db.Create(task).Error
+// Create creates a new task and records a created event
+func (r *TaskRepository) Create(task *models.Task, actorID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(task).Error; err != nil {
+			return err
+		}
+		return recordTaskEvent(tx, models.TaskEventCreated, task.ID, actorID, nil)
+	})
 }
 
 // FindByID finds a task by ID
 func (r *TaskRepository) FindByID(id uuid.UUID) (*models.Task, error) {
-	var task models.Task
-	err := r.db.Preload("Creator").Preload("Assignee").Where("id = ?", id).First(&task).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return &task, nil
+	return findTask(r.db, id)
 }
 
-// Update updates a task
-func (r *TaskRepository) Update(task *models.Task) error {
-	return r.db.Save(task).Error
+// Update updates a task and records an updated event
+func (r *TaskRepository) Update(task *models.Task, actorID uuid.UUID) error {
+	return r.mutate(models.TaskEventUpdated, task.ID, actorID, func(tx *gorm.DB) error {
+		return tx.Save(task).Error
+	})
 }
 
-// Delete deletes a task
-func (r *TaskRepository) Delete(id uuid.UUID) error {
-	return r.db.Delete(&models.Task{}, id).Error
+// Delete deletes a task and records a deleted event
+func (r *TaskRepository) Delete(id, actorID uuid.UUID) error {
+	return r.mutate(models.TaskEventDeleted, id, actorID, func(tx *gorm.DB) error {
+		return tx.Delete(&models.Task{}, id).Error
+	})
 }
 
 // List lists tasks with filters and pagination
@@ -160,12 +163,107 @@ func (r *TaskRepository) List(filter models.TaskFilter) ([]models.Task, int64, e
 	return tasks, total, nil
 }
 
-// UpdateStatus updates only the status of a task
-func (r *TaskRepository) UpdateStatus(id uuid.UUID, status models.TaskStatus) error {
-	return r.db.Model(&models.Task{}).Where("id = ?", id).Update("status", status).Error
+// UpdateStatus updates only the status of a task and records an updated event
+func (r *TaskRepository) UpdateStatus(id uuid.UUID, status models.TaskStatus, actorID uuid.UUID) error {
+	return r.mutate(models.TaskEventUpdated, id, actorID, func(tx *gorm.DB) error {
+		completedAt := gorm.Expr("NULL")
+		if status == models.TaskStatusCompleted {
+			completedAt = gorm.Expr("COALESCE(completed_at, ?)", time.Now())
+		}
+		return tx.Model(&models.Task{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"status":       status,
+			"completed_at": completedAt,
+		}).Error
+	})
+}
+
+// AssignTask assigns a task to a user and records an assigned event
+func (r *TaskRepository) AssignTask(taskID, userID, actorID uuid.UUID) error {
+	return r.mutate(models.TaskEventAssigned, taskID, actorID, func(tx *gorm.DB) error {
+		return tx.Model(&models.Task{}).Where("id = ?", taskID).Updates(map[string]interface{}{
+			"assigned_to": userID,
+			"assigned_at": time.Now(),
+		}).Error
+	})
+}
+
+// ListCollaborators lists the users who share a task with the user: the
+// creators and assignees of the tasks they created or are assigned. The user
+// is left out.
+func (r *TaskRepository) ListCollaborators(userID uuid.UUID) ([]uuid.UUID, error) {
+	var pairs []struct {
+		CreatedBy  uuid.UUID
+		AssignedTo *uuid.UUID
+	}
+	err := r.db.Model(&models.Task{}).
+		Distinct("created_by", "assigned_to").
+		Where("created_by = ? OR assigned_to = ?", userID, userID).
+		Scan(&pairs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[uuid.UUID]bool{userID: true}
+	var collaborators []uuid.UUID
+	for _, pair := range pairs {
+		for _, id := range []*uuid.UUID{&pair.CreatedBy, pair.AssignedTo} {
+			if id != nil && !seen[*id] {
+				seen[*id] = true
+				collaborators = append(collaborators, *id)
+			}
+		}
+	}
+	return collaborators, nil
+}
+
+// mutate changes a task and records the event in one transaction. The task
+// row is locked first so the event captures the state right before the change.
+func (r *TaskRepository) mutate(eventType string, taskID, actorID uuid.UUID, change func(tx *gorm.DB) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		previous, err := findTask(tx.Clauses(clause.Locking{Strength: "UPDATE"}), taskID)
+		if err != nil {
+			return err
+		}
+		if err := change(tx); err != nil {
+			return err
+		}
+		return recordTaskEvent(tx, eventType, taskID, actorID, previous)
+	})
+}
+
+// outboxLockKey is the advisory lock that orders outbox writes
+const outboxLockKey = 7_468_540_001
+
+// recordTaskEvent writes an event to the outbox with the current state of the
+// task, which is nil once deleted. Writers take a transaction-scoped lock
+// first, so events are committed in ID order and the dispatcher never sees a
+// lower ID appear after a higher one went out.
+func recordTaskEvent(tx *gorm.DB, eventType string, taskID, actorID uuid.UUID, previous *models.Task) error {
+	task, err := findTask(tx, taskID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", outboxLockKey).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.OutboxEvent{
+		Type:     eventType,
+		TaskID:   taskID,
+		ActorID:  actorID,
+		Task:     task,
+		Previous: previous,
+	}).Error
 }
 
-// AssignTask assigns a task to a user
-func (r *TaskRepository) AssignTask(taskID, userID uuid.UUID) error {
-	return r.db.Model(&models.Task{}).Where("id = ?", taskID).Update("assigned_to", userID).Error
+// findTask loads a task with its creator and assignee, or nil when missing
+func findTask(db *gorm.DB, id uuid.UUID) (*models.Task, error) {
+	var task models.Task
+	err := db.Preload("Creator").Preload("Assignee").Where("id = ?", id).First(&task).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &task, nil
 }
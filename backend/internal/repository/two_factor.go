@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TwoFactorChallengeRepository handles database operations for 2FA login challenges
+type TwoFactorChallengeRepository struct {
+	db *gorm.DB
+}
+
+// NewTwoFactorChallengeRepository creates a new two-factor challenge repository
+func NewTwoFactorChallengeRepository(db *gorm.DB) *TwoFactorChallengeRepository {
+	return &TwoFactorChallengeRepository{db: db}
+}
+
+// Create records a new challenge
+func (r *TwoFactorChallengeRepository) Create(challenge *models.TwoFactorChallenge) error {
+	return r.db.Create(challenge).Error
+}
+
+// FindByID finds a challenge by its jti
+func (r *TwoFactorChallengeRepository) FindByID(id uuid.UUID) (*models.TwoFactorChallenge, error) {
+	var challenge models.TwoFactorChallenge
+	err := r.db.Where("id = ?", id).First(&challenge).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// RecordFailure counts a wrong code sent for a challenge
+func (r *TwoFactorChallengeRepository) RecordFailure(id uuid.UUID) error {
+	return r.db.Model(&models.TwoFactorChallenge{}).Where("id = ?", id).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+// Consume marks a challenge as used. It reports false when the challenge was
+// already used, has expired or ran out of attempts, so concurrent requests
+// cannot both succeed.
+func (r *TwoFactorChallengeRepository) Consume(id uuid.UUID, maxAttempts int, now time.Time) (bool, error) {
+	result := r.db.Model(&models.TwoFactorChallenge{}).
+		Where("id = ? AND used_at IS NULL AND attempts < ? AND expires_at > ?", id, maxAttempts, now).
+		Update("used_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountFailures counts the wrong codes a user sent across the challenges
+// created since the given time
+func (r *TwoFactorChallengeRepository) CountFailures(userID uuid.UUID, since time.Time) (int, error) {
+	var failures int
+	err := r.db.Model(&models.TwoFactorChallenge{}).
+		Select("COALESCE(SUM(attempts), 0)").
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Scan(&failures).Error
+	return failures, err
+}
+
+// DeleteExpiredByUser removes a user's challenges that expired before the given time
+func (r *TwoFactorChallengeRepository) DeleteExpiredByUser(userID uuid.UUID, before time.Time) error {
+	return r.db.Where("user_id = ? AND expires_at < ?", userID, before).Delete(&models.TwoFactorChallenge{}).Error
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
 	"github.com/google/uuid"
@@ -23,10 +24,15 @@ func (r *UserRepository) Create(user *models.User) error {
 	return r.db.Create(user).Error
 }
 
-// FindByEmail finds a user by email
+// Update updates a user
+func (r *UserRepository) Update(user *models.User) error {
+	return r.db.Save(user).Error
+}
+
+// FindByEmail finds a user by email, ignoring case
 func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := r.db.Where("LOWER(email) = ?", models.NormalizeEmail(email)).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -49,16 +55,68 @@ func (r *UserRepository) FindByID(id uuid.UUID) (*models.User, error) {
 	return &user, nil
 }
 
-// EmailExists checks if an email already exists
+// FindByEmailVerificationToken finds a user by the hash of a pending email verification token
+func (r *UserRepository) FindByEmailVerificationToken(hash string) (*models.User, error) {
+	var user models.User
+	err := r.db.Where("email_verification_token_hash = ?", hash).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// EmailExists checks if an email already exists, ignoring case
 func (r *UserRepository) EmailExists(email string) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.User{}).Where("email = ?", email).Count(&count).Error
+	err := r.db.Model(&models.User{}).Where("LOWER(email) = ?", models.NormalizeEmail(email)).Count(&count).Error
 	return count > 0, err
 }
 
-// List lists all users
-func (r *UserRepository) List() ([]models.User, error) {
+// List lists users matching the filter, ordered by name, with pagination
+func (r *UserRepository) List(filter models.UserFilter) ([]models.User, int64, error) {
 	var users []models.User
-	err := r.db.Find(&users).Error
-	return users, err
+	var total int64
+
+	query := r.db.Model(&models.User{})
+	if filter.OnlyDeactivated {
+		query = query.Where("deactivated_at IS NOT NULL")
+	} else if !filter.IncludeDeactivated {
+		query = query.Where("deactivated_at IS NULL")
+	}
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		prefix := escapeLike(q) + "%"
+		query = query.Where("name ILIKE ? OR name ILIKE ? OR email ILIKE ?", prefix, "% "+prefix, prefix)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.
+		Order("name ASC").
+		Order("id ASC").
+		Limit(filter.PageSize).
+		Offset((filter.Page - 1) * filter.PageSize).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// PromoteAdmins grants the admin flag to the users with the given emails
+func (r *UserRepository) PromoteAdmins(emails []string) error {
+	if len(emails) == 0 {
+		return nil
+	}
+	return r.db.Model(&models.User{}).Where("LOWER(email) IN ?", emails).Update("is_admin", true).Error
+}
+
+// escapeLike escapes LIKE wildcards so user input matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
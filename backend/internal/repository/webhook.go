@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookRepository handles database operations for webhooks and their
+// deliveries
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// Create creates a new webhook
+func (r *WebhookRepository) Create(webhook *models.Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+// FindByID finds a webhook by ID
+func (r *WebhookRepository) FindByID(id uuid.UUID) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := r.db.Where("id = ?", id).First(&webhook).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// ListByUser lists a user's webhooks, newest first
+func (r *WebhookRepository) ListByUser(userID uuid.UUID) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// ListActiveByUsers lists the active webhooks owned by any of the users,
+// leaving out those of deactivated or deleted accounts
+func (r *WebhookRepository) ListActiveByUsers(userIDs []uuid.UUID) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if len(userIDs) == 0 {
+		return webhooks, nil
+	}
+	err := r.db.
+		Joins("JOIN users ON users.id = webhooks.user_id").
+		Where("webhooks.user_id IN ? AND webhooks.active = ?", userIDs, true).
+		Where("users.deactivated_at IS NULL AND users.anonymized_at IS NULL").
+		Find(&webhooks).Error
+	return webhooks, err
+}
+
+// SetActiveByUser enables or disables all of a user's webhooks
+func (r *WebhookRepository) SetActiveByUser(userID uuid.UUID, active bool) error {
+	return r.db.Model(&models.Webhook{}).Where("user_id = ?", userID).Update("active", active).Error
+}
+
+// Delete deletes a webhook and its delivery log
+func (r *WebhookRepository) Delete(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&models.Webhook{}).Error
+	})
+}
+
+// CreateDelivery queues a delivery, unless the webhook already has one for
+// the same event
+func (r *WebhookRepository) CreateDelivery(delivery *models.WebhookDelivery) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(delivery).Error
+}
+
+// FindDelivery finds a delivery by ID
+func (r *WebhookRepository) FindDelivery(id uuid.UUID) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.db.Where("id = ?", id).First(&delivery).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ListDeliveries lists the latest deliveries of a webhook, newest first
+func (r *WebhookRepository) ListDeliveries(webhookID uuid.UUID, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Where("webhook_id = ?", webhookID).Order("created_at DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimDueDeliveries returns up to limit pending deliveries whose next attempt
+// is due, with their webhook, and pushes their next attempt back by lease so
+// that other instances skip them while they are being sent
+func (r *WebhookRepository) ClaimDueDeliveries(now time.Time, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(deliveries))
+		for i := range deliveries {
+			ids[i] = deliveries[i].ID
+		}
+		leaseUntil := now.Add(lease)
+		if err := tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", leaseUntil).Error; err != nil {
+			return err
+		}
+		var claimed []models.WebhookDelivery
+		if err := tx.Preload("Webhook").Where("id IN ?", ids).Order("created_at").Find(&claimed).Error; err != nil {
+			return err
+		}
+		deliveries = claimed
+		return nil
+	})
+	return deliveries, err
+}
+
+// UpdateDelivery saves the outcome of a delivery attempt
+func (r *WebhookRepository) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	return r.db.Model(delivery).Select("status", "attempts", "next_attempt_at", "last_attempt_at", "response_status", "error").Updates(delivery).Error
+}
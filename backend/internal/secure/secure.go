@@ -0,0 +1,34 @@
+// Package secure generates random tokens and hashes them for storage
+package secure
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// Bytes returns n random bytes from crypto/rand
+func Bytes(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Token returns a URL-safe random string encoding n random bytes
+func Token(n int) (string, error) {
+	buf, err := Bytes(n)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Hash returns the hex SHA-256 of a token, so tokens can be stored and looked
+// up without keeping them
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/storage"
+	"github.com/google/uuid"
+)
+
+// How a deleted account's tasks are handled
+const (
+	TaskHandlingReassign  = "reassign"
+	TaskHandlingAnonymize = "anonymize"
+)
+
+// deletedUserName replaces the name of deleted accounts
+const deletedUserName = "Deleted user"
+
+// AccountRepository interface for account service
+type AccountRepository interface {
+	ListCreatedTasks(userID uuid.UUID) ([]models.Task, error)
+	ListAssignedTasks(userID uuid.UUID) ([]models.Task, error)
+	ListIdentities(userID uuid.UUID) ([]models.UserIdentity, error)
+	ListWebhooks(userID uuid.UUID) ([]models.Webhook, error)
+	ListNotifications(userID uuid.UUID) ([]models.Notification, error)
+	ListSentInvitations(userID uuid.UUID) ([]models.Invitation, error)
+	FindNotificationPreferences(userID uuid.UUID) (*models.NotificationPreferences, error)
+	ListDevices(userID uuid.UUID) ([]models.DeviceToken, error)
+	DeleteAccount(user *models.User, reassignTo *uuid.UUID) error
+}
+
+// AccountService handles self-service data export and account deletion
+type AccountService struct {
+	accountRepo AccountRepository
+	userRepo    UserRepository
+	tokenRepo   PersonalAccessTokenRepository
+	storage     storage.Storage
+}
+
+// NewAccountService creates a new account service
+func NewAccountService(accountRepo AccountRepository, userRepo UserRepository, tokenRepo PersonalAccessTokenRepository, storage storage.Storage) *AccountService {
+	return &AccountService{
+		accountRepo: accountRepo,
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		storage:     storage,
+	}
+}
+
+// avatarURL resolves avatar URLs through the storage, if any
+func (s *AccountService) avatarURL(key string) string {
+	if s.storage == nil {
+		return key
+	}
+	return s.storage.URL(key)
+}
+
+// DeleteAccountRequest represents a request to delete the caller's account
+type DeleteAccountRequest struct {
+	Password   string     `json:"password" binding:"required"`
+	Tasks      string     `json:"tasks" binding:"required,oneof=reassign anonymize"`
+	ReassignTo *uuid.UUID `json:"reassign_to"`
+}
+
+// Export returns everything the user created. Webhook secrets and token
+// hashes are left out.
+func (s *AccountService) Export(userID uuid.UUID) (*models.AccountExport, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.accountRepo.ListCreatedTasks(userID)
+	if err != nil {
+		return nil, err
+	}
+	assigned, err := s.accountRepo.ListAssignedTasks(userID)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := s.tokenRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := s.accountRepo.ListIdentities(userID)
+	if err != nil {
+		return nil, err
+	}
+	webhooks, err := s.accountRepo.ListWebhooks(userID)
+	if err != nil {
+		return nil, err
+	}
+	notifications, err := s.accountRepo.ListNotifications(userID)
+	if err != nil {
+		return nil, err
+	}
+	invitations, err := s.accountRepo.ListSentInvitations(userID)
+	if err != nil {
+		return nil, err
+	}
+	prefs, err := s.accountRepo.FindNotificationPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	devices, err := s.accountRepo.ListDevices(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Always encode empty lists as [] rather than null
+	if created == nil {
+		created = []models.Task{}
+	}
+	if assigned == nil {
+		assigned = []models.Task{}
+	}
+	if tokens == nil {
+		tokens = []models.PersonalAccessToken{}
+	}
+	if identities == nil {
+		identities = []models.UserIdentity{}
+	}
+	if webhooks == nil {
+		webhooks = []models.Webhook{}
+	}
+	if notifications == nil {
+		notifications = []models.Notification{}
+	}
+	if invitations == nil {
+		invitations = []models.Invitation{}
+	}
+	if prefs == nil {
+		prefs = models.DefaultNotificationPreferences(userID)
+	}
+	if devices == nil {
+		devices = []models.DeviceToken{}
+	}
+
+	return &models.AccountExport{
+		ExportedAt:           time.Now().UTC(),
+		User:                 user.ToResponse(s.avatarURL),
+		CreatedTasks:         created,
+		AssignedTasks:        assigned,
+		PersonalAccessTokens: tokens,
+		Identities:           identities,
+		Webhooks:             webhooks,
+		Notifications:        notifications,
+		SentInvitations:      invitations,
+		NotificationPrefs:    *prefs,
+		Devices:              devices,
+	}, nil
+}
+
+// DeleteAccount deletes the caller's account. Personal data is erased and the
+// user row is kept as an anonymized, deactivated placeholder so task history
+// stays consistent. Created tasks are either reassigned to another active user
+// or left attributed to the placeholder.
+func (s *AccountService) DeleteAccount(ctx context.Context, userID uuid.UUID, req DeleteAccountRequest) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.CheckPassword(req.Password) {
+		return errors.New("password is incorrect")
+	}
+
+	var reassignTo *uuid.UUID
+	switch req.Tasks {
+	case TaskHandlingReassign:
+		if req.ReassignTo == nil {
+			return errors.New("reassign_to is required to reassign tasks")
+		}
+		if *req.ReassignTo == userID {
+			return errors.New("cannot reassign tasks to the account being deleted")
+		}
+		target, err := s.userRepo.FindByID(*req.ReassignTo)
+		if err != nil {
+			return err
+		}
+		if target == nil || !target.IsActive() {
+			return errors.New("reassign_to must be an active user")
+		}
+		reassignTo = &target.ID
+	case TaskHandlingAnonymize:
+	default:
+		return errors.New("tasks must be reassign or anonymize")
+	}
+
+	avatar := user.AvatarKey
+	anonymize(user)
+	if err := s.accountRepo.DeleteAccount(user, reassignTo); err != nil {
+		return err
+	}
+
+	if avatar != "" && s.storage != nil {
+		for size := range models.AvatarSizes {
+			if err := s.storage.Delete(ctx, models.AvatarObjectKey(avatar, size)); err != nil {
+				log.Printf("Failed to delete avatar of deleted user %s: %v", user.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// anonymize erases the personal data of a user and deactivates the account
+func anonymize(user *models.User) {
+	now := time.Now()
+	user.Email = fmt.Sprintf("deleted-%s@deleted.invalid", user.ID)
+	user.Name = deletedUserName
+	user.Password = "!" // Not a valid bcrypt hash, so no password matches
+	user.AvatarKey = ""
+	user.IsAdmin = false
+	user.PendingEmail = ""
+	user.EmailVerificationTokenHash = ""
+	user.EmailVerificationExpiresAt = nil
+	user.TOTPSecret = ""
+	user.TOTPEnabled = false
+	user.TOTPLastStep = 0
+	user.RecoveryCodes = ""
+	user.DeactivatedAt = &now
+	user.AnonymizedAt = &now
+}
+
+func (s *AccountService) findUser(userID uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
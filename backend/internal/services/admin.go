@@ -0,0 +1,247 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/secure"
+	"github.com/google/uuid"
+)
+
+// StatsRepository interface for admin service
+type StatsRepository interface {
+	UserCounts() (models.UserCounts, error)
+	TaskCountsByStatus() (map[models.TaskStatus]int64, error)
+}
+
+// ConnectionRegistry tracks live realtime connections
+type ConnectionRegistry interface {
+	Metrics() models.RealtimeMetrics
+	DisconnectUser(userID uuid.UUID) int
+}
+
+// AdminService handles system administration
+type AdminService struct {
+	userRepo    UserRepository
+	tokenRepo   PersonalAccessTokenRepository
+	webhookRepo WebhookRepository
+	statsRepo   StatsRepository
+	connections ConnectionRegistry
+}
+
+// NewAdminService creates a new admin service
+func NewAdminService(userRepo UserRepository, tokenRepo PersonalAccessTokenRepository, webhookRepo WebhookRepository, statsRepo StatsRepository, connections ConnectionRegistry) *AdminService {
+	return &AdminService{
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		webhookRepo: webhookRepo,
+		statsRepo:   statsRepo,
+		connections: connections,
+	}
+}
+
+// UpdateUserRequest represents an admin update of a user
+type UpdateUserRequest struct {
+	IsAdmin *bool `json:"is_admin" binding:"required"`
+}
+
+// ResetPasswordResponse carries the temporary password of a reset account
+type ResetPasswordResponse struct {
+	TemporaryPassword string `json:"temporary_password"`
+}
+
+// ListUsers lists all users, including deactivated ones unless filtered
+func (s *AdminService) ListUsers(filter *models.UserFilter) ([]models.User, int64, error) {
+	filter.Normalize()
+	return s.userRepo.List(*filter)
+}
+
+// GetUser gets a user by ID
+func (s *AdminService) GetUser(userID uuid.UUID) (*models.User, error) {
+	return s.findUser(userID)
+}
+
+// UpdateUser grants or revokes the admin flag
+func (s *AdminService) UpdateUser(actorID, userID uuid.UUID, req UpdateUserRequest) (*models.User, error) {
+	if actorID == userID && !*req.IsAdmin {
+		return nil, errors.New("cannot revoke your own admin access")
+	}
+
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.AnonymizedAt != nil {
+		return nil, errors.New("deleted accounts cannot be modified")
+	}
+
+	user.IsAdmin = *req.IsAdmin
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ResetPassword replaces the user's password with a random temporary one, which
+// is returned once, and signs them out everywhere. The user must replace the
+// temporary password when signing in with it.
+func (s *AdminService) ResetPassword(userID uuid.UUID) (*ResetPasswordResponse, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.AnonymizedAt != nil {
+		return nil, errors.New("deleted accounts cannot be modified")
+	}
+
+	password, err := secure.Token(12)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Password = password
+	if err := user.HashPassword(); err != nil {
+		return nil, err
+	}
+	user.MustChangePassword = true
+	if err := s.revokeSessions(user); err != nil {
+		return nil, err
+	}
+
+	return &ResetPasswordResponse{TemporaryPassword: password}, nil
+}
+
+// ForceLogout invalidates every access and refresh token of the user, revokes
+// their personal access tokens and closes their realtime connections
+func (s *AdminService) ForceLogout(userID uuid.UUID) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	return s.revokeSessions(user)
+}
+
+// Stats returns system-wide counters
+func (s *AdminService) Stats() (*models.SystemStats, error) {
+	users, err := s.statsRepo.UserCounts()
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.statsRepo.TaskCountsByStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.SystemStats{
+		Users:         users,
+		TasksByStatus: make(map[models.TaskStatus]int64),
+	}
+	// Report every status, including those without tasks
+	for _, status := range []models.TaskStatus{models.TaskStatusPending, models.TaskStatusInProgress, models.TaskStatusCompleted, models.TaskStatusCancelled} {
+		stats.TasksByStatus[status] = 0
+	}
+	for status, count := range tasks {
+		stats.TasksByStatus[status] = count
+		stats.TasksTotal += count
+	}
+	if s.connections != nil {
+		metrics := s.connections.Metrics()
+		stats.WebSocketClients = metrics.Clients
+		stats.Realtime = &metrics
+	}
+	return stats, nil
+}
+
+// IsAdmin reports whether a user is an active system administrator
+func (s *AdminService) IsAdmin(userID uuid.UUID) (bool, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return false, err
+	}
+	return user != nil && user.IsAdmin && user.IsActive(), nil
+}
+
+// DeactivateUser blocks a user from signing in and disables their webhooks.
+// Their tasks and history are kept.
+func (s *AdminService) DeactivateUser(actorID, userID uuid.UUID) (*models.User, error) {
+	if actorID == userID {
+		return nil, errors.New("cannot deactivate your own account")
+	}
+
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive() {
+		return user, nil
+	}
+
+	now := time.Now()
+	user.DeactivatedAt = &now
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	if err := s.webhookRepo.SetActiveByUser(user.ID, false); err != nil {
+		return nil, err
+	}
+	if s.connections != nil {
+		s.connections.DisconnectUser(user.ID)
+	}
+	return user, nil
+}
+
+// ReactivateUser lets a deactivated user sign in again and enables their
+// webhooks
+func (s *AdminService) ReactivateUser(userID uuid.UUID) (*models.User, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.AnonymizedAt != nil {
+		return nil, errors.New("deleted accounts cannot be reactivated")
+	}
+	if user.IsActive() {
+		return user, nil
+	}
+
+	user.DeactivatedAt = nil
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	if err := s.webhookRepo.SetActiveByUser(user.ID, true); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// revokeSessions saves the user with a new token cutoff, then revokes personal
+// access tokens and closes realtime connections
+func (s *AdminService) revokeSessions(user *models.User) error {
+	// Token timestamps have second precision, so round up to reject tokens
+	// issued earlier within the same second
+	cutoff := time.Now().Truncate(time.Second).Add(time.Second)
+	user.TokensValidAfter = &cutoff
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	if err := s.tokenRepo.DeleteByUser(user.ID); err != nil {
+		return err
+	}
+	if s.connections != nil {
+		s.connections.DisconnectUser(user.ID)
+	}
+	return nil
+}
+
+func (s *AdminService) findUser(userID uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
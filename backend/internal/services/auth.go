@@ -2,36 +2,59 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/jwtkeys"
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/middleware"
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
-// AuthService handles authentication business logic
+// ErrAccountDeactivated is returned when a deactivated user tries to sign in
+// or use an existing session
+var ErrAccountDeactivated = errors.New("account is deactivated")
+
+// ErrPasswordChangeRequired is returned when a user signs in with a temporary
+// password without choosing a new one
+var ErrPasswordChangeRequired = errors.New("password change required")
+
 // UserRepository interface for auth service
 type UserRepository interface {
 	Create(user *models.User) error
 	FindByEmail(email string) (*models.User, error)
 	FindByID(id uuid.UUID) (*models.User, error)
+	Update(user *models.User) error
+	FindByEmailVerificationToken(hash string) (*models.User, error)
 	EmailExists(email string) (bool, error)
-	List() ([]models.User, error)
+	List(filter models.UserFilter) ([]models.User, int64, error)
 }
 
 // AuthService handles authentication business logic
 type AuthService struct {
-	userRepo UserRepository
-	config   *config.Config
+	userRepo    UserRepository
+	challenges  TwoFactorChallengeRepository
+	keys        *jwtkeys.KeySet
+	invitations *InvitationService
+	avatarURL   models.AvatarURLResolver
+	config      *config.Config
 }
 
-// NewAuthService creates a new auth service
-func NewAuthService(userRepo UserRepository, cfg *config.Config) *AuthService {
+// NewAuthService creates a new auth service. Challenges records the 2FA login
+// challenges handed out. Invitations may be nil, in which case registering
+// with an invite token is rejected. AvatarURL resolves the avatar URLs of the
+// user returned on sign-in.
+func NewAuthService(userRepo UserRepository, challenges TwoFactorChallengeRepository, keys *jwtkeys.KeySet, invitations *InvitationService, avatarURL models.AvatarURLResolver, cfg *config.Config) *AuthService {
 	return &AuthService{
-		userRepo: userRepo,
-		config:   cfg,
+		userRepo:    userRepo,
+		challenges:  challenges,
+		keys:        keys,
+		invitations: invitations,
+		avatarURL:   avatarURL,
+		config:      cfg,
 	}
 }
 
@@ -40,19 +63,31 @@ type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
 	Name     string `json:"name" binding:"required,min=2"`
+
+	// InviteToken redeems an invitation sent to Email
+	InviteToken string `json:"invite_token"`
 }
 
 // LoginRequest represents a login request
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	// Replaces a temporary password set by an admin; required in that case
+	NewPassword string `json:"new_password" binding:"omitempty,min=6"`
 }
 
-// AuthResponse represents an authentication response
+// AuthResponse represents an authentication response. When the user has
+// two-factor authentication enabled, Login returns only a challenge token that
+// must be exchanged for real tokens with VerifyTwoFactor.
 type AuthResponse struct {
-	User         models.UserResponse `json:"user"`
-	Token        string              `json:"token"`
-	RefreshToken string              `json:"refresh_token"`
+	User              *models.UserResponse `json:"user,omitempty"`
+	Token             string               `json:"token,omitempty"`
+	RefreshToken      string               `json:"refresh_token,omitempty"`
+	TwoFactorRequired bool                 `json:"two_factor_required,omitempty"`
+	ChallengeToken    string               `json:"challenge_token,omitempty"`
+	// Set with a challenge when the user signed in with a temporary password:
+	// the new password goes to VerifyTwoFactor together with the code
+	PasswordChangeRequired bool `json:"password_change_required,omitempty"`
 }
 
 // Register registers a new user
@@ -72,11 +107,22 @@ func (s *AuthService) Register(req RegisterRequest) (*AuthResponse, error) {
 		return nil, errors.New("email already registered")
 	}
 
+	var invitation *models.Invitation
+	if req.InviteToken != "" {
+		if s.invitations == nil {
+			return nil, ErrInvalidInvitation
+		}
+		if invitation, err = s.invitations.Validate(req.InviteToken, req.Email); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create user
 	user := &models.User{
-		Email:    req.Email,
+		Email:    models.NormalizeEmail(req.Email),
 		Password: req.Password,
 		Name:     req.Name,
+		IsAdmin:  s.isAdminEmail(req.Email),
 	}
 
 	// Hash password
@@ -89,22 +135,15 @@ func (s *AuthService) Register(req RegisterRequest) (*AuthResponse, error) {
 		return nil, err
 	}
 
-	// Generate tokens
-	token, err := s.generateToken(user.ID, user.Email, false)
-	if err != nil {
-		return nil, err
+	// The account exists at this point: a failed redemption is reported, and
+	// the user can sign in without the invited role
+	if invitation != nil {
+		if err := s.invitations.Redeem(invitation, user); err != nil {
+			return nil, fmt.Errorf("account created, but the invitation could not be redeemed: %w", err)
+		}
 	}
 
-	refreshToken, err := s.generateToken(user.ID, user.Email, true)
-	if err != nil {
-		return nil, err
-	}
-
-	return &AuthResponse{
-		User:         user.ToResponse(),
-		Token:        token,
-		RefreshToken: refreshToken,
-	}, nil
+	return s.issueTokens(user)
 }
 
 // Login authenticates a user
@@ -123,61 +162,191 @@ func (s *AuthService) Login(req LoginRequest) (*AuthResponse, error) {
 		return nil, errors.New("invalid email or password")
 	}
 
-	// Generate tokens
-	token, err := s.generateToken(user.ID, user.Email, false)
+	// With 2FA the temporary password is only replaced once the second step
+	// succeeds, so the password alone cannot take over the account
+	if user.MustChangePassword && !user.TOTPEnabled {
+		if err := s.checkNewPassword(user, req.NewPassword); err != nil {
+			return nil, err
+		}
+		if err := s.replaceTemporaryPassword(user, req.NewPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	response, err := s.CompleteLogin(user)
+	if err != nil {
+		return nil, err
+	}
+	response.PasswordChangeRequired = response.TwoFactorRequired && user.MustChangePassword
+	return response, nil
+}
+
+// checkNewPassword validates the password chosen by a user signing in with a
+// temporary one
+func (s *AuthService) checkNewPassword(user *models.User, newPassword string) error {
+	if !user.IsActive() {
+		return ErrAccountDeactivated
+	}
+	if newPassword == "" {
+		return ErrPasswordChangeRequired
+	}
+	if len(newPassword) < 6 {
+		return errors.New("password must be at least 6 characters")
+	}
+	if user.CheckPassword(newPassword) {
+		return errors.New("new password must differ from the temporary one")
+	}
+	return nil
+}
+
+// replaceTemporaryPassword sets the new password of a user signing in with a
+// temporary one, so the temporary password works only once
+func (s *AuthService) replaceTemporaryPassword(user *models.User, newPassword string) error {
+
+	user.Password = newPassword
+	if err := user.HashPassword(); err != nil {
+		return err
+	}
+	user.MustChangePassword = false
+	return s.userRepo.Update(user)
+}
+
+// CompleteLogin issues tokens for a user whose primary credentials have been
+// verified. Users with 2FA must complete a second step before receiving tokens.
+func (s *AuthService) CompleteLogin(user *models.User) (*AuthResponse, error) {
+	if !user.IsActive() {
+		return nil, ErrAccountDeactivated
+	}
+
+	if user.TOTPEnabled {
+		challenge, err := s.issueChallenge(user)
+		if err != nil {
+			return nil, err
+		}
+		return &AuthResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		}, nil
+	}
+
+	return s.issueTokens(user)
+}
+
+// RefreshToken refreshes an access token
+func (s *AuthService) RefreshToken(refreshToken string) (string, error) {
+	claims, err := s.parseToken(refreshToken)
+	if err != nil || (claims.TokenType != "" && claims.TokenType != middleware.TokenTypeRefresh) {
+		return "", errors.New("invalid refresh token")
+	}
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	if err := s.ValidateSession(claims.UserID, issuedAt); err != nil {
+		return "", err
+	}
+
+	// Generate new access token
+	newToken, err := s.generateToken(claims.UserID, claims.Email, middleware.TokenTypeAccess)
+	if err != nil {
+		return "", err
+	}
+
+	return newToken, nil
+}
+
+// ValidateSession checks that a token issued at issuedAt still grants access
+// for the user. It is called for every JWT-authenticated request.
+func (s *AuthService) ValidateSession(userID uuid.UUID, issuedAt time.Time) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+	if !user.IsActive() {
+		return ErrAccountDeactivated
+	}
+	if user.TokensValidAfter != nil && issuedAt.Before(*user.TokensValidAfter) {
+		return errors.New("session has been revoked")
+	}
+	return nil
+}
+
+// JWKS returns the public keys used to verify tokens
+func (s *AuthService) JWKS() jwtkeys.JWKS {
+	return s.keys.JWKS()
+}
+
+// issueTokens generates the access and refresh tokens for an authenticated user
+func (s *AuthService) issueTokens(user *models.User) (*AuthResponse, error) {
+	if !user.IsActive() {
+		return nil, ErrAccountDeactivated
+	}
+
+	token, err := s.generateToken(user.ID, user.Email, middleware.TokenTypeAccess)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := s.generateToken(user.ID, user.Email, true)
+	refreshToken, err := s.generateToken(user.ID, user.Email, middleware.TokenTypeRefresh)
 	if err != nil {
 		return nil, err
 	}
 
+	response := user.ToResponse(s.avatarURL)
 	return &AuthResponse{
-		User:         user.ToResponse(),
+		User:         &response,
 		Token:        token,
 		RefreshToken: refreshToken,
 	}, nil
 }
 
-// RefreshToken refreshes an access token
-func (s *AuthService) RefreshToken(refreshToken string) (string, error) {
-	claims := &middleware.Claims{}
+// isAdminEmail reports whether the email is listed in ADMIN_EMAILS
+func (s *AuthService) isAdminEmail(email string) bool {
+	for _, admin := range s.config.Admin.Emails {
+		if strings.EqualFold(admin, email) {
+			return true
+		}
+	}
+	return false
+}
 
-	token, err := jwt.ParseWithClaims(refreshToken, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.config.JWT.Secret), nil
-	})
+// parseToken validates a JWT and returns its claims
+func (s *AuthService) parseToken(tokenString string) (*middleware.Claims, error) {
+	claims := &middleware.Claims{}
 
+	token, err := s.keys.Parse(tokenString, claims)
 	if err != nil || !token.Valid {
-		return "", errors.New("invalid refresh token")
+		return nil, errors.New("invalid token")
 	}
 
-	// Generate new access token
-	newToken, err := s.generateToken(claims.UserID, claims.Email, false)
-	if err != nil {
-		return "", err
-	}
+	return claims, nil
+}
 
-	return newToken, nil
+// generateToken generates a JWT token of the given type
+func (s *AuthService) generateToken(userID uuid.UUID, email string, tokenType string) (string, error) {
+	return s.keys.Sign(s.newClaims(userID, email, tokenType))
 }
 
-// generateToken generates a JWT token
-func (s *AuthService) generateToken(userID uuid.UUID, email string, isRefresh bool) (string, error) {
-	expirationHours := s.config.JWT.ExpirationHours
-	if isRefresh {
-		expirationHours = s.config.JWT.RefreshExpirationHours
+// newClaims builds the claims of a token of the given type
+func (s *AuthService) newClaims(userID uuid.UUID, email string, tokenType string) middleware.Claims {
+	expiration := time.Hour * time.Duration(s.config.JWT.ExpirationHours)
+	switch tokenType {
+	case middleware.TokenTypeRefresh:
+		expiration = time.Hour * time.Duration(s.config.JWT.RefreshExpirationHours)
+	case middleware.TokenTypeTwoFactorChallenge:
+		expiration = time.Minute * time.Duration(s.config.TwoFactor.ChallengeMinutes)
 	}
 
-	claims := middleware.Claims{
-		UserID: userID,
-		Email:  email,
+	return middleware.Claims{
+		UserID:    userID,
+		Email:     email,
+		TokenType: tokenType,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(expirationHours))),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.config.JWT.Secret))
 }
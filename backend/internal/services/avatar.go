@@ -0,0 +1,146 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // Register GIF decoding for uploads
+	"image/jpeg"
+	_ "image/png" // Register PNG decoding for uploads
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/imaging"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/secure"
+	"github.com/google/uuid"
+)
+
+// maxAvatarDimension bounds the width and height of uploaded images so a small
+// file cannot decode into a huge bitmap
+const maxAvatarDimension = 4096
+
+// avatarFormOverhead is the room left in an avatar upload request for the
+// multipart headers and boundaries around the file
+const avatarFormOverhead = 64 << 10
+
+// Avatar upload errors
+var (
+	ErrAvatarTooLarge   = errors.New("avatar exceeds the maximum upload size")
+	ErrUnsupportedImage = errors.New("avatar must be a JPEG, PNG or GIF image")
+	ErrAvatarDimensions = fmt.Errorf("avatar must be at most %dx%d pixels", maxAvatarDimension, maxAvatarDimension)
+)
+
+// allowedAvatarTypes are the sniffed content types accepted for avatars
+var allowedAvatarTypes = map[string]bool{"image/jpeg": true, "image/png": true, "image/gif": true}
+
+// AvatarRequestLimit returns the largest avatar upload request body accepted,
+// so it can be rejected before the multipart form is parsed
+func (s *UserService) AvatarRequestLimit() int64 {
+	return int64(s.config.Storage.AvatarMaxBytes) + avatarFormOverhead
+}
+
+// UpdateAvatar validates the uploaded image, stores it resized to every size in
+// models.AvatarSizes and replaces the user's previous avatar
+func (s *UserService) UpdateAvatar(ctx context.Context, userID uuid.UUID, upload io.Reader) (*models.User, error) {
+	user, err := s.GetProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	maxBytes := int64(s.config.Storage.AvatarMaxBytes)
+	data, err := io.ReadAll(io.LimitReader(upload, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, ErrAvatarTooLarge
+	}
+	if !allowedAvatarTypes[http.DetectContentType(data)] {
+		return nil, ErrUnsupportedImage
+	}
+
+	imgConfig, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if imgConfig.Width > maxAvatarDimension || imgConfig.Height > maxAvatarDimension {
+		return nil, ErrAvatarDimensions
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	version, err := newAvatarVersion(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for size, pixels := range models.AvatarSizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, imaging.Thumbnail(img, pixels), &jpeg.Options{Quality: 85}); err != nil {
+			return nil, err
+		}
+		if err := s.storage.Put(ctx, models.AvatarObjectKey(version, size), &buf, "image/jpeg"); err != nil {
+			s.deleteAvatarFiles(ctx, version)
+			return nil, err
+		}
+	}
+
+	previous := user.AvatarKey
+	user.AvatarKey = version
+	if err := s.userRepo.Update(user); err != nil {
+		s.deleteAvatarFiles(ctx, version)
+		return nil, err
+	}
+
+	s.deleteAvatarFiles(ctx, previous)
+	return user, nil
+}
+
+// DeleteAvatar removes the user's avatar
+func (s *UserService) DeleteAvatar(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := s.GetProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.AvatarKey == "" {
+		return user, nil
+	}
+
+	previous := user.AvatarKey
+	user.AvatarKey = ""
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	s.deleteAvatarFiles(ctx, previous)
+	return user, nil
+}
+
+// deleteAvatarFiles removes every size of an avatar version. Failures only
+// leave orphaned files behind, so they are logged rather than returned.
+func (s *UserService) deleteAvatarFiles(ctx context.Context, version string) {
+	if version == "" {
+		return
+	}
+	for size := range models.AvatarSizes {
+		if err := s.storage.Delete(ctx, models.AvatarObjectKey(version, size)); err != nil {
+			log.Printf("Failed to delete avatar file %s: %v", models.AvatarObjectKey(version, size), err)
+		}
+	}
+}
+
+// newAvatarVersion returns a fresh key prefix so each upload gets new URLs
+// and clients never see a stale cached image
+func newAvatarVersion(userID uuid.UUID) (string, error) {
+	buf, err := secure.Bytes(8)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("avatars/%s/%s", userID, hex.EncodeToString(buf)), nil
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/mailer"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// digestRetention is how long sent digests are remembered, enough to cover a
+// weekly period
+const digestRetention = 14 * 24 * time.Hour
+
+//go:embed templates/digest.html templates/digest.txt
+var digestTemplates embed.FS
+
+var (
+	digestHTML = htmltemplate.Must(htmltemplate.ParseFS(digestTemplates, "templates/digest.html"))
+	digestText = texttemplate.Must(texttemplate.ParseFS(digestTemplates, "templates/digest.txt"))
+)
+
+// digestLabels are the texts of a digest email in one language
+type digestLabels struct {
+	DailySubject, WeeklySubject string
+	Greeting                    string // Formatted with the user's name
+	Intro                       string
+	Overdue, DueToday           string
+	Assigned, Completed         string
+	Due, AssignedTo, AssignedBy string // Task details, formatted with a date or name
+	CompletedBy                 string
+	Footer                      string
+	DateFormat                  string
+}
+
+// digestTexts are the digest texts by language. Users whose locale has no
+// texts get Spanish.
+var digestTexts = map[string]digestLabels{
+	"es": {
+		DailySubject:  "Tu resumen diario de TaskFlow",
+		WeeklySubject: "Tu resumen semanal de TaskFlow",
+		Greeting:      "Hola %s,",
+		Intro:         "Esto es lo que pasa con tus tareas.",
+		Overdue:       "Vencidas",
+		DueToday:      "Vencen hoy",
+		Assigned:      "Nuevas asignaciones",
+		Completed:     "Completadas",
+		Due:           "vence %s",
+		AssignedTo:    "asignada a %s",
+		AssignedBy:    "de %s",
+		CompletedBy:   "por %s",
+		Footer:        "Puedes desactivar este resumen en las preferencias de notificaciones de TaskFlow.",
+		DateFormat:    "02/01 15:04",
+	},
+	"en": {
+		DailySubject:  "Your daily TaskFlow digest",
+		WeeklySubject: "Your weekly TaskFlow digest",
+		Greeting:      "Hi %s,",
+		Intro:         "Here is what is happening with your tasks.",
+		Overdue:       "Overdue",
+		DueToday:      "Due today",
+		Assigned:      "New assignments",
+		Completed:     "Completed",
+		Due:           "due %s",
+		AssignedTo:    "assigned to %s",
+		AssignedBy:    "from %s",
+		CompletedBy:   "by %s",
+		Footer:        "You can turn this digest off in your TaskFlow notification preferences.",
+		DateFormat:    "Jan 2 15:04",
+	},
+}
+
+// DigestRepository interface for digest service
+type DigestRepository interface {
+	ListSubscribers() ([]models.NotificationPreferences, error)
+	ListDigestTasks(userID uuid.UUID, since, until time.Time) ([]models.Task, error)
+	ClaimDigest(delivery *models.DigestDelivery) (bool, error)
+	ReleaseDigest(delivery *models.DigestDelivery) error
+	DeleteDigestsSentBefore(cutoff time.Time) error
+}
+
+// DigestService emails users who opted in a daily or weekly summary of their
+// tasks. Each digest is sent once per period, even with several instances
+// running.
+type DigestService struct {
+	digestRepo DigestRepository
+	userRepo   UserRepository
+	mailer     mailer.Mailer
+	config     config.DigestConfig
+}
+
+// NewDigestService creates a new digest scheduler
+func NewDigestService(digestRepo DigestRepository, userRepo UserRepository, mailer mailer.Mailer, cfg config.DigestConfig) *DigestService {
+	return &DigestService{
+		digestRepo: digestRepo,
+		userRepo:   userRepo,
+		mailer:     mailer,
+		config:     cfg,
+	}
+}
+
+// digestView is the data rendered by the digest templates
+type digestView struct {
+	Language string
+	Subject  string
+	Greeting string
+	Intro    string
+	Sections []digestSection
+	Footer   string
+}
+
+// digestSection is a titled list of tasks; empty sections are left out
+type digestSection struct {
+	Title string
+	Tasks []digestItem
+}
+
+// digestItem is a task in a digest section
+type digestItem struct {
+	Title  string
+	Detail string
+}
+
+// Run sends due digests every PollInterval until ctx is done
+func (s *DigestService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.SendDue(ctx, time.Now()); err != nil {
+			log.Printf("Error sending email digests: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// SendDue sends the digests that are due at now. In the user's timezone,
+// daily digests go out once a day from the configured hour and weekly ones on
+// Mondays from that hour. A digest covers the period since the previous one
+// would have been sent: the user's overdue tasks and tasks due today, tasks
+// assigned to them by someone else and tasks completed. Digests with nothing
+// to report are not sent. A digest that fails to send is released and tried
+// again on the next check.
+func (s *DigestService) SendDue(ctx context.Context, now time.Time) error {
+	subscribers, err := s.digestRepo.ListSubscribers()
+	if err != nil {
+		return err
+	}
+
+	for _, prefs := range subscribers {
+		user, err := s.userRepo.FindByID(prefs.UserID)
+		if err != nil {
+			return err
+		}
+		if user == nil || !user.IsActive() {
+			continue
+		}
+
+		local := now.In(userLocation(user.Timezone))
+		period, since, ok := digestPeriod(prefs.Digest, local, s.config.Hour)
+		if !ok {
+			continue
+		}
+		delivery := &models.DigestDelivery{UserID: user.ID, Period: period, SentAt: now}
+		claimed, err := s.digestRepo.ClaimDigest(delivery)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.send(ctx, user, prefs.Digest, since, local); err != nil {
+			log.Printf("Failed to send %s digest to user %s: %v", prefs.Digest, user.ID, err)
+			if err := s.digestRepo.ReleaseDigest(delivery); err != nil {
+				log.Printf("Failed to release %s digest of user %s: %v", prefs.Digest, user.ID, err)
+			}
+		}
+	}
+
+	return s.digestRepo.DeleteDigestsSentBefore(now.Add(-digestRetention))
+}
+
+// send composes and mails a user's digest covering since to now, unless it
+// would be empty
+func (s *DigestService) send(ctx context.Context, user *models.User, frequency models.DigestFrequency, since, now time.Time) error {
+	loc := now.Location()
+	endOfDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc)
+	tasks, err := s.digestRepo.ListDigestTasks(user.ID, since, endOfDay)
+	if err != nil {
+		return err
+	}
+
+	language := localeLanguage(user.Locale)
+	labels, ok := digestTexts[language]
+	if !ok {
+		language = "es"
+		labels = digestTexts[language]
+	}
+
+	var overdue, dueToday, assigned, completed []digestItem
+	for i := range tasks {
+		task := &tasks[i]
+		open := task.Status != models.TaskStatusCompleted && task.Status != models.TaskStatusCancelled
+		if open && task.DueDate != nil && task.DueDate.Before(endOfDay) {
+			item := digestItem{Title: task.Title, Detail: fmt.Sprintf(labels.Due, task.DueDate.In(loc).Format(labels.DateFormat))}
+			if task.Assignee != nil && task.Assignee.ID != user.ID {
+				item.Detail += " · " + fmt.Sprintf(labels.AssignedTo, task.Assignee.Name)
+			}
+			if task.DueDate.Before(now) {
+				overdue = append(overdue, item)
+			} else {
+				dueToday = append(dueToday, item)
+			}
+		}
+		if task.AssignedTo != nil && *task.AssignedTo == user.ID && task.CreatedBy != user.ID &&
+			task.AssignedAt != nil && !task.AssignedAt.Before(since) {
+			item := digestItem{Title: task.Title}
+			if task.Creator != nil {
+				item.Detail = fmt.Sprintf(labels.AssignedBy, task.Creator.Name)
+			}
+			assigned = append(assigned, item)
+		}
+		if task.Status == models.TaskStatusCompleted && task.CompletedAt != nil && !task.CompletedAt.Before(since) {
+			item := digestItem{Title: task.Title}
+			if task.Assignee != nil && task.Assignee.ID != user.ID {
+				item.Detail = fmt.Sprintf(labels.CompletedBy, task.Assignee.Name)
+			}
+			completed = append(completed, item)
+		}
+	}
+
+	view := digestView{
+		Language: language,
+		Subject:  labels.DailySubject,
+		Greeting: fmt.Sprintf(labels.Greeting, user.Name),
+		Intro:    labels.Intro,
+		Footer:   labels.Footer,
+	}
+	if frequency == models.DigestWeekly {
+		view.Subject = labels.WeeklySubject
+	}
+	for _, section := range []digestSection{
+		{labels.Overdue, overdue},
+		{labels.DueToday, dueToday},
+		{labels.Assigned, assigned},
+		{labels.Completed, completed},
+	} {
+		if len(section.Tasks) > 0 {
+			view.Sections = append(view.Sections, section)
+		}
+	}
+	if len(view.Sections) == 0 {
+		return nil
+	}
+
+	var html, text bytes.Buffer
+	if err := digestHTML.Execute(&html, view); err != nil {
+		return err
+	}
+	if err := digestText.Execute(&text, view); err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: view.Subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+	})
+}
+
+// digestPeriod returns the period a digest of the given frequency is due for
+// at local time now, and the start of the time it covers. It reports false
+// when no digest is due yet.
+func digestPeriod(frequency models.DigestFrequency, now time.Time, hour int) (string, time.Time, bool) {
+	slot := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if now.Before(slot) {
+		return "", time.Time{}, false
+	}
+
+	switch frequency {
+	case models.DigestDaily:
+		return "daily:" + slot.Format("2006-01-02"), slot.AddDate(0, 0, -1), true
+	case models.DigestWeekly:
+		if now.Weekday() != time.Monday {
+			return "", time.Time{}, false
+		}
+		year, week := slot.ISOWeek()
+		return fmt.Sprintf("weekly:%d-W%02d", year, week), slot.AddDate(0, 0, -7), true
+	}
+	return "", time.Time{}, false
+}
+
+// userLocation returns the location of a user's timezone, or UTC when it is
+// unknown
+func userLocation(timezone string) *time.Location {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil || timezone == "" {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/mailer"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/secure"
+	"github.com/google/uuid"
+)
+
+// maxInvitationHours bounds the lifetime a caller may request
+const maxInvitationHours = 30 * 24
+
+// ErrInvalidInvitation is returned for unknown, expired, revoked or used tokens
+var ErrInvalidInvitation = errors.New("invalid or expired invitation")
+
+// ErrInvitationNotSent is returned when the invitation email could not be
+// sent. The invitation is discarded, so the request can simply be repeated.
+var ErrInvitationNotSent = errors.New("the invitation email could not be sent")
+
+// InvitationRepository interface for invitation service
+type InvitationRepository interface {
+	Create(invitation *models.Invitation) error
+	FindByID(id uuid.UUID) (*models.Invitation, error)
+	FindByHash(hash string) (*models.Invitation, error)
+	ListByInviter(userID uuid.UUID) ([]models.Invitation, error)
+	Revoke(id uuid.UUID, revokedAt time.Time) error
+	Delete(id uuid.UUID) error
+	MarkAccepted(id, userID uuid.UUID, acceptedAt time.Time) (bool, error)
+}
+
+// InvitationService handles team invitations
+type InvitationService struct {
+	invitationRepo InvitationRepository
+	userRepo       UserRepository
+	mailer         mailer.Mailer
+	config         *config.Config
+}
+
+// NewInvitationService creates a new invitation service
+func NewInvitationService(invitationRepo InvitationRepository, userRepo UserRepository, mailer mailer.Mailer, cfg *config.Config) *InvitationService {
+	return &InvitationService{
+		invitationRepo: invitationRepo,
+		userRepo:       userRepo,
+		mailer:         mailer,
+		config:         cfg,
+	}
+}
+
+// CreateInvitationRequest represents a create invitation request
+type CreateInvitationRequest struct {
+	Email          string                `json:"email" binding:"required,email"`
+	Role           models.InvitationRole `json:"role"`
+	ExpiresInHours int                   `json:"expires_in_hours"`
+}
+
+// InvitationPreview is the public view of an invitation shown before signing up
+type InvitationPreview struct {
+	Email       string                `json:"email"`
+	Role        models.InvitationRole `json:"role"`
+	InviterName string                `json:"inviter_name"`
+	ExpiresAt   time.Time             `json:"expires_at"`
+}
+
+// Create invites someone by email. Only admins may invite admins.
+func (s *InvitationService) Create(ctx context.Context, inviterID uuid.UUID, req CreateInvitationRequest) (*models.Invitation, error) {
+	inviter, err := s.userRepo.FindByID(inviterID)
+	if err != nil {
+		return nil, err
+	}
+	if inviter == nil {
+		return nil, errors.New("user not found")
+	}
+
+	if req.Role == "" {
+		req.Role = models.InvitationRoleMember
+	}
+	if !req.Role.IsValid() {
+		return nil, errors.New("invalid role")
+	}
+	if req.Role == models.InvitationRoleAdmin && !inviter.IsAdmin {
+		return nil, errors.New("only admins can invite admins")
+	}
+
+	hours := req.ExpiresInHours
+	if hours == 0 {
+		hours = s.config.Invite.ExpirationHours
+	}
+	if hours < 1 || hours > maxInvitationHours {
+		return nil, fmt.Errorf("expires_in_hours must be between 1 and %d", maxInvitationHours)
+	}
+
+	email := models.NormalizeEmail(req.Email)
+	exists, err := s.userRepo.EmailExists(email)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errors.New("a user with this email already exists")
+	}
+
+	token, err := secure.Token(24)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := &models.Invitation{
+		Email:     email,
+		Role:      req.Role,
+		InvitedBy: inviterID,
+		TokenHash: secure.Hash(token),
+		ExpiresAt: time.Now().Add(time.Duration(hours) * time.Hour),
+	}
+	if err := s.invitationRepo.Create(invitation); err != nil {
+		return nil, err
+	}
+
+	// Nobody else knows the token, so an invitation that was not delivered
+	// would only linger as pending
+	if err := s.sendInvitation(ctx, inviter, invitation, token); err != nil {
+		log.Printf("Failed to send invitation %s: %v", invitation.ID, err)
+		if err := s.invitationRepo.Delete(invitation.ID); err != nil {
+			log.Printf("Failed to delete unsent invitation %s: %v", invitation.ID, err)
+		}
+		return nil, ErrInvitationNotSent
+	}
+	return invitation, nil
+}
+
+// List lists the invitations sent by a user
+func (s *InvitationService) List(userID uuid.UUID) ([]models.Invitation, error) {
+	return s.invitationRepo.ListByInviter(userID)
+}
+
+// Revoke revokes a pending invitation. Only its sender or an admin may revoke it.
+func (s *InvitationService) Revoke(userID, invitationID uuid.UUID) error {
+	invitation, err := s.invitationRepo.FindByID(invitationID)
+	if err != nil {
+		return err
+	}
+	if invitation == nil {
+		return errors.New("invitation not found")
+	}
+
+	if invitation.InvitedBy != userID {
+		user, err := s.userRepo.FindByID(userID)
+		if err != nil {
+			return err
+		}
+		if user == nil || !user.IsAdmin {
+			return errors.New("invitation not found")
+		}
+	}
+
+	if invitation.Status(time.Now()) != models.InvitationStatusPending {
+		return errors.New("only pending invitations can be revoked")
+	}
+	return s.invitationRepo.Revoke(invitation.ID, time.Now())
+}
+
+// Preview returns the public details of a pending invitation
+func (s *InvitationService) Preview(token string) (*InvitationPreview, error) {
+	invitation, err := s.findPending(token)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &InvitationPreview{
+		Email:     invitation.Email,
+		Role:      invitation.Role,
+		ExpiresAt: invitation.ExpiresAt,
+	}
+	if invitation.Inviter != nil {
+		preview.InviterName = invitation.Inviter.Name
+	}
+	return preview, nil
+}
+
+// Validate checks that the token is a pending invitation for the email whose
+// sender may still grant it
+func (s *InvitationService) Validate(token, email string) (*models.Invitation, error) {
+	invitation, err := s.findPending(token)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(invitation.Email, strings.TrimSpace(email)) {
+		return nil, errors.New("invitation was sent to a different email")
+	}
+	if err := s.checkInviter(invitation); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// Redeem marks the invitation as used by the user and grants its role. The
+// sender is checked again, since an admin role is only granted while they are
+// still an active admin.
+func (s *InvitationService) Redeem(invitation *models.Invitation, user *models.User) error {
+	if err := s.checkInviter(invitation); err != nil {
+		return err
+	}
+
+	accepted, err := s.invitationRepo.MarkAccepted(invitation.ID, user.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		return ErrInvalidInvitation
+	}
+
+	if invitation.Role == models.InvitationRoleAdmin && !user.IsAdmin {
+		user.IsAdmin = true
+		return s.userRepo.Update(user)
+	}
+	return nil
+}
+
+// Accept redeems an invitation for an existing, signed-in user
+func (s *InvitationService) Accept(userID uuid.UUID, token string) (*models.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	invitation, err := s.Validate(token, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Redeem(invitation, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// checkInviter rejects invitations whose sender was deactivated, and admin
+// invitations whose sender is no longer an admin
+func (s *InvitationService) checkInviter(invitation *models.Invitation) error {
+	inviter, err := s.userRepo.FindByID(invitation.InvitedBy)
+	if err != nil {
+		return err
+	}
+	if inviter == nil || !inviter.IsActive() {
+		return ErrInvalidInvitation
+	}
+	if invitation.Role == models.InvitationRoleAdmin && !inviter.IsAdmin {
+		return ErrInvalidInvitation
+	}
+	return nil
+}
+
+// findPending resolves a token to a pending invitation
+func (s *InvitationService) findPending(token string) (*models.Invitation, error) {
+	if token == "" {
+		return nil, ErrInvalidInvitation
+	}
+	invitation, err := s.invitationRepo.FindByHash(secure.Hash(token))
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil || invitation.Status(time.Now()) != models.InvitationStatusPending {
+		return nil, ErrInvalidInvitation
+	}
+	return invitation, nil
+}
+
+// sendInvitation emails the invitation link
+func (s *InvitationService) sendInvitation(ctx context.Context, inviter *models.User, invitation *models.Invitation, token string) error {
+	link := s.config.Invite.URL
+	if strings.Contains(link, "?") {
+		link += "&token=" + url.QueryEscape(token)
+	} else {
+		link += "?token=" + url.QueryEscape(token)
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("%s invited you to TaskFlow", inviter.Name),
+		Text: fmt.Sprintf("Hi,\n\n%s invited you to join TaskFlow. Accept the invitation by opening:\n%s\n\nOr sign up with this invitation code: %s\n\nThe invitation expires on %s.\n",
+			inviter.Name, link, token, invitation.ExpiresAt.UTC().Format("2006-01-02 15:04 MST")),
+	})
+}
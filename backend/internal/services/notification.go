@@ -0,0 +1,297 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrNotificationNotFound is returned for unknown notifications and those of
+// other users
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// mentionPattern matches mentions in task titles and descriptions, written as
+// @[Name](user-id) so they keep pointing at the user after a rename
+var mentionPattern = regexp.MustCompile(`@\[[^\]]*\]\(([0-9a-fA-F-]{36})\)`)
+
+// NotificationRepository interface for notification service
+type NotificationRepository interface {
+	Create(notification *models.Notification) (bool, error)
+	List(filter models.NotificationFilter) ([]models.Notification, int64, error)
+	CountUnread(userID uuid.UUID) (int64, error)
+	MarkRead(userID, id uuid.UUID) (bool, error)
+	MarkAllRead(userID uuid.UUID) (int64, error)
+	FindPreferences(userID uuid.UUID) (*models.NotificationPreferences, error)
+	SavePreferences(prefs *models.NotificationPreferences) error
+}
+
+// NotificationSender delivers new notifications to the recipient, e.g. to
+// their connected clients
+type NotificationSender interface {
+	SendNotification(notification *models.Notification) error
+}
+
+// NotificationService manages the notification center. It receives task
+// events from the outbox and creates notifications for assignments and
+// mentions; the reminder scheduler adds due date notifications.
+type NotificationService struct {
+	notificationRepo NotificationRepository
+	userRepo         UserRepository
+	senders          []NotificationSender
+}
+
+// NewNotificationService creates a new notification service. New
+// notifications are stored and then handed to each sender, such as the
+// WebSocket hub and push notifications.
+func NewNotificationService(notificationRepo NotificationRepository, userRepo UserRepository, senders ...NotificationSender) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		senders:          senders,
+	}
+}
+
+// UpdateNotificationPreferencesRequest represents a partial update of the
+// notification preferences; omitted fields keep their value
+type UpdateNotificationPreferencesRequest struct {
+	Assignments         *bool `json:"assignments"`
+	Mentions            *bool `json:"mentions"`
+	DueDates            *bool `json:"due_dates"`
+	ReminderLeadMinutes []int `json:"reminder_lead_minutes"` // Replaces the lead times when present; [] only reminds when overdue
+
+	Digest *models.DigestFrequency `json:"digest"` // off, daily or weekly
+}
+
+// SinkName identifies the notification service among the outbox sinks
+func (s *NotificationService) SinkName() string {
+	return "notifications"
+}
+
+// HandleTaskEvent notifies the new assignee of a task and the users newly
+// mentioned in it. The user who made the change is never notified. Handling
+// an event again does not repeat its notifications.
+func (s *NotificationService) HandleTaskEvent(event *models.OutboxEvent) error {
+	task := event.Task
+	if task == nil {
+		return nil
+	}
+
+	actorID := event.ActorID
+	notified := map[uuid.UUID]bool{actorID: true}
+	if task.AssignedTo != nil && (event.Previous == nil || event.Previous.AssignedTo == nil || *event.Previous.AssignedTo != *task.AssignedTo) {
+		if !notified[*task.AssignedTo] {
+			if err := s.notify(*task.AssignedTo, models.NotificationTypeAssigned, task, &actorID, &event.ID); err != nil {
+				return err
+			}
+			notified[*task.AssignedTo] = true
+		}
+	}
+
+	for _, userID := range newMentions(task, event.Previous) {
+		if notified[userID] {
+			continue
+		}
+		user, err := s.userRepo.FindByID(userID)
+		if err != nil {
+			return err
+		}
+		if user == nil || !user.IsActive() {
+			continue
+		}
+		if err := s.notify(userID, models.NotificationTypeMentioned, task, &actorID, &event.ID); err != nil {
+			return err
+		}
+		notified[userID] = true
+	}
+	return nil
+}
+
+// Notify creates a notification about a task unless the recipient turned
+// that type off, and hands it to the senders. ActorID is nil for
+// notifications the system creates on its own.
+func (s *NotificationService) Notify(userID uuid.UUID, notificationType models.NotificationType, task *models.Task, actorID *uuid.UUID) error {
+	return s.notify(userID, notificationType, task, actorID, nil)
+}
+
+// notify creates a notification, recording the outbox event it comes from.
+// When the event already produced it, nothing is stored or sent.
+func (s *NotificationService) notify(userID uuid.UUID, notificationType models.NotificationType, task *models.Task, actorID *uuid.UUID, eventID *uint64) error {
+	prefs, err := s.Preferences(userID)
+	if err != nil {
+		return err
+	}
+	if !prefs.Allows(notificationType) {
+		return nil
+	}
+
+	taskID := task.ID
+	notification := &models.Notification{
+		EventID:   eventID,
+		UserID:    userID,
+		Type:      notificationType,
+		TaskID:    &taskID,
+		TaskTitle: task.Title,
+		ActorID:   actorID,
+	}
+	created, err := s.notificationRepo.Create(notification)
+	if err != nil || !created {
+		return err
+	}
+
+	// The notification is stored, so a client that misses the real-time
+	// message still finds it when listing
+	for _, sender := range s.senders {
+		if err := sender.SendNotification(notification); err != nil {
+			log.Printf("Error sending notification %s: %v", notification.ID, err)
+		}
+	}
+	return nil
+}
+
+// List lists a user's notifications with pagination
+func (s *NotificationService) List(filter models.NotificationFilter) ([]models.Notification, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+
+	return s.notificationRepo.List(filter)
+}
+
+// UnreadCount counts a user's unread notifications
+func (s *NotificationService) UnreadCount(userID uuid.UUID) (int64, error) {
+	return s.notificationRepo.CountUnread(userID)
+}
+
+// MarkRead marks one of the user's notifications as read
+func (s *NotificationService) MarkRead(userID, notificationID uuid.UUID) error {
+	found, err := s.notificationRepo.MarkRead(userID, notificationID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// MarkAllRead marks all of the user's notifications as read and returns how
+// many were unread
+func (s *NotificationService) MarkAllRead(userID uuid.UUID) (int64, error) {
+	return s.notificationRepo.MarkAllRead(userID)
+}
+
+// Preferences returns the user's notification preferences, or the defaults if
+// they never changed them
+func (s *NotificationService) Preferences(userID uuid.UUID) (*models.NotificationPreferences, error) {
+	prefs, err := s.notificationRepo.FindPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		return models.DefaultNotificationPreferences(userID), nil
+	}
+	if prefs.ReminderLeadMinutes == nil {
+		prefs.ReminderLeadMinutes = models.DefaultNotificationPreferences(userID).ReminderLeadMinutes
+	}
+	return prefs, nil
+}
+
+// UpdatePreferences changes the user's notification preferences
+func (s *NotificationService) UpdatePreferences(userID uuid.UUID, req UpdateNotificationPreferencesRequest) (*models.NotificationPreferences, error) {
+	if len(req.ReminderLeadMinutes) > models.MaxReminderLeadTimes {
+		return nil, fmt.Errorf("at most %d reminder lead times are allowed", models.MaxReminderLeadTimes)
+	}
+	for _, minutes := range req.ReminderLeadMinutes {
+		if minutes < 1 || minutes > models.MaxReminderLeadMinutes {
+			return nil, fmt.Errorf("reminder lead times must be between 1 and %d minutes", models.MaxReminderLeadMinutes)
+		}
+	}
+
+	if req.Digest != nil && !req.Digest.IsValid() {
+		return nil, errors.New("digest must be off, daily or weekly")
+	}
+
+	prefs, err := s.Preferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Assignments != nil {
+		prefs.Assignments = *req.Assignments
+	}
+	if req.Mentions != nil {
+		prefs.Mentions = *req.Mentions
+	}
+	if req.DueDates != nil {
+		prefs.DueDates = *req.DueDates
+	}
+	if req.ReminderLeadMinutes != nil {
+		prefs.ReminderLeadMinutes = uniqueSortedDesc(req.ReminderLeadMinutes)
+	}
+	if req.Digest != nil {
+		prefs.Digest = *req.Digest
+	}
+
+	if err := s.notificationRepo.SavePreferences(prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// newMentions returns the users mentioned in the task's title or description
+// that were not mentioned before the change
+func newMentions(task, previous *models.Task) []uuid.UUID {
+	before := make(map[uuid.UUID]bool)
+	if previous != nil {
+		for _, id := range mentionedUsers(previous) {
+			before[id] = true
+		}
+	}
+
+	var mentioned []uuid.UUID
+	for _, id := range mentionedUsers(task) {
+		if !before[id] {
+			mentioned = append(mentioned, id)
+		}
+	}
+	return mentioned
+}
+
+// mentionedUsers returns the users mentioned in the task, without duplicates
+func mentionedUsers(task *models.Task) []uuid.UUID {
+	var ids []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, text := range []string{task.Title, task.Description} {
+		for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+			id, err := uuid.Parse(match[1])
+			if err != nil || seen[id] {
+				continue
+			}
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// uniqueSortedDesc returns the values without duplicates, largest first
+func uniqueSortedDesc(values []int) []int {
+	result := make([]int, 0, len(values))
+	seen := make(map[int]bool, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(result)))
+	return result
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/oidc"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/secure"
+)
+
+// oidcAuthRequestTTL is how long a user has to complete login at the provider
+const oidcAuthRequestTTL = 10 * time.Minute
+
+// ErrUnknownProvider is returned for provider names that are not configured
+var ErrUnknownProvider = errors.New("unknown identity provider")
+
+// IdentityRepository interface for OIDC service
+type IdentityRepository interface {
+	FindIdentity(provider, subject string) (*models.UserIdentity, error)
+	CreateIdentity(identity *models.UserIdentity) error
+	CreateAuthRequest(request *models.OIDCAuthRequest) error
+	ConsumeAuthRequest(state string) (*models.OIDCAuthRequest, error)
+}
+
+// OIDCService handles login through external OpenID Connect providers
+type OIDCService struct {
+	providers    map[string]*oidc.Provider
+	identityRepo IdentityRepository
+	userRepo     UserRepository
+	authService  *AuthService
+}
+
+// NewOIDCService creates a new OIDC service
+func NewOIDCService(providers []*oidc.Provider, identityRepo IdentityRepository, userRepo UserRepository, authService *AuthService) *OIDCService {
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, p := range providers {
+		byName[p.Name] = p
+	}
+	return &OIDCService{
+		providers:    byName,
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		authService:  authService,
+	}
+}
+
+// Providers lists the names of the configured providers
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AuthorizationURL starts a login and returns the provider URL to redirect to
+func (s *OIDCService) AuthorizationURL(ctx context.Context, providerName string) (string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+
+	state, err := secure.Token(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := secure.Token(32)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := secure.Token(32)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", err
+	}
+
+	request := &models.OIDCAuthRequest{
+		State:        state,
+		Provider:     provider.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcAuthRequestTTL),
+	}
+	if err := s.identityRepo.CreateAuthRequest(request); err != nil {
+		return "", err
+	}
+
+	return authURL, nil
+}
+
+// Callback completes a login: it redeems the code, links or provisions the
+// user by verified email and issues TaskFlow tokens
+func (s *OIDCService) Callback(ctx context.Context, providerName, code, state string) (*AuthResponse, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	request, err := s.identityRepo.ConsumeAuthRequest(state)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil || request.Provider != provider.Name || time.Now().After(request.ExpiresAt) {
+		return nil, errors.New("invalid or expired login state")
+	}
+
+	claims, err := provider.Exchange(ctx, code, request.CodeVerifier, request.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolveUser(provider, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.authService.CompleteLogin(user)
+}
+
+// resolveUser finds the user linked to the identity, linking an existing
+// account or provisioning a new one by verified email on first login
+func (s *OIDCService) resolveUser(provider *oidc.Provider, claims *oidc.IDTokenClaims) (*models.User, error) {
+	identity, err := s.identityRepo.FindIdentity(provider.Name, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		user, err := s.userRepo.FindByID(identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, errors.New("linked user no longer exists")
+		}
+		return user, nil
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errors.New("identity provider did not return a verified email")
+	}
+
+	user, err := s.userRepo.FindByEmail(claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		if !provider.AutoProvision() {
+			return nil, errors.New("no account exists for this email")
+		}
+		if user, err = s.provisionUser(claims); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.identityRepo.CreateIdentity(&models.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider.Name,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// provisionUser creates a user with an unusable random password. Like
+// Register, it grants admin to the emails listed in ADMIN_EMAILS.
+func (s *OIDCService) provisionUser(claims *oidc.IDTokenClaims) (*models.User, error) {
+	password, err := secure.Token(32)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(claims.Name)
+	if len(name) < 2 {
+		name = strings.Split(claims.Email, "@")[0]
+	}
+
+	user := &models.User{
+		Email:    models.NormalizeEmail(claims.Email),
+		Password: password,
+		Name:     name,
+		IsAdmin:  s.authService.isAdminEmail(claims.Email),
+	}
+	if err := user.HashPassword(); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
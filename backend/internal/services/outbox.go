@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+)
+
+const (
+	outboxPruneInterval  = time.Hour       // How often dispatched events older than the retention period are deleted
+	outboxMaxRetryDelay  = 5 * time.Minute // Cap on the exponential backoff between attempts
+	outboxFailedListSize = 100             // Failed events listed for administrators
+)
+
+// ErrOutboxEventNotFound is returned when retrying an event that is unknown or
+// has not failed
+var ErrOutboxEventNotFound = errors.New("failed outbox event not found")
+
+// TaskEventSink receives the task events published from the outbox. An error
+// leaves the event in the outbox to be retried, holding back later events;
+// sinks that already handled it are not called again. SinkName identifies the
+// sink in the recorded progress, so it must not change between releases.
+type TaskEventSink interface {
+	SinkName() string
+	HandleTaskEvent(event *models.OutboxEvent) error
+}
+
+// OutboxRepository interface for outbox dispatcher
+type OutboxRepository interface {
+	DispatchNext(now time.Time, fn func(event *models.OutboxEvent) error) (bool, error)
+	DeleteDispatchedBefore(cutoff time.Time) error
+	ListFailed(limit int) ([]models.OutboxEvent, error)
+	Retry(id uint64) (bool, error)
+}
+
+// OutboxDispatcher publishes the task events recorded by TaskRepository to
+// the hub, webhooks and other sinks, once each and in order
+type OutboxDispatcher struct {
+	outboxRepo OutboxRepository
+	sinks      []TaskEventSink
+	config     config.OutboxConfig
+}
+
+// NewOutboxDispatcher creates a new outbox dispatcher
+func NewOutboxDispatcher(outboxRepo OutboxRepository, cfg config.OutboxConfig, sinks ...TaskEventSink) *OutboxDispatcher {
+	return &OutboxDispatcher{
+		outboxRepo: outboxRepo,
+		sinks:      sinks,
+		config:     cfg,
+	}
+}
+
+// DispatchPending publishes every pending event. It stops at the first event a
+// sink rejects, which is retried with exponential backoff from RetryBaseDelay
+// until MaxAttempts attempts failed. Later events wait for it.
+func (d *OutboxDispatcher) DispatchPending() error {
+	for {
+		found, err := d.outboxRepo.DispatchNext(time.Now(), d.dispatch)
+		if err != nil || !found {
+			return err
+		}
+	}
+}
+
+// FailedEvents lists the events the dispatcher gave up on
+func (d *OutboxDispatcher) FailedEvents() ([]models.OutboxEvent, error) {
+	return d.outboxRepo.ListFailed(outboxFailedListSize)
+}
+
+// RetryFailed queues an event the dispatcher gave up on again, e.g. once the
+// failing sink is fixed. It is dispatched after the events already pending
+// before it, out of ID order; clients that already received later events
+// ignore it.
+func (d *OutboxDispatcher) RetryFailed(id uint64) error {
+	found, err := d.outboxRepo.Retry(id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrOutboxEventNotFound
+	}
+	return nil
+}
+
+// dispatch passes one event to the sinks that have not handled it yet,
+// recording each success so that a retry does not repeat it. A failure
+// schedules the next attempt; an event that failed MaxAttempts times is marked
+// failed and no longer holds back the rest.
+func (d *OutboxDispatcher) dispatch(event *models.OutboxEvent) error {
+	for _, sink := range d.sinks {
+		name := sink.SinkName()
+		if event.Handled(name) {
+			continue
+		}
+		if err := sink.HandleTaskEvent(event); err != nil {
+			event.Attempts++
+			event.LastError = fmt.Sprintf("%s: %v", name, err)
+			now := time.Now()
+			next := now.Add(outboxRetryDelay(d.config.RetryBaseDelay, event.Attempts))
+			event.NextAttemptAt = &next
+			if d.config.MaxAttempts > 0 && event.Attempts >= d.config.MaxAttempts {
+				event.FailedAt = &now
+				log.Printf("Giving up outbox event %d after %d attempts: %s", event.ID, event.Attempts, event.LastError)
+			}
+			return err
+		}
+		event.HandledBy = append(event.HandledBy, name)
+	}
+
+	now := time.Now()
+	event.DispatchedAt = &now
+	return nil
+}
+
+// Run dispatches pending events every PollInterval, and prunes old dispatched
+// events, until ctx is done
+func (d *OutboxDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+	var lastPrune time.Time
+	for {
+		if err := d.DispatchPending(); err != nil {
+			log.Printf("Error dispatching outbox events: %v", err)
+		}
+		if d.config.Retention > 0 && time.Since(lastPrune) >= outboxPruneInterval {
+			if err := d.outboxRepo.DeleteDispatchedBefore(time.Now().Add(-d.config.Retention)); err != nil {
+				log.Printf("Error pruning outbox events: %v", err)
+			}
+			lastPrune = time.Now()
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// outboxRetryDelay returns the delay before the next attempt of an event that
+// failed attempts times
+func outboxRetryDelay(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < outboxMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > outboxMaxRetryDelay {
+		delay = outboxMaxRetryDelay
+	}
+	return delay
+}
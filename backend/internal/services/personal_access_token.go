@@ -0,0 +1,137 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/secure"
+	"github.com/google/uuid"
+)
+
+// lastUsedResolution limits how often last-used timestamps are written
+const lastUsedResolution = time.Minute
+
+// PersonalAccessTokenRepository interface for personal access token service
+type PersonalAccessTokenRepository interface {
+	Create(token *models.PersonalAccessToken) error
+	FindByHash(hash string) (*models.PersonalAccessToken, error)
+	ListByUser(userID uuid.UUID) ([]models.PersonalAccessToken, error)
+	Delete(id, userID uuid.UUID) (bool, error)
+	UpdateLastUsed(id uuid.UUID, usedAt time.Time) error
+	DeleteByUser(userID uuid.UUID) error
+}
+
+// PersonalAccessTokenService handles personal access token business logic
+type PersonalAccessTokenService struct {
+	tokenRepo PersonalAccessTokenRepository
+}
+
+// NewPersonalAccessTokenService creates a new personal access token service
+func NewPersonalAccessTokenService(tokenRepo PersonalAccessTokenRepository) *PersonalAccessTokenService {
+	return &PersonalAccessTokenService{
+		tokenRepo: tokenRepo,
+	}
+}
+
+// CreatePersonalAccessTokenRequest represents a create token request
+type CreatePersonalAccessTokenRequest struct {
+	Name      string            `json:"name" binding:"required,max=100"`
+	Scope     models.TokenScope `json:"scope" binding:"required"`
+	ExpiresAt *string           `json:"expires_at"`
+}
+
+// CreatePersonalAccessTokenResponse contains the plaintext token, shown only once
+type CreatePersonalAccessTokenResponse struct {
+	models.PersonalAccessToken
+	Token string `json:"token"`
+}
+
+// Create creates a new token for the user
+func (s *PersonalAccessTokenService) Create(userID uuid.UUID, req CreatePersonalAccessTokenRequest) (*CreatePersonalAccessTokenResponse, error) {
+	if !req.Scope.IsValid() {
+		return nil, errors.New("invalid scope")
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresAt != nil {
+		parsed, err := time.Parse(time.RFC3339, *req.ExpiresAt)
+		if err != nil {
+			return nil, errors.New("invalid expiration date format")
+		}
+		if parsed.Before(time.Now()) {
+			return nil, errors.New("expiration date cannot be in the past")
+		}
+		expiresAt = &parsed
+	}
+
+	random, err := secure.Token(32)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := models.PersonalAccessTokenPrefix + random
+
+	token := models.PersonalAccessToken{
+		UserID:      userID,
+		Name:        req.Name,
+		Scope:       req.Scope,
+		TokenHash:   secure.Hash(plaintext),
+		TokenPrefix: plaintext[:len(models.PersonalAccessTokenPrefix)+8],
+		ExpiresAt:   expiresAt,
+	}
+
+	if err := s.tokenRepo.Create(&token); err != nil {
+		return nil, err
+	}
+
+	return &CreatePersonalAccessTokenResponse{
+		PersonalAccessToken: token,
+		Token:               plaintext,
+	}, nil
+}
+
+// List lists the user's tokens
+func (s *PersonalAccessTokenService) List(userID uuid.UUID) ([]models.PersonalAccessToken, error) {
+	return s.tokenRepo.ListByUser(userID)
+}
+
+// Revoke deletes one of the user's tokens
+func (s *PersonalAccessTokenService) Revoke(userID, tokenID uuid.UUID) error {
+	deleted, err := s.tokenRepo.Delete(tokenID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("token not found")
+	}
+	return nil
+}
+
+// AuthenticatePersonalAccessToken resolves a plaintext token to a valid token
+// record, including its owner, and records its use
+func (s *PersonalAccessTokenService) AuthenticatePersonalAccessToken(plaintext string) (*models.PersonalAccessToken, error) {
+	if !strings.HasPrefix(plaintext, models.PersonalAccessTokenPrefix) {
+		return nil, errors.New("invalid token")
+	}
+
+	token, err := s.tokenRepo.FindByHash(secure.Hash(plaintext))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if token == nil || token.User == nil || !token.User.IsActive() || token.IsExpired(now) {
+		return nil, errors.New("invalid token")
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedResolution {
+		if err := s.tokenRepo.UpdateLastUsed(token.ID, now); err != nil {
+			log.Printf("Failed to update token last use: %v", err)
+		}
+		token.LastUsedAt = &now
+	}
+
+	return token, nil
+}
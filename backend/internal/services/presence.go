@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PresenceRepository interface for the presence shared between instances
+type PresenceRepository interface {
+	Sync(instanceID uuid.UUID, userIDs []uuid.UUID, now, staleBefore time.Time) error
+	IsOnline(userID uuid.UUID, since time.Time) (bool, error)
+}
+
+// LocalPresence reports the users connected to this instance
+type LocalPresence interface {
+	PresenceChecker
+	OnlineUsers() []uuid.UUID
+}
+
+// SharedPresence reports whether a user is connected to any backend instance.
+// Every instance writes its connected users to the database each interval;
+// a report older than two intervals no longer counts, so a user who just
+// disconnected from another instance may count as online until then.
+type SharedPresence struct {
+	instanceID uuid.UUID
+	local      LocalPresence
+	repo       PresenceRepository
+	interval   time.Duration
+}
+
+// NewSharedPresence creates the presence of this instance, identified by a
+// new random ID, on top of its local clients
+func NewSharedPresence(local LocalPresence, repo PresenceRepository, interval time.Duration) *SharedPresence {
+	return &SharedPresence{
+		instanceID: uuid.New(),
+		local:      local,
+		repo:       repo,
+		interval:   interval,
+	}
+}
+
+// IsOnline reports whether the user is connected here or was reported by
+// another instance recently. When the database cannot be read the user counts
+// as offline, so a push may be sent twice rather than not at all.
+func (p *SharedPresence) IsOnline(userID uuid.UUID) bool {
+	if p.local.IsOnline(userID) {
+		return true
+	}
+	online, err := p.repo.IsOnline(userID, time.Now().Add(-2*p.interval))
+	if err != nil {
+		log.Printf("Error checking presence of user %s: %v", userID, err)
+		return false
+	}
+	return online
+}
+
+// Sync reports the users connected to this instance as of now
+func (p *SharedPresence) Sync(now time.Time) error {
+	return p.repo.Sync(p.instanceID, p.local.OnlineUsers(), now, now.Add(-2*p.interval))
+}
+
+// Run reports the connected users every interval until ctx is done
+func (p *SharedPresence) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Sync(time.Now()); err != nil {
+			log.Printf("Error syncing presence: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/push"
+	"github.com/google/uuid"
+)
+
+// ErrDeviceNotFound is returned when unregistering a token the user does not
+// have
+var ErrDeviceNotFound = errors.New("device not found")
+
+const (
+	pushMaxAttempts   = 5              // Sends of a queued push before it is dropped
+	pushReceiptMaxAge = 24 * time.Hour // Expo keeps receipts for a day; older ones are no longer checked
+)
+
+// expoTokenPattern matches Expo push tokens
+var expoTokenPattern = regexp.MustCompile(`^Expo(nent)?PushToken\[[^\]]+\]$`)
+
+// pushBodies are the push notification texts by notification type, in
+// Spanish and English. The task title is used as the notification title.
+var pushBodies = map[string]map[models.NotificationType]string{
+	"es": {
+		models.NotificationTypeAssigned:  "Te asignaron esta tarea",
+		models.NotificationTypeDueSoon:   "La tarea vence pronto",
+		models.NotificationTypeOverdue:   "La tarea está vencida",
+		models.NotificationTypeEscalated: "Una tarea urgente que creaste está vencida",
+	},
+	"en": {
+		models.NotificationTypeAssigned:  "This task was assigned to you",
+		models.NotificationTypeDueSoon:   "This task is due soon",
+		models.NotificationTypeOverdue:   "This task is overdue",
+		models.NotificationTypeEscalated: "An urgent task you created is overdue",
+	},
+}
+
+// DeviceRepository interface for push service
+type DeviceRepository interface {
+	Register(device *models.DeviceToken) error
+	ListByUser(userID uuid.UUID) ([]models.DeviceToken, error)
+	Delete(userID uuid.UUID, token string) (bool, error)
+	DeleteTokens(tokens []string) error
+}
+
+// PresenceChecker reports whether a user has a real-time connection open
+type PresenceChecker interface {
+	IsOnline(userID uuid.UUID) bool
+}
+
+// PushService registers device push tokens and sends assignment and
+// reminder notifications to users who are offline. Notifications are queued
+// and sent in batches; tokens the push service rejects are removed.
+//
+// Pushes are best-effort: the queue and the receipts to check are kept in
+// memory, bounded by QueueSize, and lost when the process stops. The
+// notifications themselves are stored and remain in the user's inbox.
+type PushService struct {
+	deviceRepo DeviceRepository
+	userRepo   UserRepository
+	sender     push.PushSender
+	presence   PresenceChecker
+	config     config.PushConfig
+
+	mu      sync.Mutex
+	queue   []queuedPush
+	pending []pendingReceipt
+}
+
+// queuedPush is a push waiting to be sent, with the number of failed sends
+type queuedPush struct {
+	message  push.Message
+	attempts int
+}
+
+// pendingReceipt is an accepted push whose delivery receipt is not checked yet
+type pendingReceipt struct {
+	ticketID string
+	token    string
+	sentAt   time.Time
+}
+
+// NewPushService creates a new push service. Presence may be nil, in which
+// case every user is treated as offline.
+func NewPushService(deviceRepo DeviceRepository, userRepo UserRepository, sender push.PushSender, presence PresenceChecker, cfg config.PushConfig) *PushService {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	return &PushService{
+		deviceRepo: deviceRepo,
+		userRepo:   userRepo,
+		sender:     sender,
+		presence:   presence,
+		config:     cfg,
+	}
+}
+
+// RegisterDeviceRequest represents a device push token registration
+type RegisterDeviceRequest struct {
+	Token    string `json:"token" binding:"required,max=255"`
+	Platform string `json:"platform" binding:"omitempty,oneof=ios android"`
+}
+
+// RegisterDevice saves a push token for the user
+func (s *PushService) RegisterDevice(userID uuid.UUID, req RegisterDeviceRequest) (*models.DeviceToken, error) {
+	if !expoTokenPattern.MatchString(req.Token) {
+		return nil, errors.New("token must be an Expo push token")
+	}
+
+	device := &models.DeviceToken{
+		UserID:   userID,
+		Token:    req.Token,
+		Platform: req.Platform,
+	}
+	if err := s.deviceRepo.Register(device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// UnregisterDevice removes one of the user's push tokens, e.g. on logout
+func (s *PushService) UnregisterDevice(userID uuid.UUID, token string) error {
+	found, err := s.deviceRepo.Delete(userID, token)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+// SendNotification queues a push notification to each of the recipient's
+// devices if the notification is an assignment or reminder and the recipient
+// has no real-time connection open
+func (s *PushService) SendNotification(notification *models.Notification) error {
+	if _, ok := pushBodies["es"][notification.Type]; !ok {
+		return nil
+	}
+	if s.presence != nil && s.presence.IsOnline(notification.UserID) {
+		return nil
+	}
+
+	devices, err := s.deviceRepo.ListByUser(notification.UserID)
+	if err != nil || len(devices) == 0 {
+		return err
+	}
+	user, err := s.userRepo.FindByID(notification.UserID)
+	if err != nil || user == nil {
+		return err
+	}
+
+	data := map[string]string{
+		"notification_id": notification.ID.String(),
+		"type":            string(notification.Type),
+	}
+	if notification.TaskID != nil {
+		data["task_id"] = notification.TaskID.String()
+	}
+	body := pushBody(user.Locale, notification.Type)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, device := range devices {
+		if len(s.queue) >= s.config.QueueSize {
+			log.Printf("Push queue full, dropping push to %s", device.Token)
+			continue
+		}
+		s.queue = append(s.queue, queuedPush{message: push.Message{
+			To:    device.Token,
+			Title: notification.TaskTitle,
+			Body:  body,
+			Data:  data,
+			Sound: "default",
+		}})
+	}
+	return nil
+}
+
+// Flush sends the queued notifications. Tokens rejected as no longer
+// registered are removed; accepted pushes are kept to check their receipts.
+// When sending fails, the pushes that were not sent are queued again, up to
+// pushMaxAttempts times.
+func (s *PushService) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	queued := s.queue
+	s.queue = nil
+	s.mu.Unlock()
+	if len(queued) == 0 {
+		return nil
+	}
+
+	messages := make([]push.Message, len(queued))
+	for i, q := range queued {
+		messages[i] = q.message
+	}
+	tickets, err := s.sender.Send(ctx, messages)
+	if len(tickets) > len(messages) {
+		tickets = tickets[:len(messages)]
+	}
+	var retry []queuedPush
+	for _, q := range queued[len(tickets):] {
+		q.attempts++
+		if q.attempts >= pushMaxAttempts {
+			log.Printf("Dropping push to %s after %d failed attempts", q.message.To, q.attempts)
+			continue
+		}
+		retry = append(retry, q)
+	}
+
+	var invalid []string
+	var accepted []pendingReceipt
+	now := time.Now()
+	for i, ticket := range tickets {
+		switch {
+		case ticket.InvalidToken():
+			invalid = append(invalid, messages[i].To)
+		case ticket.ID != "":
+			accepted = append(accepted, pendingReceipt{ticketID: ticket.ID, token: messages[i].To, sentAt: now})
+		case ticket.Error != "":
+			log.Printf("Push to %s rejected: %s %s", messages[i].To, ticket.Error, ticket.Message)
+		}
+	}
+
+	s.mu.Lock()
+	s.queue = append(retry, s.queue...)
+	if dropped := len(s.queue) - s.config.QueueSize; dropped > 0 {
+		log.Printf("Push queue full, dropping %d pushes", dropped)
+		s.queue = s.queue[:s.config.QueueSize]
+	}
+	s.pending = append(s.pending, accepted...)
+	s.mu.Unlock()
+
+	if deleteErr := s.deviceRepo.DeleteTokens(invalid); deleteErr != nil && err == nil {
+		err = deleteErr
+	}
+	return err
+}
+
+// CheckReceipts fetches the receipts of pushes sent at least ReceiptDelay
+// before now and removes the tokens they report as no longer registered.
+// Receipts that are not ready yet are checked again next time, until they are
+// pushReceiptMaxAge old.
+func (s *PushService) CheckReceipts(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	var due, waiting []pendingReceipt
+	for _, p := range s.pending {
+		age := now.Sub(p.sentAt)
+		switch {
+		case age > pushReceiptMaxAge:
+			log.Printf("No receipt for push to %s after %s, giving up", p.token, age.Round(time.Minute))
+		case age >= s.config.ReceiptDelay:
+			due = append(due, p)
+		default:
+			waiting = append(waiting, p)
+		}
+	}
+	s.pending = waiting
+	s.mu.Unlock()
+	if len(due) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(due))
+	for i, p := range due {
+		ids[i] = p.ticketID
+	}
+	receipts, err := s.sender.Receipts(ctx, ids)
+	if err != nil {
+		s.mu.Lock()
+		s.pending = append(s.pending, due...)
+		s.mu.Unlock()
+		return err
+	}
+
+	var invalid, notReady []pendingReceipt
+	for _, p := range due {
+		receipt, ok := receipts[p.ticketID]
+		switch {
+		case !ok:
+			notReady = append(notReady, p)
+		case receipt.InvalidToken():
+			invalid = append(invalid, p)
+		case receipt.Error != "":
+			log.Printf("Push to %s failed: %s %s", p.token, receipt.Error, receipt.Message)
+		}
+	}
+
+	s.mu.Lock()
+	s.pending = append(s.pending, notReady...)
+	s.mu.Unlock()
+
+	tokens := make([]string, len(invalid))
+	for i, p := range invalid {
+		tokens[i] = p.token
+	}
+	return s.deviceRepo.DeleteTokens(tokens)
+}
+
+// Run flushes queued notifications every BatchInterval and checks receipts
+// until ctx is done
+func (s *PushService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.BatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		if err := s.Flush(ctx); err != nil {
+			log.Printf("Error sending push notifications: %v", err)
+		}
+		if err := s.CheckReceipts(ctx, time.Now()); err != nil {
+			log.Printf("Error checking push receipts: %v", err)
+		}
+	}
+}
+
+// pushBody returns the push text for a notification type in the user's
+// language, falling back to Spanish
+func pushBody(locale string, notificationType models.NotificationType) string {
+	bodies, ok := pushBodies[localeLanguage(locale)]
+	if !ok {
+		bodies = pushBodies["es"]
+	}
+	return bodies[notificationType]
+}
+
+// localeLanguage returns the lowercase language of a locale, e.g. "en" for
+// "en-US" or "en_GB"
+func localeLanguage(locale string) string {
+	return strings.ToLower(strings.SplitN(strings.ReplaceAll(locale, "_", "-"), "-", 2)[0])
+}
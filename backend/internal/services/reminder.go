@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// reminderLookback bounds how long after its due date an open task is still
+// checked, and how long sent reminders are remembered
+const reminderLookback = 7 * 24 * time.Hour
+
+// ReminderRepository interface for reminder scheduler
+type ReminderRepository interface {
+	ListOpenTasksDueBetween(from, to time.Time) ([]models.Task, error)
+	ClaimReminder(reminder *models.TaskReminder) (bool, error)
+	ReleaseReminder(reminder *models.TaskReminder) error
+	DeleteRemindersDueBefore(cutoff time.Time) error
+}
+
+// ReminderService reminds users of tasks that are due soon or overdue, and
+// escalates overdue urgent tasks to their creator. Each reminder is sent once
+// per threshold and due date, even with several instances running.
+type ReminderService struct {
+	reminderRepo  ReminderRepository
+	notifications *NotificationService
+	config        config.ReminderConfig
+}
+
+// NewReminderService creates a new reminder scheduler
+func NewReminderService(reminderRepo ReminderRepository, notifications *NotificationService, cfg config.ReminderConfig) *ReminderService {
+	return &ReminderService{
+		reminderRepo:  reminderRepo,
+		notifications: notifications,
+		config:        cfg,
+	}
+}
+
+// Run sends due reminders every PollInterval until ctx is done
+func (s *ReminderService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.SendDue(time.Now()); err != nil {
+			log.Printf("Error sending due date reminders: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// SendDue sends the reminders that are due at now. A task's reminders go to
+// its assignee, or to its creator while unassigned or while the assignee is
+// deactivated:
+//
+//   - due_soon when the task is within one of the user's lead times of its
+//     due date; only the shortest lead time reached is reminded
+//   - overdue once the due date has passed
+//   - escalated to the creator of an urgent task assigned to someone else
+//     once it has been overdue for EscalateAfter
+//
+// Deactivated users get no reminders. A task whose reminders fail is logged
+// and skipped; its reminders are tried again on the next check.
+func (s *ReminderService) SendDue(now time.Time) error {
+	tasks, err := s.reminderRepo.ListOpenTasksDueBetween(now.Add(-reminderLookback), now.Add(time.Duration(models.MaxReminderLeadMinutes)*time.Minute))
+	if err != nil {
+		return err
+	}
+
+	prefs := make(map[uuid.UUID]*models.NotificationPreferences)
+	preferences := func(userID uuid.UUID) (*models.NotificationPreferences, error) {
+		if p, ok := prefs[userID]; ok {
+			return p, nil
+		}
+		p, err := s.notifications.Preferences(userID)
+		if err != nil {
+			return nil, err
+		}
+		prefs[userID] = p
+		return p, nil
+	}
+
+	for i := range tasks {
+		if err := s.remindTask(&tasks[i], preferences, now); err != nil {
+			log.Printf("Error sending reminders for task %s: %v", tasks[i].ID, err)
+		}
+	}
+
+	return s.reminderRepo.DeleteRemindersDueBefore(now.Add(-reminderLookback))
+}
+
+// remindTask sends the reminders a task has reached at now. The task's
+// creator and assignee must be loaded.
+func (s *ReminderService) remindTask(task *models.Task, preferences func(uuid.UUID) (*models.NotificationPreferences, error), now time.Time) error {
+	due := *task.DueDate
+	creatorActive := task.Creator != nil && task.Creator.IsActive()
+
+	recipient, recipientActive := task.CreatedBy, creatorActive
+	if task.AssignedTo != nil && task.Assignee != nil && task.Assignee.IsActive() {
+		recipient, recipientActive = *task.AssignedTo, true
+	}
+	if !recipientActive {
+		return nil
+	}
+	p, err := preferences(recipient)
+	if err != nil {
+		return err
+	}
+	if p.DueDates {
+		if threshold, notificationType := reminderThreshold(now, due, p.ReminderLeadMinutes); threshold != "" {
+			if err := s.remind(task, recipient, threshold, notificationType, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	if s.config.EscalateAfter > 0 && task.Priority == models.PriorityUrgent && recipient != task.CreatedBy && creatorActive && !now.Before(due.Add(s.config.EscalateAfter)) {
+		p, err := preferences(task.CreatedBy)
+		if err != nil {
+			return err
+		}
+		if p.DueDates {
+			return s.remind(task, task.CreatedBy, "escalated", models.NotificationTypeEscalated, now)
+		}
+	}
+	return nil
+}
+
+// remind notifies the user about the task unless this threshold was already
+// reminded for its current due date. The claim is released when notifying
+// fails, so the reminder is not lost.
+func (s *ReminderService) remind(task *models.Task, userID uuid.UUID, threshold string, notificationType models.NotificationType, now time.Time) error {
+	reminder := &models.TaskReminder{
+		TaskID:    task.ID,
+		UserID:    userID,
+		Threshold: threshold,
+		DueDate:   *task.DueDate,
+		SentAt:    now,
+	}
+	claimed, err := s.reminderRepo.ClaimReminder(reminder)
+	if err != nil || !claimed {
+		return err
+	}
+	if err := s.notifications.Notify(userID, notificationType, task, nil); err != nil {
+		if releaseErr := s.reminderRepo.ReleaseReminder(reminder); releaseErr != nil {
+			log.Printf("Error releasing %s reminder of task %s: %v", threshold, task.ID, releaseErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// reminderThreshold returns the reminder threshold a task due at due has
+// reached at now, if any. Lead times are in minutes.
+func reminderThreshold(now, due time.Time, leadMinutes []int) (string, models.NotificationType) {
+	if !now.Before(due) {
+		return "overdue", models.NotificationTypeOverdue
+	}
+	shortest := 0
+	for _, minutes := range leadMinutes {
+		if !now.Before(due.Add(-time.Duration(minutes)*time.Minute)) && (shortest == 0 || minutes < shortest) {
+			shortest = minutes
+		}
+	}
+	if shortest == 0 {
+		return "", ""
+	}
+	return "due_soon:" + strconv.Itoa(shortest), models.NotificationTypeDueSoon
+}
@@ -8,15 +8,17 @@ import (
 	"github.com/google/uuid"
 )
 
-// TaskRepository interface for task service
+// TaskRepository interface for task service. Mutations record a task event
+// for actorID in the same transaction.
 type TaskRepository interface {
-	Create(task *models.Task) error
+	Create(task *models.Task, actorID uuid.UUID) error
 	FindByID(id uuid.UUID) (*models.Task, error)
-	Update(task *models.Task) error
-	Delete(id uuid.UUID) error
+	Update(task *models.Task, actorID uuid.UUID) error
+	Delete(id, actorID uuid.UUID) error
 	List(filter models.TaskFilter) ([]models.Task, int64, error)
-	UpdateStatus(id uuid.UUID, status models.TaskStatus) error
-	AssignTask(taskID, userID uuid.UUID) error
+	UpdateStatus(id uuid.UUID, status models.TaskStatus, actorID uuid.UUID) error
+	AssignTask(taskID, userID, actorID uuid.UUID) error
+	ListCollaborators(userID uuid.UUID) ([]uuid.UUID, error)
 }
 
 // TaskService handles task business logic
@@ -78,7 +80,7 @@ func (s *TaskService) Create(userID uuid.UUID, req CreateTaskRequest) (*models.T
 		CreatedBy:   userID,
 	}
 
-	if err := s.taskRepo.Create(task); err != nil {
+	if err := s.taskRepo.Create(task, userID); err != nil {
 		return nil, err
 	}
 
@@ -98,6 +100,26 @@ func (s *TaskService) GetByID(id uuid.UUID) (*models.Task, error) {
 	return task, nil
 }
 
+// Collaborators lists the users who share a task with the user, as creator or
+// assignee. They may see each other's presence.
+func (s *TaskService) Collaborators(userID uuid.UUID) ([]uuid.UUID, error) {
+	return s.taskRepo.ListCollaborators(userID)
+}
+
+// CanViewTask reports whether the user is the creator or assignee of a task
+func (s *TaskService) CanViewTask(userID, taskID uuid.UUID) (bool, error) {
+	task, err := s.taskRepo.FindByID(taskID)
+	if err != nil || task == nil {
+		return false, err
+	}
+	for _, id := range task.Audience() {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // Update updates a task
 func (s *TaskService) Update(id uuid.UUID, userID uuid.UUID, req UpdateTaskRequest) (*models.Task, error) {
 	task, err := s.GetByID(id)
@@ -127,7 +149,7 @@ func (s *TaskService) Update(id uuid.UUID, userID uuid.UUID, req UpdateTaskReque
 		if !req.Status.IsValid() {
 			return nil, errors.New("invalid status")
 		}
-		task.Status = *req.Status
+		task.SetStatus(*req.Status, time.Now())
 	}
 	if req.DueDate != nil {
 		parsed, err := time.Parse(time.RFC3339, *req.DueDate)
@@ -140,7 +162,7 @@ func (s *TaskService) Update(id uuid.UUID, userID uuid.UUID, req UpdateTaskReque
 		task.DueDate = &parsed
 	}
 
-	if err := s.taskRepo.Update(task); err != nil {
+	if err := s.taskRepo.Update(task, userID); err != nil {
 		return nil, err
 	}
 
@@ -159,7 +181,7 @@ func (s *TaskService) Delete(id uuid.UUID, userID uuid.UUID) error {
 		return errors.New("unauthorized to delete this task")
 	}
 
-	return s.taskRepo.Delete(id)
+	return s.taskRepo.Delete(id, userID)
 }
 
 // List lists tasks with filters
@@ -193,7 +215,7 @@ func (s *TaskService) UpdateStatus(id uuid.UUID, userID uuid.UUID, status models
 		return nil, errors.New("invalid status")
 	}
 
-	if err := s.taskRepo.UpdateStatus(id, status); err != nil {
+	if err := s.taskRepo.UpdateStatus(id, status, userID); err != nil {
 		return nil, err
 	}
 
@@ -220,8 +242,11 @@ func (s *TaskService) AssignTask(taskID uuid.UUID, assignToUserID uuid.UUID, req
 	if assignee == nil {
 		return nil, errors.New("assignee user not found")
 	}
+	if !assignee.IsActive() {
+		return nil, errors.New("assignee account is deactivated")
+	}
 
-	if err := s.taskRepo.AssignTask(taskID, assignToUserID); err != nil {
+	if err := s.taskRepo.AssignTask(taskID, assignToUserID, requestUserID); err != nil {
 		return nil, err
 	}
 
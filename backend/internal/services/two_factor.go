@@ -0,0 +1,337 @@
+package services
+
+import (
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/middleware"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/secure"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/totp"
+	"github.com/google/uuid"
+)
+
+// recoveryCodeCount is the number of recovery codes generated per user
+const recoveryCodeCount = 10
+
+const (
+	// twoFactorChallengeAttempts is the number of wrong codes a login
+	// challenge takes before it is discarded
+	twoFactorChallengeAttempts = 5
+	// twoFactorUserAttempts is the number of wrong codes a user may send
+	// across all challenges within twoFactorFailureWindow
+	twoFactorUserAttempts  = 10
+	twoFactorFailureWindow = 15 * time.Minute
+)
+
+// ErrTooManyTwoFactorAttempts is returned when a user sent too many wrong
+// 2FA codes recently
+var ErrTooManyTwoFactorAttempts = errors.New("too many invalid two-factor codes, try again later")
+
+// TwoFactorChallengeRepository interface for the 2FA login challenges
+type TwoFactorChallengeRepository interface {
+	Create(challenge *models.TwoFactorChallenge) error
+	FindByID(id uuid.UUID) (*models.TwoFactorChallenge, error)
+	RecordFailure(id uuid.UUID) error
+	Consume(id uuid.UUID, maxAttempts int, now time.Time) (bool, error)
+	CountFailures(userID uuid.UUID, since time.Time) (int, error)
+	DeleteExpiredByUser(userID uuid.UUID, before time.Time) error
+}
+
+// TwoFactorSetupResponse contains the data needed to enroll an authenticator app
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesResponse contains freshly generated recovery codes, shown only once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorCodeRequest represents a request carrying a TOTP code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest represents a request to disable 2FA
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// VerifyTwoFactorRequest represents the second step of a two-factor login.
+// Code may be a TOTP code or an unused recovery code.
+type VerifyTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+	// Replaces a temporary password set by an admin; required in that case
+	NewPassword string `json:"new_password" binding:"omitempty,min=6"`
+}
+
+// SetupTwoFactor generates a new TOTP secret for the user. 2FA is not enabled
+// until the secret is confirmed with a valid code.
+func (s *AuthService) SetupTwoFactor(userID uuid.UUID) (*TwoFactorSetupResponse, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(s.config.TwoFactor.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables 2FA once the user proves possession of the secret
+func (s *AuthService) ConfirmTwoFactor(userID uuid.UUID, req TwoFactorCodeRequest) (*RecoveryCodesResponse, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("two-factor setup has not been started")
+	}
+	if !s.checkTOTP(user, req.Code) {
+		return nil, errors.New("invalid two-factor code")
+	}
+
+	codes, err := s.resetRecoveryCodes(user)
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPEnabled = true
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor turns 2FA off after checking the password and a current code
+func (s *AuthService) DisableTwoFactor(userID uuid.UUID, req DisableTwoFactorRequest) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+	if !user.CheckPassword(req.Password) {
+		return errors.New("invalid password")
+	}
+	if !s.checkTOTP(user, req.Code) && !s.useRecoveryCode(user, req.Code) {
+		return errors.New("invalid two-factor code")
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	user.RecoveryCodes = ""
+	return s.userRepo.Update(user)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a current code
+func (s *AuthService) RegenerateRecoveryCodes(userID uuid.UUID, req TwoFactorCodeRequest) (*RecoveryCodesResponse, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+	if !s.checkTOTP(user, req.Code) {
+		return nil, errors.New("invalid two-factor code")
+	}
+
+	codes, err := s.resetRecoveryCodes(user)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// VerifyTwoFactor exchanges a login challenge token and a valid code for real
+// tokens. Each challenge can be exchanged once, and wrong codes are capped per
+// challenge and per user.
+func (s *AuthService) VerifyTwoFactor(req VerifyTwoFactorRequest) (*AuthResponse, error) {
+	errInvalidChallenge := errors.New("invalid or expired challenge")
+	now := time.Now()
+
+	claims, err := s.parseToken(req.ChallengeToken)
+	if err != nil || claims.TokenType != middleware.TokenTypeTwoFactorChallenge {
+		return nil, errInvalidChallenge
+	}
+	challengeID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, errInvalidChallenge
+	}
+	challenge, err := s.challenges.FindByID(challengeID)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil || challenge.UserID != claims.UserID || challenge.UsedAt != nil ||
+		challenge.Attempts >= twoFactorChallengeAttempts || !now.Before(challenge.ExpiresAt) {
+		return nil, errInvalidChallenge
+	}
+
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.TOTPEnabled {
+		return nil, errInvalidChallenge
+	}
+	if !user.IsActive() {
+		return nil, ErrAccountDeactivated
+	}
+	failures, err := s.challenges.CountFailures(user.ID, now.Add(-twoFactorFailureWindow))
+	if err != nil {
+		return nil, err
+	}
+	if failures >= twoFactorUserAttempts {
+		return nil, ErrTooManyTwoFactorAttempts
+	}
+	// Checked before the code so a missing password does not burn it
+	if user.MustChangePassword {
+		if err := s.checkNewPassword(user, req.NewPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	if !s.checkTOTP(user, req.Code) && !s.useRecoveryCode(user, req.Code) {
+		if err := s.challenges.RecordFailure(challenge.ID); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("invalid two-factor code")
+	}
+	consumed, err := s.challenges.Consume(challenge.ID, twoFactorChallengeAttempts, now)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, errInvalidChallenge
+	}
+	if user.MustChangePassword {
+		if err := s.replaceTemporaryPassword(user, req.NewPassword); err != nil {
+			return nil, err
+		}
+	} else if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user)
+}
+
+// issueChallenge records a new login challenge for the user and returns its
+// token. Challenges that expired before the failure window are dropped on the
+// way, since their wrong codes no longer count.
+func (s *AuthService) issueChallenge(user *models.User) (string, error) {
+	if err := s.challenges.DeleteExpiredByUser(user.ID, time.Now().Add(-twoFactorFailureWindow)); err != nil {
+		return "", err
+	}
+
+	claims := s.newClaims(user.ID, user.Email, middleware.TokenTypeTwoFactorChallenge)
+	challenge := &models.TwoFactorChallenge{
+		UserID:    user.ID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+	if err := s.challenges.Create(challenge); err != nil {
+		return "", err
+	}
+
+	claims.ID = challenge.ID.String()
+	return s.keys.Sign(claims)
+}
+
+// findUser loads a user that must exist
+func (s *AuthService) findUser(userID uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// checkTOTP validates a code and records its time step so it cannot be replayed.
+// The caller is responsible for persisting the user.
+func (s *AuthService) checkTOTP(user *models.User, code string) bool {
+	step, ok := totp.Validate(code, user.TOTPSecret, time.Now())
+	if !ok || step <= user.TOTPLastStep {
+		return false
+	}
+	user.TOTPLastStep = step
+	return true
+}
+
+// useRecoveryCode consumes a recovery code if it matches one of the stored hashes.
+// The caller is responsible for persisting the user.
+func (s *AuthService) useRecoveryCode(user *models.User, code string) bool {
+	if user.RecoveryCodes == "" {
+		return false
+	}
+
+	hash := hashRecoveryCode(code)
+	hashes := strings.Split(user.RecoveryCodes, ",")
+	for i, h := range hashes {
+		if h == hash {
+			hashes = append(hashes[:i], hashes[i+1:]...)
+			user.RecoveryCodes = strings.Join(hashes, ",")
+			return true
+		}
+	}
+	return false
+}
+
+// resetRecoveryCodes generates new recovery codes, storing only their hashes
+func (s *AuthService) resetRecoveryCodes(user *models.User) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	for i := range codes {
+		buf, err := secure.Bytes(6)
+		if err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(buf))
+		codes[i] = raw[:5] + "-" + raw[5:10]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	user.RecoveryCodes = strings.Join(hashes, ",")
+	return codes, nil
+}
+
+// hashRecoveryCode normalizes and hashes a recovery code
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return secure.Hash(normalized)
+}
@@ -1,22 +1,188 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/mailer"
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/secure"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/storage"
+	"github.com/google/uuid"
 )
 
+// emailVerificationTTL is how long an email change link remains valid
+const emailVerificationTTL = 24 * time.Hour
+
+// localePattern loosely matches BCP 47 language tags such as "es" or "es-AR"
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
 // UserService handles user business logic
 type UserService struct {
 	userRepo UserRepository
+	mailer   mailer.Mailer
+	storage  storage.Storage
+	config   *config.Config
 }
 
 // NewUserService creates a new user service
-func NewUserService(userRepo UserRepository) *UserService {
+func NewUserService(userRepo UserRepository, mailer mailer.Mailer, storage storage.Storage, cfg *config.Config) *UserService {
 	return &UserService{
 		userRepo: userRepo,
+		mailer:   mailer,
+		storage:  storage,
+		config:   cfg,
+	}
+}
+
+// UpdateProfileRequest represents a profile update. Changing the email or the
+// password requires the current password; a new email only takes effect once
+// verified.
+type UpdateProfileRequest struct {
+	Name            *string `json:"name" binding:"omitempty,min=2,max=100"`
+	Email           *string `json:"email" binding:"omitempty,email"`
+	CurrentPassword string  `json:"current_password"`
+	NewPassword     *string `json:"new_password" binding:"omitempty,min=6"`
+	Timezone        *string `json:"timezone"`
+	Locale          *string `json:"locale"`
+}
+
+// List lists users with search and pagination, normalizing filter to the page
+// actually served. Any authenticated user can list every active user, not only
+// those they share tasks with, so tasks can be assigned to anyone; only the
+// public summary of each user should be exposed.
+func (s *UserService) List(filter *models.UserFilter) ([]models.User, int64, error) {
+	filter.Normalize()
+	return s.userRepo.List(*filter)
+}
+
+// GetProfile gets the profile of a user
+func (s *UserService) GetProfile(userID uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
 	}
+	return user, nil
 }
 
-// List lists all users
-func (s *UserService) List() ([]models.User, error) {
-	return s.userRepo.List()
+// UpdateProfile updates the profile of a user
+func (s *UserService) UpdateProfile(ctx context.Context, userID uuid.UUID, req UpdateProfileRequest) (*models.User, error) {
+	user, err := s.GetProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	emailChanged := req.Email != nil && !strings.EqualFold(*req.Email, user.Email)
+	if (emailChanged || req.NewPassword != nil) && !user.CheckPassword(req.CurrentPassword) {
+		return nil, errors.New("current password is incorrect")
+	}
+
+	if req.Name != nil {
+		user.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" || *req.Timezone == "Local" {
+			return nil, errors.New("invalid timezone")
+		}
+		user.Timezone = *req.Timezone
+	}
+	if req.Locale != nil {
+		if !localePattern.MatchString(*req.Locale) {
+			return nil, errors.New("invalid locale")
+		}
+		user.Locale = *req.Locale
+	}
+	if req.NewPassword != nil {
+		user.Password = *req.NewPassword
+		if err := user.HashPassword(); err != nil {
+			return nil, err
+		}
+		user.MustChangePassword = false
+	}
+
+	var verificationToken string
+	if emailChanged {
+		exists, err := s.userRepo.EmailExists(*req.Email)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, errors.New("email already registered")
+		}
+
+		verificationToken, err = secure.Token(32)
+		if err != nil {
+			return nil, err
+		}
+		expiresAt := time.Now().Add(emailVerificationTTL)
+		user.PendingEmail = models.NormalizeEmail(*req.Email)
+		user.EmailVerificationTokenHash = secure.Hash(verificationToken)
+		user.EmailVerificationExpiresAt = &expiresAt
+	}
+
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	if verificationToken != "" {
+		s.sendEmailVerification(ctx, user, verificationToken)
+	}
+
+	return user, nil
+}
+
+// VerifyEmail confirms a pending email change
+func (s *UserService) VerifyEmail(token string) (*models.User, error) {
+	user, err := s.userRepo.FindByEmailVerificationToken(secure.Hash(token))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.PendingEmail == "" || user.EmailVerificationExpiresAt == nil ||
+		time.Now().After(*user.EmailVerificationExpiresAt) {
+		return nil, errors.New("invalid or expired verification token")
+	}
+
+	// The address may have been taken since the change was requested
+	exists, err := s.userRepo.EmailExists(user.PendingEmail)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errors.New("email already registered")
+	}
+
+	user.Email = user.PendingEmail
+	user.PendingEmail = ""
+	user.EmailVerificationTokenHash = ""
+	user.EmailVerificationExpiresAt = nil
+
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// sendEmailVerification mails the verification link to the new address
+func (s *UserService) sendEmailVerification(ctx context.Context, user *models.User, token string) {
+	link := fmt.Sprintf("%s/api/v1/auth/verify-email?token=%s", strings.TrimSuffix(s.config.Server.PublicURL, "/"), url.QueryEscape(token))
+
+	err := s.mailer.Send(ctx, mailer.Message{
+		To:      user.PendingEmail,
+		Subject: "Confirm your new TaskFlow email address",
+		Text: fmt.Sprintf("Hi %s,\n\nConfirm this address for your TaskFlow account by opening:\n%s\n\nThe link expires in 24 hours. If you did not request this change, ignore this email.\n",
+			user.Name, link),
+	})
+	if err != nil {
+		log.Printf("Failed to send email verification to user %s: %v", user.ID, err)
+	}
 }
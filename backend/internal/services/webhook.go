@@ -0,0 +1,477 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/secure"
+	"github.com/google/uuid"
+)
+
+// Headers sent with every webhook delivery
+const (
+	WebhookSignatureHeader = "X-TaskFlow-Signature" // "sha256=" + hex HMAC of "<timestamp>.<body>"
+	WebhookTimestampHeader = "X-TaskFlow-Timestamp" // Unix seconds when the request was signed
+	WebhookEventHeader     = "X-TaskFlow-Event"
+	WebhookDeliveryHeader  = "X-TaskFlow-Delivery"
+)
+
+const (
+	webhookSecretPrefix    = "whsec_"
+	webhookMinSecretLength = 16
+	webhookBatchSize       = 20
+	webhookDeliveryLog     = 50             // Deliveries listed per webhook
+	webhookMaxRetryDelay   = 24 * time.Hour // Cap on the exponential backoff
+	webhookMaxErrorLength  = 500            // Stored error messages are truncated
+	webhookMaxResponseBody = 64 << 10       // Response bytes read before closing
+)
+
+// ErrWebhookNotFound is returned for unknown webhooks and those of other users
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// ErrWebhookDeliveryNotFound is returned for deliveries of other webhooks
+var ErrWebhookDeliveryNotFound = errors.New("delivery not found")
+
+// WebhookRepository interface for webhook service
+type WebhookRepository interface {
+	Create(webhook *models.Webhook) error
+	FindByID(id uuid.UUID) (*models.Webhook, error)
+	ListByUser(userID uuid.UUID) ([]models.Webhook, error)
+	ListActiveByUsers(userIDs []uuid.UUID) ([]models.Webhook, error)
+	SetActiveByUser(userID uuid.UUID, active bool) error
+	Delete(id uuid.UUID) error
+	CreateDelivery(delivery *models.WebhookDelivery) error
+	FindDelivery(id uuid.UUID) (*models.WebhookDelivery, error)
+	ListDeliveries(webhookID uuid.UUID, limit int) ([]models.WebhookDelivery, error)
+	ClaimDueDeliveries(now time.Time, limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	UpdateDelivery(delivery *models.WebhookDelivery) error
+}
+
+// WebhookService manages webhook subscriptions and delivers task events to
+// them from a background worker
+type WebhookService struct {
+	webhookRepo WebhookRepository
+	client      *http.Client
+	config      config.WebhookConfig
+}
+
+// NewWebhookService creates a new webhook service. A nil client uses one with
+// the configured timeout that does not follow redirects and, unless private
+// addresses are allowed, refuses to connect to them.
+func NewWebhookService(webhookRepo WebhookRepository, client *http.Client, cfg config.WebhookConfig) *WebhookService {
+	if client == nil {
+		client = newWebhookClient(cfg)
+	}
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		client:      client,
+		config:      cfg,
+	}
+}
+
+// CreateWebhookRequest represents a create webhook request
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,max=2048"`
+	EventTypes []string `json:"event_types"`              // created, updated, deleted, assigned; empty for all
+	Secret     string   `json:"secret" binding:"max=100"` // Generated when empty
+}
+
+// CreateWebhookResponse contains the signing secret, shown only once
+type CreateWebhookResponse struct {
+	models.Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookPayload is the JSON body of a delivery. EventID increases with every
+// event and is shared by all deliveries of the same event, including
+// redeliveries, so receivers can order events and ignore duplicates.
+type WebhookPayload struct {
+	EventID    uint64       `json:"event_id"`
+	Type       string       `json:"type"`
+	TaskID     uuid.UUID    `json:"task_id"`
+	Task       *models.Task `json:"task,omitempty"`
+	UserID     uuid.UUID    `json:"user_id"` // User who triggered the event
+	OccurredAt time.Time    `json:"occurred_at"`
+}
+
+// Create subscribes a URL to the task events the user can see
+func (s *WebhookService) Create(userID uuid.UUID, req CreateWebhookRequest) (*CreateWebhookResponse, error) {
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, errors.New("url must be an absolute http or https URL")
+	}
+	if !s.config.AllowPrivate {
+		if err := checkPublicHost(target.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+	for _, eventType := range req.EventTypes {
+		if !models.IsValidTaskEventType(eventType) {
+			return nil, fmt.Errorf("invalid event type: %s", eventType)
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		random, err := secure.Token(32)
+		if err != nil {
+			return nil, err
+		}
+		secret = webhookSecretPrefix + random
+	} else if len(secret) < webhookMinSecretLength {
+		return nil, fmt.Errorf("secret must be at least %d characters", webhookMinSecretLength)
+	}
+
+	webhook := models.Webhook{
+		UserID:     userID,
+		URL:        target.String(),
+		Secret:     secret,
+		EventTypes: req.EventTypes,
+		Active:     true,
+	}
+	if err := s.webhookRepo.Create(&webhook); err != nil {
+		return nil, err
+	}
+	return &CreateWebhookResponse{Webhook: webhook, Secret: secret}, nil
+}
+
+// List lists the user's webhooks
+func (s *WebhookService) List(userID uuid.UUID) ([]models.Webhook, error) {
+	return s.webhookRepo.ListByUser(userID)
+}
+
+// Delete deletes one of the user's webhooks with its delivery log
+func (s *WebhookService) Delete(userID, webhookID uuid.UUID) error {
+	if _, err := s.findOwned(userID, webhookID); err != nil {
+		return err
+	}
+	return s.webhookRepo.Delete(webhookID)
+}
+
+// Deliveries lists the latest deliveries of one of the user's webhooks
+func (s *WebhookService) Deliveries(userID, webhookID uuid.UUID) ([]models.WebhookDelivery, error) {
+	if _, err := s.findOwned(userID, webhookID); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.ListDeliveries(webhookID, webhookDeliveryLog)
+}
+
+// Redeliver queues a new delivery with the payload of an earlier one
+func (s *WebhookService) Redeliver(userID, webhookID, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	if _, err := s.findOwned(userID, webhookID); err != nil {
+		return nil, err
+	}
+	original, err := s.webhookRepo.FindDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if original == nil || original.WebhookID != webhookID {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		WebhookID:     webhookID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+	}
+	if err := s.webhookRepo.CreateDelivery(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// SinkName identifies the webhook service among the outbox sinks
+func (s *WebhookService) SinkName() string {
+	return "webhooks"
+}
+
+// HandleTaskEvent queues a task event for the webhooks of the users involved
+// in the task: whoever triggered the event and the audience of the task,
+// before and after the change. Each webhook gets one delivery per event, even
+// when the event is handled again after a failure.
+func (s *WebhookService) HandleTaskEvent(event *models.OutboxEvent) error {
+	owners := []uuid.UUID{event.ActorID}
+	for _, task := range []*models.Task{event.Task, event.Previous} {
+		if task != nil {
+			owners = append(owners, task.Audience()...)
+		}
+	}
+	webhooks, err := s.webhookRepo.ListActiveByUsers(uniqueUUIDs(owners))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	payload, err := json.Marshal(WebhookPayload{
+		EventID:    event.ID,
+		Type:       event.Type,
+		TaskID:     event.TaskID,
+		Task:       event.Task,
+		UserID:     event.ActorID,
+		OccurredAt: event.CreatedAt.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range webhooks {
+		if !webhooks[i].Accepts(event.Type) {
+			continue
+		}
+		eventID := event.ID
+		delivery := &models.WebhookDelivery{
+			WebhookID:     webhooks[i].ID,
+			EventID:       &eventID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+		}
+		if err := s.webhookRepo.CreateDelivery(delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run delivers due webhooks every PollInterval until ctx is done
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.DeliverDue(ctx); err != nil {
+			log.Printf("Error delivering webhooks: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// DeliverDue sends the deliveries whose next attempt is due. Failed attempts
+// are retried with exponential backoff until MaxAttempts.
+func (s *WebhookService) DeliverDue(ctx context.Context) error {
+	for {
+		// Claimed deliveries are hidden from other instances for longer than
+		// a batch can take to send
+		lease := s.config.Timeout*webhookBatchSize + time.Minute
+		deliveries, err := s.webhookRepo.ClaimDueDeliveries(time.Now(), webhookBatchSize, lease)
+		if err != nil {
+			return err
+		}
+		for i := range deliveries {
+			s.attempt(ctx, &deliveries[i])
+			if err := s.webhookRepo.UpdateDelivery(&deliveries[i]); err != nil {
+				return err
+			}
+		}
+		if len(deliveries) < webhookBatchSize || ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// attempt sends a delivery once and records the outcome on it
+func (s *WebhookService) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = 0
+	delivery.Error = ""
+
+	webhook := delivery.Webhook
+	if webhook == nil || !webhook.Active {
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+		delivery.Error = "webhook is disabled or deleted"
+		return
+	}
+
+	status, err := s.send(ctx, webhook, delivery, now)
+	delivery.ResponseStatus = status
+	if err == nil {
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.NextAttemptAt = nil
+		return
+	}
+
+	delivery.Error = err.Error()
+	if len(delivery.Error) > webhookMaxErrorLength {
+		delivery.Error = delivery.Error[:webhookMaxErrorLength]
+	}
+	if delivery.Attempts >= s.config.MaxAttempts {
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+		return
+	}
+	next := now.Add(webhookRetryDelay(s.config.RetryBaseDelay, delivery.Attempts))
+	delivery.NextAttemptAt = &next
+}
+
+// send posts the payload and returns the response status. Any status other
+// than 2xx is an error.
+func (s *WebhookService) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TaskFlow-Webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID.String())
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, webhookMaxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload returns the signature header value for a request body.
+// Receivers recompute it with their secret and the timestamp header, and
+// should reject old timestamps to prevent replays.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetryDelay returns the wait after the given number of failed
+// attempts: base, 2*base, 4*base... up to webhookMaxRetryDelay
+func webhookRetryDelay(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < webhookMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxRetryDelay {
+		delay = webhookMaxRetryDelay
+	}
+	return delay
+}
+
+// findOwned returns a webhook of the user
+func (s *WebhookService) findOwned(userID, webhookID uuid.UUID) (*models.Webhook, error) {
+	webhook, err := s.webhookRepo.FindByID(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil || webhook.UserID != userID {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// uniqueUUIDs drops duplicate and nil IDs, keeping the first occurrence
+func uniqueUUIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if id == uuid.Nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
+
+// newWebhookClient creates the client deliveries are sent with. Redirects are
+// not followed: the 3xx response fails the attempt. The address is checked
+// when connecting, after DNS resolution, so a host that later resolves to a
+// private address is refused too. Proxies are not used, since they would
+// connect on the client's behalf.
+func newWebhookClient(cfg config.WebhookConfig) *http.Client {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivate {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("refusing to connect to non-public address %s", host)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkPublicHost resolves a webhook host and rejects it unless every address
+// it resolves to is public
+func checkPublicHost(host string) error {
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		var err error
+		if ips, err = net.LookupIP(host); err != nil {
+			return fmt.Errorf("url host could not be resolved: %s", host)
+		}
+	}
+	for _, ip := range ips {
+		if !isPublicIP(ip) {
+			return errors.New("url must not point to a loopback, private or link-local address")
+		}
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), not covered by
+// net.IP.IsPrivate
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP reports whether an address is routable on the internet. Loopback,
+// private (RFC 1918 and IPv6 ULA), link-local, including the cloud metadata
+// address 169.254.169.254, unspecified and multicast addresses are not.
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified() &&
+		!sharedAddressSpace.Contains(ip)
+}
@@ -0,0 +1,57 @@
+package websocket
+
+import "time"
+
+// MessageTypeResyncRequired tells a reconnecting client that events it missed
+// are no longer available and it must reload its data
+const MessageTypeResyncRequired = "resync_required"
+
+// backlogEntry is a delivered event kept for replay
+type backlogEntry struct {
+	id      uint64
+	message []byte
+}
+
+// userBacklog is a bounded ring of the latest events sent to one user. Only
+// the hub's Run goroutine touches it.
+type userBacklog struct {
+	entries []backlogEntry
+	next    int       // write position once the ring is full
+	evicted uint64    // ID of the newest event pushed out of the ring
+	updated time.Time // when the last event was added
+}
+
+func (b *userBacklog) add(entry backlogEntry, size int) {
+	b.updated = time.Now()
+	if len(b.entries) < size {
+		b.entries = append(b.entries, entry)
+		return
+	}
+	b.evicted = b.entries[b.next].id
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % size
+}
+
+// newest returns the ID of the latest event in the backlog
+func (b *userBacklog) newest() uint64 {
+	if len(b.entries) == 0 {
+		return b.evicted
+	}
+	return b.entries[(b.next+len(b.entries)-1)%len(b.entries)].id
+}
+
+// since returns the events newer than lastID, oldest first. ok is false when
+// some of them were already evicted.
+func (b *userBacklog) since(lastID uint64) (entries []backlogEntry, ok bool) {
+	if lastID < b.evicted {
+		return nil, false
+	}
+	n := len(b.entries)
+	for i := 0; i < n; i++ {
+		entry := b.entries[(b.next+i)%n]
+		if entry.id > lastID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, true
+}
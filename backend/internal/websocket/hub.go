@@ -1,115 +1,528 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/eventbus"
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
-// Client represents a WebSocket client
+// Close reasons sent to clients the hub disconnects
+const (
+	CloseReasonTooSlow        = "client too slow"
+	CloseReasonSessionRevoked = "session revoked"
+)
+
+// ErrClientClosed is returned by Next once the hub has closed the client and
+// every pending message was read
+var ErrClientClosed = errors.New("client closed")
+
+// Client represents a connected client. Conn is nil for Server-Sent Events
+// streams. Create clients with NewClient.
 type Client struct {
 	ID     uuid.UUID
 	UserID uuid.UUID
 	Conn   *websocket.Conn
-	Send   chan []byte
 	Hub    *Hub
+
+	// LastEventID is the ID of the last event a reconnecting client saw. When
+	// set, the events it missed are replayed on registration.
+	LastEventID *uint64
+
+	queue         *outbox
+	subscriptions subscriptions
+	viewing       *uuid.UUID // task being viewed, guarded by the hub lock
+}
+
+// NewClient creates a client of the hub with a queue of QueueSize messages.
+// Conn may be nil for transports other than WebSocket.
+func NewClient(hub *Hub, userID uuid.UUID, conn *websocket.Conn) *Client {
+	return &Client{
+		ID:     uuid.New(),
+		UserID: userID,
+		Conn:   conn,
+		Hub:    hub,
+		queue:  newOutbox(hub.config.QueueSize),
+	}
+}
+
+// Next returns the next queued message, waiting until one is available or ctx
+// is done. It returns ErrClientClosed after the last message of a closed
+// client.
+func (c *Client) Next(ctx context.Context) ([]byte, error) {
+	for {
+		message, closed := c.queue.pop()
+		if message != nil {
+			return message, nil
+		}
+		if closed {
+			return nil, ErrClientClosed
+		}
+		select {
+		case <-c.queue.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
 }
 
-// Hub maintains active clients and broadcasts messages
+// CloseReason returns the close code and reason the hub gave when it closed
+// the client, or zero values while it is open
+func (c *Client) CloseReason() (int, string) {
+	return c.queue.reason()
+}
+
+// Hub maintains active clients and delivers messages to them
 type Hub struct {
 	Clients    map[uuid.UUID]*Client
-	Broadcast  chan []byte
 	Register   chan *Client
 	Unregister chan *Client
+	deliveries chan eventbus.Envelope
+	direct     chan directMessage
+	users      map[uuid.UUID]map[uuid.UUID]*Client // user ID -> client ID -> client
+	viewers    map[uuid.UUID]map[uuid.UUID]int     // task ID -> user ID -> clients viewing
+	authorizer TaskAuthorizer
+	bus        eventbus.Bus
+	sharedBus  bool // other instances may deliver to clients this hub does not know
+	config     config.WebSocketConfig
 	mu         sync.RWMutex
+
+	// Event IDs are the sequence numbers carried in the envelopes, the same on
+	// every instance. firstEventID starts the latest unbroken run of sequences
+	// this process received; clients that last saw an older event missed
+	// events it cannot replay.
+	firstEventID uint64
+	lastEventID  uint64
+	backlogs     map[uuid.UUID]*userBacklog
+
+	// droppedEventID is the newest event of the backlogs dropped for being
+	// idle. Users without a backlog who last saw an older event may have
+	// missed events that were dropped with it.
+	droppedEventID uint64
+
+	slowClientsDropped atomic.Uint64
+	messagesCoalesced  atomic.Uint64
+	presenceDropped    atomic.Uint64
+}
+
+// directMessage is a message for a single client, such as a protocol reply
+type directMessage struct {
+	client  *Client
+	message []byte
 }
 
-// NewHub creates a new WebSocket hub
-func NewHub() *Hub {
-	return &Hub{
+// NewHub creates a new WebSocket hub. The authorizer checks task
+// subscriptions; when nil, subscribing to a task is refused. Events are
+// published on the bus and delivered to local clients as they come back from
+// it; a nil bus keeps them in process.
+func NewHub(cfg config.WebSocketConfig, authorizer TaskAuthorizer, bus eventbus.Bus) *Hub {
+	if cfg.PingInterval <= 0 || cfg.PingInterval >= cfg.PongTimeout {
+		cfg.PingInterval = cfg.PongTimeout * 9 / 10
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 256
+	}
+	if bus == nil {
+		bus = eventbus.NewLocalBus()
+	}
+	_, local := bus.(*eventbus.LocalBus)
+	h := &Hub{
 		Clients:    make(map[uuid.UUID]*Client),
-		Broadcast:  make(chan []byte, 256),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
+		deliveries: make(chan eventbus.Envelope, 256),
+		direct:     make(chan directMessage, 256),
+		users:      make(map[uuid.UUID]map[uuid.UUID]*Client),
+		viewers:    make(map[uuid.UUID]map[uuid.UUID]int),
+		authorizer: authorizer,
+		bus:        bus,
+		sharedBus:  !local,
+		config:     cfg,
+		backlogs:   make(map[uuid.UUID]*userBacklog),
 	}
+	bus.Subscribe(func(envelope eventbus.Envelope) {
+		h.deliveries <- envelope
+	})
+	return h
 }
 
-// Run starts the hub
+// Run starts the hub. Backlogs idle for longer than BacklogTTL are dropped
+// along the way.
 func (h *Hub) Run() {
+	var sweep <-chan time.Time
+	if h.config.BacklogTTL > 0 {
+		ticker := time.NewTicker(h.config.BacklogTTL)
+		defer ticker.Stop()
+		sweep = ticker.C
+	}
+
 	for {
 		select {
 		case client := <-h.Register:
 			h.mu.Lock()
 			h.Clients[client.ID] = client
+			if h.users[client.UserID] == nil {
+				h.users[client.UserID] = make(map[uuid.UUID]*Client)
+			}
+			h.users[client.UserID][client.ID] = client
+			h.userJoined(client)
+			if client.LastEventID != nil {
+				h.replay(client, *client.LastEventID)
+			}
 			h.mu.Unlock()
-			log.Printf("Client %s (User %s) connected. Total clients: %d", client.ID, client.UserID, len(h.Clients))
+			log.Printf("Client %s (User %s) connected. Total clients: %d", client.ID, client.UserID, h.ClientCount())
 
 		case client := <-h.Unregister:
 			h.mu.Lock()
-			if _, ok := h.Clients[client.ID]; ok {
-				delete(h.Clients, client.ID)
-				close(client.Send)
-			}
+			h.removeClient(client, websocket.CloseNormalClosure, "")
 			h.mu.Unlock()
-			log.Printf("Client %s disconnected. Total clients: %d", client.ID, len(h.Clients))
-
-		case message := <-h.Broadcast:
-			h.mu.RLock()
-			for _, client := range h.Clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(h.Clients, client.ID)
+			log.Printf("Client %s disconnected. Total clients: %d", client.ID, h.ClientCount())
+
+		case d := <-h.deliveries:
+			if d.Sequence != 0 {
+				// The outbox publishes in order, so an older sequence is an
+				// event published again after a failed dispatch
+				if d.Sequence <= h.lastEventID {
+					continue
+				}
+				// A skipped sequence may be an event this instance missed,
+				// e.g. while the bus reconnected, so replay starts over here
+				if h.firstEventID == 0 || d.Sequence != h.lastEventID+1 {
+					h.firstEventID = d.Sequence
+				}
+				h.lastEventID = d.Sequence
+			}
+			d.Event.ID = d.Sequence
+			message, err := json.Marshal(d.Event)
+			if err != nil {
+				log.Printf("Error marshaling task event: %v", err)
+				continue
+			}
+
+			item := outboxItem{key: coalesceKey(d.Event), message: message}
+
+			h.mu.Lock()
+			for _, userID := range d.Recipients {
+				if d.Sequence != 0 {
+					h.record(userID, backlogEntry{id: d.Sequence, message: message})
 				}
+				for _, client := range h.users[userID] {
+					if client.subscriptions.matches(d.Channels) {
+						h.enqueue(client, item)
+					}
+				}
+			}
+			h.mu.Unlock()
+
+		case dm := <-h.direct:
+			h.mu.Lock()
+			if _, ok := h.Clients[dm.client.ID]; ok {
+				h.enqueue(dm.client, outboxItem{message: dm.message})
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
+
+		case now := <-sweep:
+			h.mu.Lock()
+			h.dropIdleBacklogs(now.Add(-h.config.BacklogTTL))
+			h.mu.Unlock()
+		}
+	}
+}
+
+// dropIdleBacklogs removes the backlogs that got no event since cutoff, so
+// users who stopped receiving events do not hold memory forever. Clients that
+// reconnect after that get resync_required. The caller must hold the write
+// lock.
+func (h *Hub) dropIdleBacklogs(cutoff time.Time) {
+	for userID, backlog := range h.backlogs {
+		if backlog.updated.Before(cutoff) {
+			if newest := backlog.newest(); newest > h.droppedEventID {
+				h.droppedEventID = newest
+			}
+			delete(h.backlogs, userID)
 		}
 	}
 }
 
-// BroadcastTaskEvent broadcasts a task event to all connected clients
-func (h *Hub) BroadcastTaskEvent(event models.TaskEvent) {
-	message, err := json.Marshal(event)
-	if err != nil {
-		log.Printf("Error marshaling task event: %v", err)
+// coalesceKey returns the key under which an event replaces a pending one.
+// Updates carry the whole task, so only the latest pending update of a task is
+// worth sending; other events are all delivered.
+func coalesceKey(event models.TaskEvent) string {
+	if event.Type == models.TaskEventUpdated && event.Task != nil {
+		return "updated:" + event.TaskID.String()
+	}
+	return ""
+}
+
+// enqueue queues a message for a client. A client whose queue is full cannot
+// keep up, so it is disconnected and told why rather than silently missing
+// events; it can reconnect with its last event ID to catch up. The caller must
+// hold the write lock.
+func (h *Hub) enqueue(client *Client, item outboxItem) {
+	coalesced, ok := client.queue.push(item)
+	if coalesced {
+		h.messagesCoalesced.Add(1)
+	}
+	if !ok {
+		h.slowClientsDropped.Add(1)
+		log.Printf("Client %s (User %s) is too slow, disconnecting", client.ID, client.UserID)
+		h.removeClient(client, websocket.CloseTryAgainLater, CloseReasonTooSlow)
+	}
+}
+
+// record keeps an event in the user's backlog
+func (h *Hub) record(userID uuid.UUID, entry backlogEntry) {
+	if h.config.BacklogSize <= 0 {
+		return
+	}
+	backlog := h.backlogs[userID]
+	if backlog == nil {
+		backlog = &userBacklog{}
+		h.backlogs[userID] = backlog
+	}
+	backlog.add(entry, h.config.BacklogSize)
+}
+
+// replay sends a reconnecting client the events after lastID, or tells it to
+// resync when they are no longer available. The caller must hold the write
+// lock.
+func (h *Hub) replay(client *Client, lastID uint64) {
+	if lastID == h.lastEventID {
+		return
+	}
+
+	missed, ok := h.missedEvents(client.UserID, lastID)
+	if ok && len(missed) > client.queue.space() {
+		ok = false
+	}
+	if !ok {
+		message, _ := json.Marshal(ServerReply{Type: MessageTypeResyncRequired})
+		missed = []backlogEntry{{message: message}}
+	}
+	for _, entry := range missed {
+		client.queue.push(outboxItem{message: entry.message})
+	}
+}
+
+// missedEvents returns the user's events after lastID. ok is false when some
+// of them cannot be replayed.
+func (h *Hub) missedEvents(userID uuid.UUID, lastID uint64) (entries []backlogEntry, ok bool) {
+	// IDs from before this process received its first event, or not received
+	// here yet, cannot be accounted for
+	if h.firstEventID == 0 || lastID < h.firstEventID-1 || lastID > h.lastEventID || h.config.BacklogSize <= 0 {
+		return nil, false
+	}
+	backlog := h.backlogs[userID]
+	if backlog == nil {
+		return nil, lastID >= h.droppedEventID
+	}
+	return backlog.since(lastID)
+}
+
+// removeClient drops a client from the hub and closes its queue with the given
+// close code and reason. The caller must hold the write lock.
+func (h *Hub) removeClient(client *Client, code int, reason string) {
+	if _, ok := h.Clients[client.ID]; !ok {
 		return
 	}
-	h.Broadcast <- message
+	delete(h.Clients, client.ID)
+	if clients := h.users[client.UserID]; clients != nil {
+		delete(clients, client.ID)
+		if len(clients) == 0 {
+			delete(h.users, client.UserID)
+		}
+	}
+	client.queue.close(code, reason)
+	h.userLeft(client)
+}
+
+// ClientCount returns the number of connected clients
+func (h *Hub) ClientCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.Clients)
+}
+
+// Metrics returns counters about connected clients and delivery
+func (h *Hub) Metrics() models.RealtimeMetrics {
+	return models.RealtimeMetrics{
+		Clients:            h.ClientCount(),
+		SlowClientsDropped: h.slowClientsDropped.Load(),
+		MessagesCoalesced:  h.messagesCoalesced.Load(),
+		PresenceDropped:    h.presenceDropped.Load(),
+	}
+}
+
+// DisconnectUser closes every connection of a user and returns how many were
+// closed. WebSocket clients receive a close frame; event streams end with a
+// closed message.
+func (h *Hub) DisconnectUser(userID uuid.UUID) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	closed := 0
+	for _, client := range h.users[userID] {
+		h.removeClient(client, websocket.ClosePolicyViolation, CloseReasonSessionRevoked)
+		closed++
+	}
+	return closed
+}
+
+// BroadcastTaskEvent sends a task event to the users who can see the task:
+// its creator and assignee, plus the user who triggered the event. The event
+// ID, the outbox ID of the event, orders it for replay; events without one are
+// delivered live only. Previous is
+// the task as it was before the change, if known, so that users who lost
+// access through it (a former assignee, everyone involved in a deleted task)
+// are notified too. Clients that subscribed to channels only receive the event
+// if they follow the task or, as its assignee, their assignments.
+func (h *Hub) BroadcastTaskEvent(event models.TaskEvent, previous *models.Task) error {
+	recipients := []uuid.UUID{event.UserID}
+	channels := []string{TaskChannel(event.TaskID)}
+	for _, task := range []*models.Task{event.Task, previous} {
+		if task == nil {
+			continue
+		}
+		recipients = append(recipients, task.Audience()...)
+		if task.AssignedTo != nil {
+			channels = append(channels, assignmentsChannel(*task.AssignedTo))
+		}
+	}
+	return h.publish(event.ID, recipients, channels, event)
 }
 
-// ReadPump pumps messages from the WebSocket connection to the hub
+// SinkName identifies the hub among the outbox sinks
+func (h *Hub) SinkName() string {
+	return "realtime"
+}
+
+// HandleTaskEvent broadcasts an event published from the outbox
+func (h *Hub) HandleTaskEvent(event *models.OutboxEvent) error {
+	return h.BroadcastTaskEvent(event.TaskEvent(), event.Previous)
+}
+
+// SendNotification delivers a new notification to its recipient's clients as
+// a notification event. Clients that subscribed to channels receive it if they
+// follow its task or their assignments. Notification events have no ID and are
+// not replayed on reconnect; clients reload missed notifications from the API.
+func (h *Hub) SendNotification(notification *models.Notification) error {
+	event := models.TaskEvent{Type: models.TaskEventNotification, Notification: notification}
+	channels := []string{assignmentsChannel(notification.UserID)}
+	if notification.TaskID != nil {
+		event.TaskID = *notification.TaskID
+		channels = append(channels, TaskChannel(*notification.TaskID))
+	}
+	if notification.ActorID != nil {
+		event.UserID = *notification.ActorID
+	}
+	return h.publish(0, []uuid.UUID{notification.UserID}, channels, event)
+}
+
+// publish sends an event with the given sequence number for the given users
+// through the bus. Duplicate and nil user IDs are ignored.
+func (h *Hub) publish(sequence uint64, userIDs []uuid.UUID, channels []string, event models.TaskEvent) error {
+	seen := make(map[uuid.UUID]bool, len(userIDs))
+	unique := make([]uuid.UUID, 0, len(userIDs))
+	for _, id := range userIDs {
+		if id == uuid.Nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+	envelope := eventbus.Envelope{Sequence: sequence, Recipients: unique, Channels: channels, Event: event}
+	return h.bus.Publish(context.Background(), envelope)
+}
+
+// ReadPump reads protocol messages from the WebSocket connection. The read
+// deadline is extended on every pong, so a peer that stops answering pings is
+// dropped after PongTimeout.
 func (c *Client) ReadPump() {
 	defer func() {
 		c.Hub.Unregister <- c
 		c.Conn.Close()
 	}()
 
+	cfg := c.Hub.config
+	if cfg.MaxMessageBytes > 0 {
+		c.Conn.SetReadLimit(cfg.MaxMessageBytes)
+	}
+	if cfg.PongTimeout > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(cfg.PongTimeout))
+		c.Conn.SetPongHandler(func(string) error {
+			return c.Conn.SetReadDeadline(time.Now().Add(cfg.PongTimeout))
+		})
+	}
+
 	for {
-		_, _, err := c.Conn.ReadMessage()
+		_, data, err := c.Conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error: %v", err)
 			}
 			break
 		}
+		c.HandleMessage(data)
 	}
 }
 
-// WritePump pumps messages from the hub to the WebSocket connection
+// WritePump writes queued messages to the WebSocket connection and pings the
+// peer periodically. When the hub closes the client, the pending messages are
+// written and followed by a close frame with the reason. Closing the
+// connection on exit ends ReadPump, which unregisters the client.
 func (c *Client) WritePump() {
-	defer func() {
-		c.Conn.Close()
-	}()
+	cfg := c.Hub.config
+	var ping <-chan time.Time
+	if cfg.PingInterval > 0 {
+		ticker := time.NewTicker(cfg.PingInterval)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+	defer c.Conn.Close()
 
-	for message := range c.Send {
-		if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
-			log.Printf("Error writing message: %v", err)
+	for {
+		message, closed := c.queue.pop()
+		if message != nil {
+			c.setWriteDeadline()
+			if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				log.Printf("Error writing message: %v", err)
+				return
+			}
+			continue
+		}
+		if closed {
+			c.setWriteDeadline()
+			c.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(c.queue.reason()))
 			return
 		}
+
+		select {
+		case <-c.queue.ready:
+
+		case <-ping:
+			c.setWriteDeadline()
+			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// setWriteDeadline bounds the next write by WriteTimeout
+func (c *Client) setWriteDeadline() {
+	if c.Hub.config.WriteTimeout > 0 {
+		c.Conn.SetWriteDeadline(time.Now().Add(c.Hub.config.WriteTimeout))
 	}
 }
@@ -0,0 +1,105 @@
+package websocket
+
+import "sync"
+
+// outboxItem is a message waiting to be written to a client
+type outboxItem struct {
+	// key identifies messages that a newer one with the same key makes
+	// redundant, such as two "updated" events for one task. Empty for
+	// messages that must all be delivered.
+	key     string
+	message []byte
+}
+
+// outbox is a client's bounded queue of pending messages. The hub pushes
+// without blocking; the client's writer pops them. Once closed, it keeps the
+// reason to send to the peer.
+type outbox struct {
+	mu          sync.Mutex
+	items       []outboxItem
+	limit       int
+	ready       chan struct{} // signalled when items are added or the outbox closes
+	closed      bool
+	closeCode   int
+	closeReason string
+}
+
+func newOutbox(limit int) *outbox {
+	return &outbox{limit: limit, ready: make(chan struct{}, 1)}
+}
+
+// push queues a message. A pending message with the same key is dropped and
+// the new one appended, so the client still sees updates in order. coalesced
+// reports whether a message was dropped; ok is false when the queue is full.
+func (o *outbox) push(item outboxItem) (coalesced, ok bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return false, true
+	}
+
+	if item.key != "" {
+		for i, pending := range o.items {
+			if pending.key == item.key {
+				o.items = append(o.items[:i], o.items[i+1:]...)
+				coalesced = true
+				break
+			}
+		}
+	}
+
+	if len(o.items) >= o.limit {
+		return coalesced, false
+	}
+	o.items = append(o.items, item)
+	o.signal()
+	return coalesced, true
+}
+
+// space returns how many more messages fit
+func (o *outbox) space() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.limit - len(o.items)
+}
+
+// close stops accepting messages. Pending messages are still written, then
+// the writer closes the connection with the given code and reason.
+func (o *outbox) close(code int, reason string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return
+	}
+	o.closed = true
+	o.closeCode = code
+	o.closeReason = reason
+	o.signal()
+}
+
+// pop removes and returns the oldest pending message, or nil when there is
+// none. closed reports whether the outbox is closed.
+func (o *outbox) pop() (message []byte, closed bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.items) > 0 {
+		message = o.items[0].message
+		o.items = o.items[1:]
+	}
+	return message, o.closed
+}
+
+// reason returns the close code and reason
+func (o *outbox) reason() (int, string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.closeCode, o.closeReason
+}
+
+// signal wakes the writer. The caller must hold the lock.
+func (o *outbox) signal() {
+	select {
+	case o.ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,205 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// ActionView reports the task the client is showing. An empty channel means
+// the client stopped viewing any task.
+const ActionView = "view"
+
+// Presence message types
+const (
+	MessageTypeUserOnline  = "user_online"
+	MessageTypeUserOffline = "user_offline"
+	MessageTypeTaskViewing = "task_viewing"
+	MessageTypeTaskLeft    = "task_left"
+)
+
+// PresenceEvent announces that a user came online or went offline, sent to
+// clients subscribed to the presence channel whose user shares a task with
+// them, or started or stopped viewing a task, sent to the other viewers of the
+// task. Presence events are not replayed on reconnect.
+type PresenceEvent struct {
+	Type   string     `json:"type"`
+	UserID uuid.UUID  `json:"user_id"`
+	TaskID *uuid.UUID `json:"task_id,omitempty"`
+}
+
+// PresenceSnapshot lists who is online and which tasks they are viewing.
+// Partial is set when other instances share the event bus: their clients are
+// not counted.
+type PresenceSnapshot struct {
+	Online  []uuid.UUID               `json:"online"`
+	Viewers map[uuid.UUID][]uuid.UUID `json:"viewers"` // task ID -> users viewing it
+	Partial bool                      `json:"partial"`
+}
+
+// SetViewing records the task a client is viewing, or nil when it viewed none
+func (h *Hub) SetViewing(client *Client, taskID *uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.Clients[client.ID]; ok {
+		h.setViewing(client, taskID)
+	}
+}
+
+// Presence returns which of the users sharing a task with the user are
+// online, the user included, and, for the tasks the user may see, who is
+// viewing them. Only clients of this instance are counted.
+func (h *Hub) Presence(userID uuid.UUID) PresenceSnapshot {
+	visible := map[uuid.UUID]bool{userID: true}
+	if h.authorizer != nil {
+		collaborators, err := h.authorizer.Collaborators(userID)
+		if err != nil {
+			log.Printf("Error loading collaborators of user %s: %v", userID, err)
+		}
+		for _, id := range collaborators {
+			visible[id] = true
+		}
+	}
+
+	h.mu.RLock()
+	snapshot := PresenceSnapshot{
+		Online:  make([]uuid.UUID, 0, len(visible)),
+		Viewers: make(map[uuid.UUID][]uuid.UUID, len(h.viewers)),
+		Partial: h.sharedBus,
+	}
+	for id := range h.users {
+		if visible[id] {
+			snapshot.Online = append(snapshot.Online, id)
+		}
+	}
+	for taskID, viewers := range h.viewers {
+		for id := range viewers {
+			snapshot.Viewers[taskID] = append(snapshot.Viewers[taskID], id)
+		}
+	}
+	h.mu.RUnlock()
+
+	sortUUIDs(snapshot.Online)
+	for taskID, viewers := range snapshot.Viewers {
+		allowed := false
+		if h.authorizer != nil {
+			allowed, _ = h.authorizer.CanViewTask(userID, taskID)
+		}
+		if !allowed {
+			delete(snapshot.Viewers, taskID)
+			continue
+		}
+		sortUUIDs(viewers)
+	}
+	return snapshot
+}
+
+// IsOnline reports whether the user has a client connected to this instance.
+// With several instances, a user connected only to another one counts as
+// offline here; services.SharedPresence covers every instance.
+func (h *Hub) IsOnline(userID uuid.UUID) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.users[userID]) > 0
+}
+
+// OnlineUsers lists the users with a client connected to this instance
+func (h *Hub) OnlineUsers() []uuid.UUID {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	users := make([]uuid.UUID, 0, len(h.users))
+	for userID, clients := range h.users {
+		if len(clients) > 0 {
+			users = append(users, userID)
+		}
+	}
+	return users
+}
+
+// userJoined announces a user's first connection. The caller must hold the
+// write lock.
+func (h *Hub) userJoined(client *Client) {
+	if len(h.users[client.UserID]) == 1 {
+		h.notifyAll(PresenceEvent{Type: MessageTypeUserOnline, UserID: client.UserID})
+	}
+}
+
+// userLeft clears a removed client's presence and announces when the user has
+// no connections left. The caller must hold the write lock.
+func (h *Hub) userLeft(client *Client) {
+	h.setViewing(client, nil)
+	if len(h.users[client.UserID]) == 0 {
+		h.notifyAll(PresenceEvent{Type: MessageTypeUserOffline, UserID: client.UserID})
+	}
+}
+
+// setViewing moves a client to another task and tells the viewers of both
+// tasks when the user joins or leaves them. The caller must hold the write
+// lock.
+func (h *Hub) setViewing(client *Client, taskID *uuid.UUID) {
+	if client.viewing == taskID || (client.viewing != nil && taskID != nil && *client.viewing == *taskID) {
+		return
+	}
+
+	if previous := client.viewing; previous != nil {
+		client.viewing = nil
+		viewers := h.viewers[*previous]
+		viewers[client.UserID]--
+		if viewers[client.UserID] <= 0 {
+			delete(viewers, client.UserID)
+			h.notifyViewers(*previous, PresenceEvent{Type: MessageTypeTaskLeft, UserID: client.UserID, TaskID: previous})
+		}
+		if len(viewers) == 0 {
+			delete(h.viewers, *previous)
+		}
+	}
+
+	if taskID != nil {
+		id := *taskID
+		client.viewing = &id
+		if h.viewers[id] == nil {
+			h.viewers[id] = make(map[uuid.UUID]int)
+		}
+		h.viewers[id][client.UserID]++
+		if h.viewers[id][client.UserID] == 1 {
+			h.notifyViewers(id, PresenceEvent{Type: MessageTypeTaskViewing, UserID: client.UserID, TaskID: &id})
+		}
+	}
+}
+
+// notifyAll sends a presence event to every client subscribed to presence
+func (h *Hub) notifyAll(event PresenceEvent) {
+	message, _ := json.Marshal(event)
+	for _, client := range h.Clients {
+		if client.subscriptions.followsPresence(event.UserID) {
+			h.trySend(client, message)
+		}
+	}
+}
+
+// notifyViewers sends a presence event to the clients viewing a task
+func (h *Hub) notifyViewers(taskID uuid.UUID, event PresenceEvent) {
+	message, _ := json.Marshal(event)
+	for userID := range h.viewers[taskID] {
+		for _, client := range h.users[userID] {
+			if client.viewing != nil && *client.viewing == taskID {
+				h.trySend(client, message)
+			}
+		}
+	}
+}
+
+// trySend queues a message unless the client's queue is full. Presence is
+// best effort, so slow clients miss it rather than being dropped here; the
+// miss is counted in the metrics.
+func (h *Hub) trySend(client *Client, message []byte) {
+	if _, ok := client.queue.push(outboxItem{message: message}); !ok {
+		h.presenceDropped.Add(1)
+	}
+}
+
+func sortUUIDs(ids []uuid.UUID) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+}
@@ -0,0 +1,248 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Inbound actions
+const (
+	ActionSubscribe   = "subscribe"
+	ActionUnsubscribe = "unsubscribe"
+)
+
+// Outbound message types, alongside task events
+const (
+	MessageTypeAck   = "ack"
+	MessageTypeError = "error"
+)
+
+// Channels a client can subscribe to. Task channels are written as
+// "task:<task id>".
+const (
+	ChannelAssignments   = "assignments"
+	ChannelPresence      = "presence" // online/offline announcements of users sharing a task; does not filter task events
+	channelTaskPrefix    = "task:"
+	channelProjectPrefix = "project:"
+)
+
+// ErrForbiddenChannel is returned when subscribing to a channel the user may
+// not see
+var ErrForbiddenChannel = errors.New("forbidden")
+
+// TaskAuthorizer decides whether a user may follow a task, and whose presence
+// they may see: the users they share a task with
+type TaskAuthorizer interface {
+	CanViewTask(userID, taskID uuid.UUID) (bool, error)
+	Collaborators(userID uuid.UUID) ([]uuid.UUID, error)
+}
+
+// ClientMessage is a message sent by the client
+type ClientMessage struct {
+	ID      string `json:"id,omitempty"` // echoed back in the ack or error
+	Action  string `json:"action"`
+	Channel string `json:"channel"`
+}
+
+// ServerReply acknowledges or rejects a client message
+type ServerReply struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	Action  string `json:"action,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// subscriptions is the set of channels a client follows. A client without
+// task subscriptions receives every task event it is allowed to see.
+type subscriptions struct {
+	mu       sync.RWMutex
+	channels map[string]bool
+	presence map[uuid.UUID]bool // users whose presence is followed; nil when not subscribed
+}
+
+func (s *subscriptions) add(channel string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.channels == nil {
+		s.channels = make(map[string]bool)
+	}
+	s.channels[channel] = true
+}
+
+func (s *subscriptions) remove(channel string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if channel == ChannelPresence {
+		s.presence = nil
+		return
+	}
+	delete(s.channels, channel)
+}
+
+// followPresence subscribes to the online/offline events of the given users
+func (s *subscriptions) followPresence(users map[uuid.UUID]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.presence = users
+}
+
+// followsPresence reports whether the client wants the online/offline events
+// of a user
+func (s *subscriptions) followsPresence(userID uuid.UUID) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.presence[userID]
+}
+
+// matches reports whether a message published on the given channels should
+// reach the client
+func (s *subscriptions) matches(channels []string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.channels) == 0 {
+		return true
+	}
+	for _, channel := range channels {
+		if s.channels[channel] {
+			return true
+		}
+	}
+	return false
+}
+
+// TaskChannel returns the channel name of a task
+func TaskChannel(taskID uuid.UUID) string {
+	return channelTaskPrefix + taskID.String()
+}
+
+// assignmentsChannel is the internal, per-user name of ChannelAssignments
+func assignmentsChannel(userID uuid.UUID) string {
+	return ChannelAssignments + ":" + userID.String()
+}
+
+// HandleMessage processes a message received from the client and replies with
+// an ack or an error
+func (c *Client) HandleMessage(data []byte) {
+	var msg ClientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		c.reply(ServerReply{Type: MessageTypeError, Error: "invalid message"})
+		return
+	}
+
+	channel, err := c.resolveChannel(msg)
+	if err != nil {
+		c.reply(ServerReply{Type: MessageTypeError, ID: msg.ID, Action: msg.Action, Channel: msg.Channel, Error: err.Error()})
+		return
+	}
+
+	switch msg.Action {
+	case ActionSubscribe:
+		if err := c.subscribe(channel); err != nil {
+			c.reply(ServerReply{Type: MessageTypeError, ID: msg.ID, Action: msg.Action, Channel: msg.Channel, Error: err.Error()})
+			return
+		}
+	case ActionUnsubscribe:
+		c.subscriptions.remove(channel)
+	case ActionView:
+		var taskID *uuid.UUID
+		if channel != "" {
+			id, _ := uuid.Parse(strings.TrimPrefix(channel, channelTaskPrefix))
+			taskID = &id
+		}
+		c.Hub.SetViewing(c, taskID)
+	}
+	c.reply(ServerReply{Type: MessageTypeAck, ID: msg.ID, Action: msg.Action, Channel: msg.Channel})
+}
+
+// Subscribe makes the client follow a channel, with the same validation and
+// authorization as a subscribe message. Used by transports without inbound
+// messages, such as Server-Sent Events.
+func (c *Client) Subscribe(channel string) error {
+	resolved, err := c.resolveChannel(ClientMessage{Action: ActionSubscribe, Channel: channel})
+	if err != nil {
+		return err
+	}
+	return c.subscribe(resolved)
+}
+
+// subscribe follows a resolved channel. Following presence loads the users
+// who share a task with the client's user; the list is kept until the client
+// subscribes again.
+func (c *Client) subscribe(channel string) error {
+	if channel != ChannelPresence {
+		c.subscriptions.add(channel)
+		return nil
+	}
+
+	users := make(map[uuid.UUID]bool)
+	if c.Hub.authorizer != nil {
+		collaborators, err := c.Hub.authorizer.Collaborators(c.UserID)
+		if err != nil {
+			return errors.New("could not load presence")
+		}
+		for _, id := range collaborators {
+			users[id] = true
+		}
+	}
+	c.subscriptions.followPresence(users)
+	return nil
+}
+
+// resolveChannel validates a message and returns the internal channel name.
+// Subscribing to or viewing a task requires permission to view it.
+func (c *Client) resolveChannel(msg ClientMessage) (string, error) {
+	switch msg.Action {
+	case ActionSubscribe, ActionUnsubscribe:
+	case ActionView:
+		if msg.Channel == "" {
+			return "", nil
+		}
+		if !strings.HasPrefix(msg.Channel, channelTaskPrefix) {
+			return "", errors.New("only task channels can be viewed")
+		}
+	default:
+		return "", errors.New("unknown action")
+	}
+
+	switch {
+	case msg.Channel == ChannelAssignments:
+		return assignmentsChannel(c.UserID), nil
+
+	case msg.Channel == ChannelPresence:
+		return ChannelPresence, nil
+
+	case strings.HasPrefix(msg.Channel, channelTaskPrefix):
+		taskID, err := uuid.Parse(strings.TrimPrefix(msg.Channel, channelTaskPrefix))
+		if err != nil {
+			return "", errors.New("invalid task ID")
+		}
+		if msg.Action != ActionUnsubscribe {
+			if c.Hub.authorizer == nil {
+				return "", ErrForbiddenChannel
+			}
+			allowed, err := c.Hub.authorizer.CanViewTask(c.UserID, taskID)
+			if err != nil || !allowed {
+				return "", ErrForbiddenChannel
+			}
+		}
+		return TaskChannel(taskID), nil
+
+	case strings.HasPrefix(msg.Channel, channelProjectPrefix):
+		return "", errors.New("project channels are not supported")
+	}
+	return "", errors.New("unknown channel")
+}
+
+// reply queues a reply for this client only
+func (c *Client) reply(reply ServerReply) {
+	message, err := json.Marshal(reply)
+	if err != nil {
+		return
+	}
+	c.Hub.direct <- directMessage{client: c, message: message}
+}
@@ -0,0 +1,75 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ServeSSE writes the messages queued for the client as Server-Sent Events
+// until ctx is done or the hub drops the client. A client dropped by the hub
+// gets a final closed message with the reason. The client must already be
+// registered. Task events carry their ID in the id field so that browsers
+// resume with a Last-Event-ID header. Comments are sent every PingInterval to
+// keep proxies from closing an idle stream.
+func (c *Client) ServeSSE(ctx context.Context, w io.Writer, flush func()) {
+	var ping <-chan time.Time
+	if c.Hub.config.PingInterval > 0 {
+		ticker := time.NewTicker(c.Hub.config.PingInterval)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+
+	for {
+		message, closed := c.queue.pop()
+		if message != nil {
+			if err := writeSSE(w, message); err != nil {
+				return
+			}
+			flush()
+			continue
+		}
+		if closed {
+			if _, reason := c.queue.reason(); reason != "" {
+				message, _ := json.Marshal(ServerReply{Type: MessageTypeClosed, Error: reason})
+				writeSSE(w, message)
+				flush()
+			}
+			return
+		}
+
+		select {
+		case <-c.queue.ready:
+
+		case <-ping:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flush()
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// MessageTypeClosed ends an event stream the hub closed. Its error field holds
+// the reason; WebSocket clients get it in the close frame instead.
+const MessageTypeClosed = "closed"
+
+// writeSSE writes one JSON message as an event. Messages are single-line JSON,
+// so one data field is enough.
+func writeSSE(w io.Writer, message []byte) error {
+	var envelope struct {
+		ID uint64 `json:"id"`
+	}
+	if json.Unmarshal(message, &envelope) == nil && envelope.ID != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", envelope.ID); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "data: %s\n\n", message)
+	return err
+}
@@ -0,0 +1,226 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/middleware"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAccountRepository is an in-memory AccountRepository
+type fakeAccountRepository struct {
+	created    []models.Task
+	assigned   []models.Task
+	webhooks   []models.Webhook
+	devices    []models.DeviceToken
+	deleted    *models.User
+	reassignTo *uuid.UUID
+}
+
+func (r *fakeAccountRepository) ListCreatedTasks(userID uuid.UUID) ([]models.Task, error) {
+	return r.created, nil
+}
+
+func (r *fakeAccountRepository) ListAssignedTasks(userID uuid.UUID) ([]models.Task, error) {
+	return r.assigned, nil
+}
+
+func (r *fakeAccountRepository) ListIdentities(userID uuid.UUID) ([]models.UserIdentity, error) {
+	return nil, nil
+}
+
+func (r *fakeAccountRepository) ListWebhooks(userID uuid.UUID) ([]models.Webhook, error) {
+	return r.webhooks, nil
+}
+
+func (r *fakeAccountRepository) ListNotifications(userID uuid.UUID) ([]models.Notification, error) {
+	return nil, nil
+}
+
+func (r *fakeAccountRepository) ListSentInvitations(userID uuid.UUID) ([]models.Invitation, error) {
+	return nil, nil
+}
+
+func (r *fakeAccountRepository) FindNotificationPreferences(userID uuid.UUID) (*models.NotificationPreferences, error) {
+	return nil, nil
+}
+
+func (r *fakeAccountRepository) ListDevices(userID uuid.UUID) ([]models.DeviceToken, error) {
+	return r.devices, nil
+}
+
+func (r *fakeAccountRepository) DeleteAccount(user *models.User, reassignTo *uuid.UUID) error {
+	r.deleted = user
+	r.reassignTo = reassignTo
+	return nil
+}
+
+func TestLogin_DeactivatedUserRejected(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newTwoFactorAuthService(mockRepo)
+
+	deactivatedAt := time.Now()
+	user := &models.User{ID: uuid.New(), Email: "gone@example.com", Password: "password123", DeactivatedAt: &deactivatedAt}
+	user.HashPassword()
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil)
+
+	_, err := service.Login(services.LoginRequest{Email: user.Email, Password: "password123"})
+
+	assert.ErrorIs(t, err, services.ErrAccountDeactivated)
+}
+
+func TestRefreshToken_DeactivatedUserRejected(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newTwoFactorAuthService(mockRepo)
+
+	user := &models.User{ID: uuid.New(), Email: "user@example.com", Password: "password123"}
+	user.HashPassword()
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil)
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+
+	response, err := service.Login(services.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+
+	_, err = service.RefreshToken(response.RefreshToken)
+	require.NoError(t, err)
+
+	deactivatedAt := time.Now()
+	user.DeactivatedAt = &deactivatedAt
+
+	_, err = service.RefreshToken(response.RefreshToken)
+	assert.ErrorIs(t, err, services.ErrAccountDeactivated)
+}
+
+func TestDeleteAccount_AnonymizesUser(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	accountRepo := &fakeAccountRepository{}
+	service := services.NewAccountService(accountRepo, mockRepo, new(MockPersonalAccessTokenRepository), nil)
+
+	user := newProfileUser()
+	user.TOTPEnabled = true
+	user.TOTPSecret = "SECRET"
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+
+	err := service.DeleteAccount(context.Background(), user.ID, services.DeleteAccountRequest{
+		Password: "password123",
+		Tasks:    services.TaskHandlingAnonymize,
+	})
+
+	require.NoError(t, err)
+	require.NotNil(t, accountRepo.deleted)
+	assert.Nil(t, accountRepo.reassignTo)
+	assert.Equal(t, "Deleted user", accountRepo.deleted.Name)
+	assert.True(t, strings.HasSuffix(accountRepo.deleted.Email, "@deleted.invalid"))
+	assert.Empty(t, accountRepo.deleted.TOTPSecret)
+	assert.False(t, accountRepo.deleted.IsActive())
+	assert.False(t, accountRepo.deleted.CheckPassword("password123"))
+}
+
+func TestDeleteAccount_ReassignRequiresActiveTarget(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	accountRepo := &fakeAccountRepository{}
+	service := services.NewAccountService(accountRepo, mockRepo, new(MockPersonalAccessTokenRepository), nil)
+
+	user := newProfileUser()
+	deactivatedAt := time.Now()
+	inactive := &models.User{ID: uuid.New(), DeactivatedAt: &deactivatedAt}
+	active := &models.User{ID: uuid.New()}
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	mockRepo.On("FindByID", inactive.ID).Return(inactive, nil)
+	mockRepo.On("FindByID", active.ID).Return(active, nil)
+
+	err := service.DeleteAccount(context.Background(), user.ID, services.DeleteAccountRequest{
+		Password:   "password123",
+		Tasks:      services.TaskHandlingReassign,
+		ReassignTo: &inactive.ID,
+	})
+	assert.Error(t, err)
+	assert.Nil(t, accountRepo.deleted)
+
+	err = service.DeleteAccount(context.Background(), user.ID, services.DeleteAccountRequest{
+		Password:   "password123",
+		Tasks:      services.TaskHandlingReassign,
+		ReassignTo: &active.ID,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, accountRepo.reassignTo)
+	assert.Equal(t, active.ID, *accountRepo.reassignTo)
+}
+
+func TestExport_IncludesCreatedData(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	tokenRepo := new(MockPersonalAccessTokenRepository)
+	user := newProfileUser()
+	accountRepo := &fakeAccountRepository{
+		created:  []models.Task{{ID: uuid.New(), Title: "Mine", CreatedBy: user.ID}},
+		webhooks: []models.Webhook{{ID: uuid.New(), UserID: user.ID, URL: "https://example.com/hook", Secret: "whsec_secret"}},
+		devices:  []models.DeviceToken{{ID: uuid.New(), UserID: user.ID, Token: "ExponentPushToken[phone]"}},
+	}
+	service := services.NewAccountService(accountRepo, mockRepo, tokenRepo, nil)
+
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	tokenRepo.On("ListByUser", user.ID).Return([]models.PersonalAccessToken{}, nil)
+
+	export, err := service.Export(user.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, user.Email, export.User.Email)
+	assert.Len(t, export.CreatedTasks, 1)
+	assert.NotNil(t, export.AssignedTasks)
+	assert.NotNil(t, export.Identities)
+	assert.NotNil(t, export.Notifications)
+	assert.NotNil(t, export.SentInvitations)
+	assert.Len(t, export.Devices, 1)
+	assert.True(t, export.NotificationPrefs.Assignments, "defaults when never saved")
+
+	body, err := json.Marshal(export)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "https://example.com/hook")
+	assert.NotContains(t, string(body), "whsec_secret", "webhook secrets are not exported")
+}
+
+func TestRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockRepo := new(MockUserRepository)
+	adminService := services.NewAdminService(mockRepo, new(MockPersonalAccessTokenRepository), newFakeWebhookRepository(), nil, nil)
+
+	admin := &models.User{ID: uuid.New(), IsAdmin: true}
+	member := &models.User{ID: uuid.New()}
+	mockRepo.On("FindByID", admin.ID).Return(admin, nil)
+	mockRepo.On("FindByID", member.ID).Return(member, nil)
+
+	request := func(userID uuid.UUID) int {
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set("user_id", userID) })
+		router.GET("/admin", middleware.RequireAdmin(adminService), func(c *gin.Context) { c.Status(http.StatusOK) })
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request(admin.ID))
+	assert.Equal(t, http.StatusForbidden, request(member.ID))
+}
+
+func TestDeactivateUser_CannotDeactivateSelf(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	adminService := services.NewAdminService(mockRepo, new(MockPersonalAccessTokenRepository), newFakeWebhookRepository(), nil, nil)
+	adminID := uuid.New()
+
+	_, err := adminService.DeactivateUser(adminID, adminID)
+
+	assert.Error(t, err)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/middleware"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStatsRepository returns fixed counts
+type fakeStatsRepository struct{}
+
+func (r *fakeStatsRepository) UserCounts() (models.UserCounts, error) {
+	return models.UserCounts{Total: 3, Active: 2, Deactivated: 1, Admins: 1}, nil
+}
+
+func (r *fakeStatsRepository) TaskCountsByStatus() (map[models.TaskStatus]int64, error) {
+	return map[models.TaskStatus]int64{models.TaskStatusPending: 4, models.TaskStatusCompleted: 2}, nil
+}
+
+// fakeConnections records disconnected users
+type fakeConnections struct {
+	disconnected []uuid.UUID
+}
+
+func (f *fakeConnections) Metrics() models.RealtimeMetrics {
+	return models.RealtimeMetrics{Clients: 5, SlowClientsDropped: 2}
+}
+
+func (f *fakeConnections) DisconnectUser(userID uuid.UUID) int {
+	f.disconnected = append(f.disconnected, userID)
+	return 1
+}
+
+func TestForceLogout_RevokesExistingSessions(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	tokenRepo := new(MockPersonalAccessTokenRepository)
+	connections := &fakeConnections{}
+	authService := newTwoFactorAuthService(mockRepo)
+	adminService := services.NewAdminService(mockRepo, tokenRepo, newFakeWebhookRepository(), &fakeStatsRepository{}, connections)
+
+	user := &models.User{ID: uuid.New(), Email: "user@example.com"}
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	mockRepo.On("Update", user).Return(nil)
+	tokenRepo.On("DeleteByUser", user.ID).Return(nil)
+
+	issuedAt := time.Now()
+	require.NoError(t, authService.ValidateSession(user.ID, issuedAt))
+
+	require.NoError(t, adminService.ForceLogout(user.ID))
+
+	assert.Error(t, authService.ValidateSession(user.ID, issuedAt))
+	assert.NoError(t, authService.ValidateSession(user.ID, time.Now().Add(2*time.Second)))
+	assert.Equal(t, []uuid.UUID{user.ID}, connections.disconnected)
+	tokenRepo.AssertExpectations(t)
+}
+
+func TestDeactivateUser_DisablesWebhooks(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	webhooks := newFakeWebhookRepository()
+	adminService := services.NewAdminService(mockRepo, new(MockPersonalAccessTokenRepository), webhooks, &fakeStatsRepository{}, nil)
+
+	user := &models.User{ID: uuid.New(), Email: "user@example.com"}
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	mockRepo.On("Update", user).Return(nil)
+	require.NoError(t, webhooks.Create(&models.Webhook{UserID: user.ID, URL: "https://example.com/hook", Active: true}))
+
+	_, err := adminService.DeactivateUser(uuid.New(), user.ID)
+	require.NoError(t, err)
+	active, err := webhooks.ListActiveByUsers([]uuid.UUID{user.ID})
+	require.NoError(t, err)
+	assert.Empty(t, active, "a deactivated user's webhooks stop receiving events")
+
+	_, err = adminService.ReactivateUser(user.ID)
+	require.NoError(t, err)
+	active, err = webhooks.ListActiveByUsers([]uuid.UUID{user.ID})
+	require.NoError(t, err)
+	assert.Len(t, active, 1)
+}
+
+func TestResetPassword_ReturnsWorkingTemporaryPassword(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	tokenRepo := new(MockPersonalAccessTokenRepository)
+	adminService := services.NewAdminService(mockRepo, tokenRepo, newFakeWebhookRepository(), &fakeStatsRepository{}, nil)
+
+	user := newProfileUser()
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	mockRepo.On("Update", user).Return(nil)
+	tokenRepo.On("DeleteByUser", user.ID).Return(nil)
+
+	response, err := adminService.ResetPassword(user.ID)
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.TemporaryPassword)
+	assert.True(t, user.CheckPassword(response.TemporaryPassword))
+	assert.False(t, user.CheckPassword("password123"))
+	assert.NotNil(t, user.TokensValidAfter)
+	assert.True(t, user.MustChangePassword)
+
+	// The temporary password only signs in together with a new password
+	authService := newTwoFactorAuthService(mockRepo)
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil)
+	login := services.LoginRequest{Email: user.Email, Password: response.TemporaryPassword}
+	_, err = authService.Login(login)
+	assert.ErrorIs(t, err, services.ErrPasswordChangeRequired)
+
+	login.NewPassword = "chosen-password"
+	tokens, err := authService.Login(login)
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.Token)
+	assert.False(t, user.MustChangePassword)
+	assert.True(t, user.CheckPassword("chosen-password"))
+
+	_, err = authService.Login(services.LoginRequest{Email: user.Email, Password: response.TemporaryPassword})
+	assert.Error(t, err, "the temporary password no longer works")
+}
+
+// fakeAdminChecker reports every user as an admin
+type fakeAdminChecker struct{}
+
+func (fakeAdminChecker) IsAdmin(userID uuid.UUID) (bool, error) {
+	return true, nil
+}
+
+func TestRequireAdmin_RejectsPersonalAccessTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	request := func(viaToken bool) int {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("user_id", uuid.New())
+			if viaToken {
+				c.Set("token_id", uuid.New())
+			}
+		})
+		router.GET("/admin/stats", middleware.RequireAdmin(fakeAdminChecker{}), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/stats", nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request(false))
+	assert.Equal(t, http.StatusForbidden, request(true))
+}
+
+func TestAdminStats(t *testing.T) {
+	adminService := services.NewAdminService(new(MockUserRepository), new(MockPersonalAccessTokenRepository), newFakeWebhookRepository(), &fakeStatsRepository{}, &fakeConnections{})
+
+	stats, err := adminService.Stats()
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Users.Total)
+	assert.Equal(t, int64(6), stats.TasksTotal)
+	assert.Equal(t, int64(0), stats.TasksByStatus[models.TaskStatusInProgress])
+	assert.Equal(t, 5, stats.WebSocketClients)
+	require.NotNil(t, stats.Realtime)
+	assert.Equal(t, uint64(2), stats.Realtime.SlowClientsDropped)
+}
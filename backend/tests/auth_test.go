@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/jwtkeys"
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
 	"github.com/google/uuid"
@@ -37,14 +38,27 @@ func (m *MockUserRepository) FindByID(id uuid.UUID) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) Update(user *models.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) FindByEmailVerificationToken(hash string) (*models.User, error) {
+	args := m.Called(hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 func (m *MockUserRepository) EmailExists(email string) (bool, error) {
 	args := m.Called(email)
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockUserRepository) List() ([]models.User, error) {
-	args := m.Called()
-	return args.Get(0).([]models.User), args.Error(1)
+func (m *MockUserRepository) List(filter models.UserFilter) ([]models.User, int64, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]models.User), args.Get(1).(int64), args.Error(2)
 }
 
 func TestRegister_Success(t *testing.T) {
@@ -56,7 +70,7 @@ func TestRegister_Success(t *testing.T) {
 			RefreshExpirationHours: 168,
 		},
 	}
-	service := services.NewAuthService(mockRepo, cfg)
+	service := services.NewAuthService(mockRepo, newFakeTwoFactorChallengeRepository(), jwtkeys.NewHMAC(cfg.JWT.Secret), nil, nil, cfg)
 
 	req := services.RegisterRequest{
 		Email:    "test@example.com",
@@ -84,7 +98,7 @@ func TestRegister_EmailExists(t *testing.T) {
 			Secret: "test-secret",
 		},
 	}
-	service := services.NewAuthService(mockRepo, cfg)
+	service := services.NewAuthService(mockRepo, newFakeTwoFactorChallengeRepository(), jwtkeys.NewHMAC(cfg.JWT.Secret), nil, nil, cfg)
 
 	req := services.RegisterRequest{
 		Email:    "existing@example.com",
@@ -111,7 +125,7 @@ func TestLogin_Success(t *testing.T) {
 			RefreshExpirationHours: 168,
 		},
 	}
-	service := services.NewAuthService(mockRepo, cfg)
+	service := services.NewAuthService(mockRepo, newFakeTwoFactorChallengeRepository(), jwtkeys.NewHMAC(cfg.JWT.Secret), nil, nil, cfg)
 
 	user := &models.User{
 		ID:    uuid.New(),
@@ -144,7 +158,7 @@ func TestLogin_InvalidPassword(t *testing.T) {
 			Secret: "test-secret",
 		},
 	}
-	service := services.NewAuthService(mockRepo, cfg)
+	service := services.NewAuthService(mockRepo, newFakeTwoFactorChallengeRepository(), jwtkeys.NewHMAC(cfg.JWT.Secret), nil, nil, cfg)
 
 	user := &models.User{
 		ID:    uuid.New(),
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/jwtkeys"
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
 	"github.com/stretchr/testify/assert"
 )
@@ -15,7 +16,7 @@ func TestRegister_WeakPassword_ShouldFail(t *testing.T) {
 			Secret: "test-secret",
 		},
 	}
-	service := services.NewAuthService(mockRepo, cfg)
+	service := services.NewAuthService(mockRepo, newFakeTwoFactorChallengeRepository(), jwtkeys.NewHMAC(cfg.JWT.Secret), nil, nil, cfg)
 
 	req := services.RegisterRequest{
 		Email:    "test@example.com",
@@ -39,7 +40,7 @@ func TestRegister_EmptyEmail_ShouldFail(t *testing.T) {
 			Secret: "test-secret",
 		},
 	}
-	service := services.NewAuthService(mockRepo, cfg)
+	service := services.NewAuthService(mockRepo, newFakeTwoFactorChallengeRepository(), jwtkeys.NewHMAC(cfg.JWT.Secret), nil, nil, cfg)
 
 	req := services.RegisterRequest{
 		Email:    "",
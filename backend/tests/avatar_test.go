@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/handlers"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/imaging"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newAvatarService(t *testing.T, repo *MockUserRepository, maxBytes int) (*services.UserService, *storage.LocalStorage, string) {
+	dir := t.TempDir()
+	files, err := storage.NewLocal(dir, "https://api.taskflow.test/uploads")
+	require.NoError(t, err)
+
+	cfg := &config.Config{Storage: config.StorageConfig{AvatarMaxBytes: maxBytes}}
+	return services.NewUserService(repo, &fakeMailer{}, files, cfg), files, dir
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 40, B: 40, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestThumbnail_CropsToSquare(t *testing.T) {
+	// Left third black, the rest white: the centered square crop is all white
+	src := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 300; x++ {
+			if x < 50 {
+				src.Set(x, y, color.Black)
+			} else {
+				src.Set(x, y, color.White)
+			}
+		}
+	}
+
+	thumb := imaging.Thumbnail(src, 64)
+
+	assert.Equal(t, image.Rect(0, 0, 64, 64), thumb.Bounds())
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, thumb.RGBAAt(0, 32))
+}
+
+func TestLocalStorage_RejectsKeysOutsideRoot(t *testing.T) {
+	files, err := storage.NewLocal(t.TempDir(), "http://localhost/uploads")
+	require.NoError(t, err)
+
+	for _, key := range []string{"", "../escape.txt", "/etc/passwd", "a/../../b"} {
+		err := files.Put(context.Background(), key, strings.NewReader("x"), "text/plain")
+		assert.ErrorIs(t, err, storage.ErrInvalidKey, key)
+	}
+}
+
+func TestUpdateAvatar_StoresResizedVersions(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service, files, dir := newAvatarService(t, mockRepo, 1<<20)
+	user := newProfileUser()
+
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	mockRepo.On("Update", user).Return(nil)
+
+	updated, err := service.UpdateAvatar(context.Background(), user.ID, bytes.NewReader(encodePNG(t, 400, 300)))
+	require.NoError(t, err)
+	require.NotEmpty(t, updated.AvatarKey)
+
+	for size, pixels := range models.AvatarSizes {
+		file, err := os.Open(filepath.Join(dir, filepath.FromSlash(models.AvatarObjectKey(updated.AvatarKey, size))))
+		require.NoError(t, err)
+		cfg, err := jpeg.DecodeConfig(file)
+		file.Close()
+		require.NoError(t, err)
+		assert.Equal(t, pixels, cfg.Width)
+		assert.Equal(t, pixels, cfg.Height)
+	}
+
+	avatar := updated.ToResponse(files.URL).Avatar
+	require.NotNil(t, avatar)
+	assert.True(t, strings.HasPrefix(avatar.Small, "https://api.taskflow.test/uploads/avatars/"), avatar.Small)
+
+	// A new upload replaces the previous files
+	previous := updated.AvatarKey
+	updated, err = service.UpdateAvatar(context.Background(), user.ID, bytes.NewReader(encodePNG(t, 50, 50)))
+	require.NoError(t, err)
+	assert.NotEqual(t, previous, updated.AvatarKey)
+	_, err = os.Stat(filepath.Join(dir, filepath.FromSlash(models.AvatarObjectKey(previous, "small"))))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUpdateAvatar_RejectsInvalidUploads(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service, _, _ := newAvatarService(t, mockRepo, 1024)
+	user := newProfileUser()
+
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+
+	_, err := service.UpdateAvatar(context.Background(), user.ID, strings.NewReader("not an image"))
+	assert.ErrorIs(t, err, services.ErrUnsupportedImage)
+
+	_, err = service.UpdateAvatar(context.Background(), user.ID, bytes.NewReader(make([]byte, 2048)))
+	assert.ErrorIs(t, err, services.ErrAvatarTooLarge)
+
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestUploadAvatar_RejectsOversizedRequestBeforeParsing(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service, files, _ := newAvatarService(t, mockRepo, 1024)
+	handler := handlers.NewUserHandler(service, files.URL)
+	userID := uuid.New()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PUT("/me/avatar", func(c *gin.Context) { c.Set("user_id", userID) }, handler.UploadAvatar)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("avatar", "huge.png")
+	require.NoError(t, err)
+	_, err = part.Write(make([]byte, 1<<20))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPut, "/me/avatar", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	mockRepo.AssertNotCalled(t, "FindByID", mock.Anything)
+}
@@ -0,0 +1,261 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/mailer"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDigestRepository serves fixed subscribers and tasks and remembers
+// claimed digests
+type fakeDigestRepository struct {
+	subscribers []models.NotificationPreferences
+	tasks       []*models.Task
+	claimed     map[string]bool
+}
+
+func newFakeDigestRepository() *fakeDigestRepository {
+	return &fakeDigestRepository{claimed: make(map[string]bool)}
+}
+
+func (r *fakeDigestRepository) subscribe(userID uuid.UUID, frequency models.DigestFrequency) {
+	prefs := models.DefaultNotificationPreferences(userID)
+	prefs.Digest = frequency
+	r.subscribers = append(r.subscribers, *prefs)
+}
+
+func (r *fakeDigestRepository) ListSubscribers() ([]models.NotificationPreferences, error) {
+	return r.subscribers, nil
+}
+
+// ListDigestTasks returns every task of the user; the service picks the ones
+// that belong in the digest
+func (r *fakeDigestRepository) ListDigestTasks(userID uuid.UUID, since, until time.Time) ([]models.Task, error) {
+	var result []models.Task
+	for _, task := range r.tasks {
+		if task.CreatedBy == userID || (task.AssignedTo != nil && *task.AssignedTo == userID) {
+			result = append(result, *task)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeDigestRepository) ClaimDigest(delivery *models.DigestDelivery) (bool, error) {
+	key := delivery.UserID.String() + "/" + delivery.Period
+	if r.claimed[key] {
+		return false, nil
+	}
+	r.claimed[key] = true
+	return true, nil
+}
+
+func (r *fakeDigestRepository) ReleaseDigest(delivery *models.DigestDelivery) error {
+	delete(r.claimed, delivery.UserID.String()+"/"+delivery.Period)
+	return nil
+}
+
+func (r *fakeDigestRepository) DeleteDigestsSentBefore(cutoff time.Time) error {
+	return nil
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestDigest_SendsDailyDigestOncePerDay(t *testing.T) {
+	manager := &models.User{ID: uuid.New(), Email: "manager@example.com", Name: "Marta", Timezone: "America/Argentina/Buenos_Aires", Locale: "es-AR"}
+	ana := &models.User{ID: uuid.New(), Name: "Ana"}
+	users := new(MockUserRepository)
+	users.On("FindByID", manager.ID).Return(manager, nil)
+
+	// 07:30 in Buenos Aires (UTC-3)
+	now := time.Date(2026, 10, 19, 10, 30, 0, 0, time.UTC)
+	task := func(title string, status models.TaskStatus, due *time.Time) *models.Task {
+		return &models.Task{ID: uuid.New(), Title: title, Status: status, DueDate: due, CreatedBy: manager.ID}
+	}
+	overdue := task("Informe <b>Q3</b>", models.TaskStatusInProgress, timePtr(now.Add(-26*time.Hour)))
+	overdue.AssignedTo, overdue.Assignee = &ana.ID, ana
+	dueToday := task("Llamar al cliente", models.TaskStatusPending, timePtr(now.Add(8*time.Hour)))
+	dueTomorrow := task("Revisar contrato", models.TaskStatusPending, timePtr(now.Add(30*time.Hour)))
+	cancelled := task("Cancelada", models.TaskStatusCancelled, timePtr(now.Add(-time.Hour)))
+	assigned := &models.Task{ID: uuid.New(), Title: "Preparar demo", CreatedBy: ana.ID, Creator: ana, AssignedTo: &manager.ID, AssignedAt: timePtr(now.Add(-2 * time.Hour))}
+	oldAssignment := &models.Task{ID: uuid.New(), Title: "Vieja asignación", CreatedBy: ana.ID, AssignedTo: &manager.ID, AssignedAt: timePtr(now.Add(-72 * time.Hour))}
+	completed := task("Cerrar sprint", models.TaskStatusCompleted, nil)
+	completed.AssignedTo, completed.Assignee, completed.CompletedAt = &ana.ID, ana, timePtr(now.Add(-20*time.Hour))
+	completedLastWeek := task("Completada antes", models.TaskStatusCompleted, nil)
+	completedLastWeek.CompletedAt = timePtr(now.Add(-7 * 24 * time.Hour))
+
+	digests := newFakeDigestRepository()
+	digests.tasks = []*models.Task{overdue, dueToday, dueTomorrow, cancelled, assigned, oldAssignment, completed, completedLastWeek}
+	digests.subscribe(manager.ID, models.DigestDaily)
+	mail := &fakeMailer{}
+	service := services.NewDigestService(digests, users, mail, config.DigestConfig{Hour: 7})
+
+	require.NoError(t, service.SendDue(context.Background(), now.Add(-time.Hour)))
+	assert.Empty(t, mail.sent, "before 07:00 local time")
+
+	require.NoError(t, service.SendDue(context.Background(), now))
+	require.NoError(t, service.SendDue(context.Background(), now.Add(time.Hour)))
+	require.Len(t, mail.sent, 1)
+	msg := mail.sent[0]
+	assert.Equal(t, manager.Email, msg.To)
+	assert.Equal(t, "Tu resumen diario de TaskFlow", msg.Subject)
+
+	for _, want := range []string{
+		"Hola Marta,",
+		"Vencidas (1)\n- Informe <b>Q3</b> · vence 18/10 05:30 · asignada a Ana",
+		"Vencen hoy (1)\n- Llamar al cliente · vence 19/10 15:30",
+		"Nuevas asignaciones (1)\n- Preparar demo · de Ana",
+		"Completadas (1)\n- Cerrar sprint · por Ana",
+	} {
+		assert.Contains(t, msg.Text, want)
+	}
+	for _, unwanted := range []string{dueTomorrow.Title, cancelled.Title, oldAssignment.Title, completedLastWeek.Title} {
+		assert.NotContains(t, msg.Text, unwanted)
+		assert.NotContains(t, msg.HTML, unwanted)
+	}
+	assert.Contains(t, msg.HTML, "Informe &lt;b&gt;Q3&lt;/b&gt;")
+	assert.Contains(t, msg.HTML, `<html lang="es">`)
+
+	// The next morning brings a new digest
+	require.NoError(t, service.SendDue(context.Background(), now.Add(24*time.Hour)))
+	assert.Len(t, mail.sent, 2)
+}
+
+func TestDigest_WeeklyOnMondaysAndSkipsEmpty(t *testing.T) {
+	weekly := &models.User{ID: uuid.New(), Email: "weekly@example.com", Name: "Sam", Timezone: "UTC", Locale: "en-US"}
+	idle := &models.User{ID: uuid.New(), Email: "idle@example.com", Name: "Idle", Timezone: "UTC"}
+	gone := &models.User{ID: uuid.New(), Email: "gone@example.com", Name: "Gone", Timezone: "UTC", DeactivatedAt: timePtr(time.Now())}
+	users := new(MockUserRepository)
+	for _, user := range []*models.User{weekly, idle, gone} {
+		users.On("FindByID", user.ID).Return(user, nil)
+	}
+
+	monday := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	digests := newFakeDigestRepository()
+	digests.tasks = []*models.Task{
+		{ID: uuid.New(), Title: "Late", Status: models.TaskStatusPending, CreatedBy: weekly.ID, DueDate: timePtr(monday.Add(-72 * time.Hour))},
+		{ID: uuid.New(), Title: "Late too", Status: models.TaskStatusPending, CreatedBy: gone.ID, DueDate: timePtr(monday.Add(-72 * time.Hour))},
+	}
+	digests.subscribe(weekly.ID, models.DigestWeekly)
+	digests.subscribe(idle.ID, models.DigestDaily)
+	digests.subscribe(gone.ID, models.DigestDaily)
+	mail := &fakeMailer{}
+	service := services.NewDigestService(digests, users, mail, config.DigestConfig{Hour: 7})
+
+	require.NoError(t, service.SendDue(context.Background(), monday.Add(-24*time.Hour)))
+	assert.Empty(t, mail.sent, "weekly digests wait for Monday; empty digests are not sent")
+
+	require.NoError(t, service.SendDue(context.Background(), monday))
+	require.NoError(t, service.SendDue(context.Background(), monday.Add(3*time.Hour)))
+	require.NoError(t, service.SendDue(context.Background(), monday.Add(24*time.Hour)))
+	require.Len(t, mail.sent, 1)
+	assert.Equal(t, weekly.Email, mail.sent[0].To)
+	assert.Equal(t, "Your weekly TaskFlow digest", mail.sent[0].Subject)
+	assert.Contains(t, mail.sent[0].Text, "Overdue (1)\n- Late · due Oct 16 08:00")
+}
+
+func TestDigest_RetriesAfterMailerFailure(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "user@example.com", Name: "Lu", Timezone: "UTC", Locale: "es"}
+	users := new(MockUserRepository)
+	users.On("FindByID", user.ID).Return(user, nil)
+
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	digests := newFakeDigestRepository()
+	digests.tasks = []*models.Task{{ID: uuid.New(), Title: "Pendiente", Status: models.TaskStatusPending, CreatedBy: user.ID, DueDate: timePtr(now.Add(-time.Hour))}}
+	digests.subscribe(user.ID, models.DigestDaily)
+	mail := &fakeMailer{err: errors.New("smtp unavailable")}
+	service := services.NewDigestService(digests, users, mail, config.DigestConfig{Hour: 7})
+
+	require.NoError(t, service.SendDue(context.Background(), now))
+	assert.Empty(t, mail.sent)
+	assert.Empty(t, digests.claimed, "the claim is released when sending fails")
+
+	mail.err = nil
+	require.NoError(t, service.SendDue(context.Background(), now.Add(time.Minute)))
+	require.NoError(t, service.SendDue(context.Background(), now.Add(2*time.Minute)))
+	require.Len(t, mail.sent, 1)
+	assert.Contains(t, mail.sent[0].Text, "Pendiente")
+}
+
+func TestDigest_PreferenceIsOptIn(t *testing.T) {
+	service := services.NewNotificationService(newFakeNotificationRepository(), new(MockUserRepository))
+	userID := uuid.New()
+
+	prefs, err := service.Preferences(userID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DigestOff, prefs.Digest)
+
+	monthly := models.DigestFrequency("monthly")
+	_, err = service.UpdatePreferences(userID, services.UpdateNotificationPreferencesRequest{Digest: &monthly})
+	assert.Error(t, err)
+
+	daily := models.DigestDaily
+	prefs, err = service.UpdatePreferences(userID, services.UpdateNotificationPreferencesRequest{Digest: &daily})
+	require.NoError(t, err)
+	assert.Equal(t, models.DigestDaily, prefs.Digest)
+	assert.True(t, prefs.Assignments, "other preferences are unchanged")
+}
+
+func TestFileMailer_WritesMultipartEmail(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	fileMailer, err := mailer.New(config.MailConfig{Driver: "file", FileDir: dir, From: "TaskFlow <no-reply@taskflow.local>"})
+	require.NoError(t, err)
+
+	err = fileMailer.Send(context.Background(), mailer.Message{
+		To:      "user@example.com",
+		Subject: "Tu resumen diario de TaskFlow",
+		Text:    "Hola\nmundo",
+		HTML:    "<p>Hola</p>",
+	})
+	require.NoError(t, err)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0].Name(), "-user@example.com.eml"))
+
+	raw, err := os.Open(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	defer raw.Close()
+	msg, err := mail.ReadMessage(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", msg.Header.Get("To"))
+	assert.Equal(t, "Tu resumen diario de TaskFlow", msg.Header.Get("Subject"))
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	var contents []string
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		contents = append(contents, part.Header.Get("Content-Type")+": "+string(body))
+	}
+	assert.Equal(t, []string{
+		"text/plain; charset=UTF-8: Hola\r\nmundo",
+		"text/html; charset=UTF-8: <p>Hola</p>",
+	}, contents)
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/handlers"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/websocket"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sseEvent is one parsed Server-Sent Event
+type sseEvent struct {
+	id   string
+	data string
+}
+
+// startEventStream serves /events for a fixed user and opens a stream
+func startEventStream(t *testing.T, hub *websocket.Hub, userID uuid.UUID, query string, header http.Header) (*http.Response, <-chan sseEvent) {
+	gin.SetMode(gin.TestMode)
+	handler := handlers.NewTaskHandler(services.NewTaskService(nil, nil), hub)
+	router := gin.New()
+	router.GET("/events", func(c *gin.Context) { c.Set("user_id", userID) }, handler.Events)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events"+query, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	events := make(chan sseEvent, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		var event sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				event.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				event.data = strings.TrimPrefix(line, "data: ")
+			case line == "" && event.data != "":
+				events <- event
+				event = sseEvent{}
+			}
+		}
+	}()
+	return resp, events
+}
+
+func nextSSE(t *testing.T, events <-chan sseEvent) sseEvent {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return sseEvent{}
+	}
+}
+
+func TestEventStream_StreamsTaskEvents(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{}, nil, nil)
+	go hub.Run()
+	user := uuid.New()
+
+	resp, events := startEventStream(t, hub, user, "", nil)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	require.Eventually(t, func() bool { return hub.ClientCount() == 1 }, time.Second, 10*time.Millisecond)
+
+	task := &models.Task{ID: uuid.New(), Title: "Streamed", CreatedBy: user}
+	hub.BroadcastTaskEvent(models.TaskEvent{ID: 1, Type: "created", TaskID: task.ID, Task: task, UserID: user}, nil)
+
+	event := nextSSE(t, events)
+	var payload models.TaskEvent
+	require.NoError(t, json.Unmarshal([]byte(event.data), &payload))
+	assert.Equal(t, task.ID, payload.TaskID)
+	assert.NotEmpty(t, event.id)
+	assert.Equal(t, event.id, jsonNumber(payload.ID))
+}
+
+func TestEventStream_ResumesFromLastEventID(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{BacklogSize: 10}, nil, nil)
+	go hub.Run()
+	user := uuid.New()
+	task := &models.Task{ID: uuid.New(), CreatedBy: user}
+
+	// A WebSocket client of the same user observes the events as they happen
+	observer := registerClient(hub, user)
+	var first, second models.TaskEvent
+	hub.BroadcastTaskEvent(models.TaskEvent{ID: 1, Type: "created", TaskID: task.ID, Task: task, UserID: user}, nil)
+	require.True(t, receive(observer, &first))
+	hub.BroadcastTaskEvent(models.TaskEvent{ID: 2, Type: "updated", TaskID: task.ID, Task: task, UserID: user}, nil)
+	require.True(t, receive(observer, &second))
+
+	_, events := startEventStream(t, hub, user, "", http.Header{"Last-Event-Id": {jsonNumber(first.ID)}})
+
+	event := nextSSE(t, events)
+	assert.Equal(t, jsonNumber(second.ID), event.id)
+}
+
+func TestEventStream_ForbiddenChannelRejected(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{}, fakeTaskAuthorizer{}, nil)
+	go hub.Run()
+
+	resp, _ := startEventStream(t, hub, uuid.New(), "?channels=task:"+uuid.NewString(), nil)
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, 0, hub.ClientCount())
+}
+
+func jsonNumber(n uint64) string {
+	data, _ := json.Marshal(n)
+	return string(data)
+}
+
+func TestEventStream_ClosedMessageOnDisconnect(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{}, nil, nil)
+	go hub.Run()
+	user := uuid.New()
+
+	_, events := startEventStream(t, hub, user, "", nil)
+	require.Eventually(t, func() bool { return hub.ClientCount() == 1 }, time.Second, 10*time.Millisecond)
+	hub.DisconnectUser(user)
+
+	var reply websocket.ServerReply
+	require.NoError(t, json.Unmarshal([]byte(nextSSE(t, events).data), &reply))
+	assert.Equal(t, websocket.MessageTypeClosed, reply.Type)
+	assert.Equal(t, websocket.CloseReasonSessionRevoked, reply.Error)
+}
@@ -0,0 +1,233 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/jwtkeys"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeInvitationRepository is an in-memory InvitationRepository
+type fakeInvitationRepository struct {
+	invitations map[uuid.UUID]*models.Invitation
+	acceptErr   error // Returned by MarkAccepted when set
+}
+
+func newFakeInvitationRepository() *fakeInvitationRepository {
+	return &fakeInvitationRepository{invitations: make(map[uuid.UUID]*models.Invitation)}
+}
+
+func (r *fakeInvitationRepository) Create(invitation *models.Invitation) error {
+	invitation.ID = uuid.New()
+	r.invitations[invitation.ID] = invitation
+	return nil
+}
+
+func (r *fakeInvitationRepository) FindByID(id uuid.UUID) (*models.Invitation, error) {
+	return r.invitations[id], nil
+}
+
+func (r *fakeInvitationRepository) FindByHash(hash string) (*models.Invitation, error) {
+	for _, invitation := range r.invitations {
+		if invitation.TokenHash == hash {
+			return invitation, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeInvitationRepository) ListByInviter(userID uuid.UUID) ([]models.Invitation, error) {
+	var result []models.Invitation
+	for _, invitation := range r.invitations {
+		if invitation.InvitedBy == userID {
+			result = append(result, *invitation)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeInvitationRepository) Revoke(id uuid.UUID, revokedAt time.Time) error {
+	r.invitations[id].RevokedAt = &revokedAt
+	return nil
+}
+
+func (r *fakeInvitationRepository) Delete(id uuid.UUID) error {
+	delete(r.invitations, id)
+	return nil
+}
+
+func (r *fakeInvitationRepository) MarkAccepted(id, userID uuid.UUID, acceptedAt time.Time) (bool, error) {
+	if r.acceptErr != nil {
+		return false, r.acceptErr
+	}
+	invitation := r.invitations[id]
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		return false, nil
+	}
+	invitation.AcceptedAt = &acceptedAt
+	invitation.AcceptedBy = &userID
+	return true, nil
+}
+
+type invitationFixture struct {
+	userRepo    *MockUserRepository
+	invitations *fakeInvitationRepository
+	mail        *fakeMailer
+	service     *services.InvitationService
+	auth        *services.AuthService
+	admin       *models.User
+}
+
+func newInvitationFixture() *invitationFixture {
+	cfg := &config.Config{
+		JWT:    config.JWTConfig{Secret: "test-secret", ExpirationHours: 1, RefreshExpirationHours: 1},
+		Invite: config.InvitationConfig{URL: "taskflow://invite", ExpirationHours: 72},
+	}
+	f := &invitationFixture{
+		userRepo:    new(MockUserRepository),
+		invitations: newFakeInvitationRepository(),
+		mail:        &fakeMailer{},
+		admin:       &models.User{ID: uuid.New(), Name: "Admin", Email: "admin@example.com", IsAdmin: true},
+	}
+	f.service = services.NewInvitationService(f.invitations, f.userRepo, f.mail, cfg)
+	f.auth = services.NewAuthService(f.userRepo, newFakeTwoFactorChallengeRepository(), jwtkeys.NewHMAC(cfg.JWT.Secret), f.service, nil, cfg)
+	f.userRepo.On("FindByID", f.admin.ID).Return(f.admin, nil)
+	return f
+}
+
+// invite creates an invitation and returns the token from the email
+func (f *invitationFixture) invite(t *testing.T, email string, role models.InvitationRole) string {
+	f.userRepo.On("EmailExists", email).Return(false, nil)
+
+	_, err := f.service.Create(context.Background(), f.admin.ID, services.CreateInvitationRequest{Email: email, Role: role})
+	require.NoError(t, err)
+	require.NotEmpty(t, f.mail.sent)
+
+	text := f.mail.sent[len(f.mail.sent)-1].Text
+	start := strings.Index(text, "taskflow://invite?token=")
+	require.GreaterOrEqual(t, start, 0)
+	return strings.Fields(text[start+len("taskflow://invite?token="):])[0]
+}
+
+func TestRegister_WithInvitationGrantsRole(t *testing.T) {
+	f := newInvitationFixture()
+	token := f.invite(t, "new@example.com", models.InvitationRoleAdmin)
+
+	f.userRepo.On("Create", mock.AnythingOfType("*models.User")).Return(nil)
+	f.userRepo.On("Update", mock.AnythingOfType("*models.User")).Return(nil)
+
+	response, err := f.auth.Register(services.RegisterRequest{
+		Email:       "new@example.com",
+		Password:    "password123",
+		Name:        "New User",
+		InviteToken: token,
+	})
+
+	require.NoError(t, err)
+	assert.True(t, response.User.IsAdmin)
+
+	// The token is single-use
+	_, err = f.service.Preview(token)
+	assert.ErrorIs(t, err, services.ErrInvalidInvitation)
+}
+
+func TestRegister_InvitationForDifferentEmailRejected(t *testing.T) {
+	f := newInvitationFixture()
+	token := f.invite(t, "invited@example.com", models.InvitationRoleMember)
+	f.userRepo.On("EmailExists", "someone-else@example.com").Return(false, nil)
+
+	_, err := f.auth.Register(services.RegisterRequest{
+		Email:       "someone-else@example.com",
+		Password:    "password123",
+		Name:        "Someone",
+		InviteToken: token,
+	})
+
+	assert.Error(t, err)
+	f.userRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestInvitation_RevokedTokenRejected(t *testing.T) {
+	f := newInvitationFixture()
+	token := f.invite(t, "invited@example.com", models.InvitationRoleMember)
+
+	preview, err := f.service.Preview(token)
+	require.NoError(t, err)
+	assert.Equal(t, "invited@example.com", preview.Email)
+
+	invitations, err := f.service.List(f.admin.ID)
+	require.NoError(t, err)
+	require.Len(t, invitations, 1)
+	require.NoError(t, f.service.Revoke(f.admin.ID, invitations[0].ID))
+
+	_, err = f.service.Preview(token)
+	assert.ErrorIs(t, err, services.ErrInvalidInvitation)
+}
+
+func TestInvitation_OnlyAdminsInviteAdmins(t *testing.T) {
+	f := newInvitationFixture()
+	member := &models.User{ID: uuid.New(), Name: "Member"}
+	f.userRepo.On("FindByID", member.ID).Return(member, nil)
+
+	_, err := f.service.Create(context.Background(), member.ID, services.CreateInvitationRequest{
+		Email: "friend@example.com",
+		Role:  models.InvitationRoleAdmin,
+	})
+
+	assert.Error(t, err)
+	assert.Empty(t, f.mail.sent)
+}
+
+func TestInvitation_UndeliveredInvitationIsDiscarded(t *testing.T) {
+	f := newInvitationFixture()
+	f.userRepo.On("EmailExists", "friend@example.com").Return(false, nil)
+	f.mail.err = errors.New("smtp unavailable")
+
+	_, err := f.service.Create(context.Background(), f.admin.ID, services.CreateInvitationRequest{Email: "friend@example.com"})
+
+	assert.ErrorIs(t, err, services.ErrInvitationNotSent)
+	assert.Empty(t, f.invitations.invitations)
+}
+
+func TestRegister_AdminInvitationRequiresActiveAdminInviter(t *testing.T) {
+	f := newInvitationFixture()
+	adminToken := f.invite(t, "admin2@example.com", models.InvitationRoleAdmin)
+	memberToken := f.invite(t, "member@example.com", models.InvitationRoleMember)
+	register := func(email, token string) error {
+		f.userRepo.On("EmailExists", email).Return(false, nil)
+		_, err := f.auth.Register(services.RegisterRequest{Email: email, Password: "password123", Name: "New", InviteToken: token})
+		return err
+	}
+
+	// A demoted admin's pending admin invitation no longer grants anything
+	f.admin.IsAdmin = false
+	assert.ErrorIs(t, register("admin2@example.com", adminToken), services.ErrInvalidInvitation)
+	f.userRepo.AssertNotCalled(t, "Create", mock.Anything)
+
+	// Nor does any invitation from a deactivated user
+	f.admin.DeactivatedAt = timePtr(time.Now())
+	assert.ErrorIs(t, register("member@example.com", memberToken), services.ErrInvalidInvitation)
+	f.userRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestRegister_FailedRedemptionIsReported(t *testing.T) {
+	f := newInvitationFixture()
+	token := f.invite(t, "new@example.com", models.InvitationRoleAdmin)
+	f.userRepo.On("Create", mock.AnythingOfType("*models.User")).Return(nil)
+	f.invitations.acceptErr = errors.New("connection reset")
+
+	_, err := f.auth.Register(services.RegisterRequest{Email: "new@example.com", Password: "password123", Name: "New", InviteToken: token})
+
+	assert.ErrorContains(t, err, "connection reset")
+	f.userRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/jwtkeys"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeyFiles(t *testing.T) (rsaPath, edPath string) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaPath = filepath.Join(dir, "rsa.pem")
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	require.NoError(t, os.WriteFile(rsaPath, rsaPEM, 0o600))
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	edPath = filepath.Join(dir, "ed25519.pem")
+	require.NoError(t, os.WriteFile(edPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDER}), 0o600))
+
+	return rsaPath, edPath
+}
+
+func newTestClaims() *middleware.Claims {
+	return &middleware.Claims{
+		UserID:    uuid.New(),
+		Email:     "test@example.com",
+		TokenType: middleware.TokenTypeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+}
+
+func TestKeySet_RotationGracePeriod(t *testing.T) {
+	rsaPath, edPath := writeKeyFiles(t)
+
+	// Before rotation: RS256 key "2024" signs tokens
+	before, err := jwtkeys.Load(config.JWTConfig{Keys: "2024=" + rsaPath})
+	require.NoError(t, err)
+	oldToken, err := before.Sign(newTestClaims())
+	require.NoError(t, err)
+
+	// After rotation: EdDSA key "2025" signs, "2024" still verifies during its grace period
+	grace := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	after, err := jwtkeys.Load(config.JWTConfig{Keys: "2025=" + edPath + ",2024=" + rsaPath + "@" + grace})
+	require.NoError(t, err)
+
+	newToken, err := after.Sign(newTestClaims())
+	require.NoError(t, err)
+
+	parsed, err := after.Parse(newToken, &middleware.Claims{})
+	require.NoError(t, err)
+	assert.Equal(t, "2025", parsed.Header["kid"])
+	assert.Equal(t, "EdDSA", parsed.Method.Alg())
+
+	_, err = after.Parse(oldToken, &middleware.Claims{})
+	assert.NoError(t, err)
+
+	jwks := after.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "RSA", jwks.Keys[0].Kty)
+	assert.Equal(t, "OKP", jwks.Keys[1].Kty)
+
+	// Once the grace period is over the old key no longer verifies
+	expired := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	retired, err := jwtkeys.Load(config.JWTConfig{Keys: "2025=" + edPath + ",2024=" + rsaPath + "@" + expired})
+	require.NoError(t, err)
+	_, err = retired.Parse(oldToken, &middleware.Claims{})
+	assert.Error(t, err)
+	assert.Len(t, retired.JWKS().Keys, 1)
+}
+
+func TestKeySet_LegacyHMACTokens(t *testing.T) {
+	_, edPath := writeKeyFiles(t)
+
+	legacy := jwtkeys.NewHMAC("test-secret")
+	legacyToken, err := legacy.Sign(newTestClaims())
+	require.NoError(t, err)
+
+	// Legacy tokens verify while JWT_SECRET is still configured
+	migrating, err := jwtkeys.Load(config.JWTConfig{Secret: "test-secret", Keys: "k1=" + edPath})
+	require.NoError(t, err)
+	_, err = migrating.Parse(legacyToken, &middleware.Claims{})
+	assert.NoError(t, err)
+
+	// ...and are rejected once the secret is removed
+	migrated, err := jwtkeys.Load(config.JWTConfig{Keys: "k1=" + edPath})
+	require.NoError(t, err)
+	_, err = migrated.Parse(legacyToken, &middleware.Claims{})
+	assert.Error(t, err)
+
+	// The HMAC secret is never published
+	assert.Empty(t, legacy.JWKS().Keys)
+}
+
+func TestKeySet_RejectsAlgorithmMismatch(t *testing.T) {
+	_, edPath := writeKeyFiles(t)
+	set, err := jwtkeys.Load(config.JWTConfig{Secret: "test-secret", Keys: "k1=" + edPath})
+	require.NoError(t, err)
+
+	// An HS256 token claiming an asymmetric kid must not verify
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, newTestClaims())
+	token.Header["kid"] = "k1"
+	forged, err := token.SignedString([]byte("test-secret"))
+	require.NoError(t, err)
+
+	_, err = set.Parse(forged, &middleware.Claims{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,264 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNotificationRepository keeps notifications in memory, oldest first.
+// Creating a notification for a user in failures returns that error.
+type fakeNotificationRepository struct {
+	notifications []*models.Notification
+	preferences   map[uuid.UUID]*models.NotificationPreferences
+	failures      map[uuid.UUID]error
+}
+
+func newFakeNotificationRepository() *fakeNotificationRepository {
+	return &fakeNotificationRepository{preferences: make(map[uuid.UUID]*models.NotificationPreferences)}
+}
+
+func (r *fakeNotificationRepository) Create(notification *models.Notification) (bool, error) {
+	if err := r.failures[notification.UserID]; err != nil {
+		return false, err
+	}
+	if notification.EventID != nil {
+		for _, n := range r.notifications {
+			if n.EventID != nil && *n.EventID == *notification.EventID && n.UserID == notification.UserID && n.Type == notification.Type {
+				return false, nil
+			}
+		}
+	}
+	notification.ID = uuid.New()
+	notification.CreatedAt = time.Now()
+	r.notifications = append(r.notifications, notification)
+	return true, nil
+}
+
+func (r *fakeNotificationRepository) List(filter models.NotificationFilter) ([]models.Notification, int64, error) {
+	var matching []models.Notification
+	for i := len(r.notifications) - 1; i >= 0; i-- {
+		n := r.notifications[i]
+		if n.UserID == filter.UserID && (!filter.UnreadOnly || n.ReadAt == nil) {
+			matching = append(matching, *n)
+		}
+	}
+	start := (filter.Page - 1) * filter.PageSize
+	if start > len(matching) {
+		start = len(matching)
+	}
+	end := start + filter.PageSize
+	if end > len(matching) {
+		end = len(matching)
+	}
+	return matching[start:end], int64(len(matching)), nil
+}
+
+func (r *fakeNotificationRepository) CountUnread(userID uuid.UUID) (int64, error) {
+	var count int64
+	for _, n := range r.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeNotificationRepository) MarkRead(userID, id uuid.UUID) (bool, error) {
+	for _, n := range r.notifications {
+		if n.ID == id && n.UserID == userID {
+			if n.ReadAt == nil {
+				now := time.Now()
+				n.ReadAt = &now
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeNotificationRepository) MarkAllRead(userID uuid.UUID) (int64, error) {
+	var updated int64
+	now := time.Now()
+	for _, n := range r.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			n.ReadAt = &now
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+func (r *fakeNotificationRepository) FindPreferences(userID uuid.UUID) (*models.NotificationPreferences, error) {
+	return r.preferences[userID], nil
+}
+
+func (r *fakeNotificationRepository) SavePreferences(prefs *models.NotificationPreferences) error {
+	r.preferences[prefs.UserID] = prefs
+	return nil
+}
+
+// forUser returns the notifications sent to a user, oldest first
+func (r *fakeNotificationRepository) forUser(userID uuid.UUID) []*models.Notification {
+	var result []*models.Notification
+	for _, n := range r.notifications {
+		if n.UserID == userID {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+func mention(user uuid.UUID) string {
+	return fmt.Sprintf("@[Someone](%s)", user)
+}
+
+func TestNotification_AssignmentNotifiesNewAssignee(t *testing.T) {
+	repo := newFakeNotificationRepository()
+	service := services.NewNotificationService(repo, new(MockUserRepository))
+
+	creator, first, second := uuid.New(), uuid.New(), uuid.New()
+	unassigned := &models.Task{ID: uuid.New(), Title: "Report", CreatedBy: creator}
+	assigned := *unassigned
+	assigned.AssignedTo = &first
+	reassigned := assigned
+	reassigned.AssignedTo = &second
+	edited := reassigned
+	edited.Title = "Quarterly report"
+
+	events := []*models.OutboxEvent{
+		{Type: models.TaskEventCreated, TaskID: unassigned.ID, ActorID: creator, Task: unassigned},
+		{Type: models.TaskEventAssigned, TaskID: unassigned.ID, ActorID: creator, Task: &assigned, Previous: unassigned},
+		{Type: models.TaskEventAssigned, TaskID: unassigned.ID, ActorID: creator, Task: &reassigned, Previous: &assigned},
+		{Type: models.TaskEventUpdated, TaskID: unassigned.ID, ActorID: creator, Task: &edited, Previous: &reassigned},
+	}
+	for _, event := range events {
+		require.NoError(t, service.HandleTaskEvent(event))
+	}
+
+	require.Len(t, repo.forUser(first), 1)
+	require.Len(t, repo.forUser(second), 1)
+	assert.Empty(t, repo.forUser(creator))
+	n := repo.forUser(second)[0]
+	assert.Equal(t, models.NotificationTypeAssigned, n.Type)
+	assert.Equal(t, unassigned.ID, *n.TaskID)
+	assert.Equal(t, creator, *n.ActorID)
+	assert.Equal(t, "Report", n.TaskTitle)
+
+	// Assigning a task to yourself is not news
+	self := &models.Task{ID: uuid.New(), CreatedBy: creator, AssignedTo: &creator}
+	require.NoError(t, service.HandleTaskEvent(&models.OutboxEvent{Type: models.TaskEventCreated, TaskID: self.ID, ActorID: creator, Task: self}))
+	assert.Empty(t, repo.forUser(creator))
+}
+
+func TestNotification_OnlyNewMentionsNotify(t *testing.T) {
+	repo := newFakeNotificationRepository()
+	users := new(MockUserRepository)
+	service := services.NewNotificationService(repo, users)
+
+	creator, mentioned, deactivated, optedOut := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	deactivatedAt := time.Now()
+	users.On("FindByID", mentioned).Return(&models.User{ID: mentioned}, nil)
+	users.On("FindByID", deactivated).Return(&models.User{ID: deactivated, DeactivatedAt: &deactivatedAt}, nil)
+	users.On("FindByID", optedOut).Return(&models.User{ID: optedOut}, nil)
+	_, err := service.UpdatePreferences(optedOut, services.UpdateNotificationPreferencesRequest{Mentions: new(bool)})
+	require.NoError(t, err)
+
+	before := &models.Task{ID: uuid.New(), Title: "Launch", CreatedBy: creator, Description: "Ask " + mention(mentioned)}
+	after := *before
+	after.Description = "Ask " + mention(mentioned) + " and " + mention(mentioned) + ", " + mention(deactivated) + ", " + mention(optedOut) + " and " + mention(creator)
+
+	require.NoError(t, service.HandleTaskEvent(&models.OutboxEvent{Type: models.TaskEventCreated, TaskID: before.ID, ActorID: creator, Task: before}))
+	require.NoError(t, service.HandleTaskEvent(&models.OutboxEvent{Type: models.TaskEventUpdated, TaskID: before.ID, ActorID: creator, Task: &after, Previous: before}))
+
+	require.Len(t, repo.forUser(mentioned), 1, "mentions carried over from before the edit are not repeated")
+	assert.Equal(t, models.NotificationTypeMentioned, repo.forUser(mentioned)[0].Type)
+	assert.Empty(t, repo.forUser(deactivated))
+	assert.Empty(t, repo.forUser(optedOut))
+	assert.Empty(t, repo.forUser(creator))
+	users.AssertNotCalled(t, "FindByID", creator)
+}
+
+// countingSender counts the notifications handed to it
+type countingSender struct {
+	sent int
+}
+
+func (s *countingSender) SendNotification(notification *models.Notification) error {
+	s.sent++
+	return nil
+}
+
+func TestNotification_RedispatchedEventsAreNotRepeated(t *testing.T) {
+	repo := newFakeNotificationRepository()
+	sender := &countingSender{}
+	service := services.NewNotificationService(repo, new(MockUserRepository), sender)
+
+	creator, assignee := uuid.New(), uuid.New()
+	task := &models.Task{ID: uuid.New(), Title: "Report", CreatedBy: creator, AssignedTo: &assignee}
+	event := &models.OutboxEvent{ID: 7, Type: models.TaskEventCreated, TaskID: task.ID, ActorID: creator, Task: task}
+
+	// The outbox retries the sink when saving its progress failed
+	require.NoError(t, service.HandleTaskEvent(event))
+	require.NoError(t, service.HandleTaskEvent(event))
+
+	require.Len(t, repo.forUser(assignee), 1)
+	assert.Equal(t, uint64(7), *repo.forUser(assignee)[0].EventID)
+	assert.Equal(t, 1, sender.sent)
+}
+
+func TestNotification_ListAndMarkRead(t *testing.T) {
+	repo := newFakeNotificationRepository()
+	service := services.NewNotificationService(repo, new(MockUserRepository))
+
+	user, other := uuid.New(), uuid.New()
+	for _, recipient := range []uuid.UUID{user, user, user, other} {
+		_, err := repo.Create(&models.Notification{UserID: recipient, Type: models.NotificationTypeAssigned})
+		require.NoError(t, err)
+	}
+
+	page, total, err := service.List(models.NotificationFilter{UserID: user, Page: 1, PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, page, 2)
+	assert.Equal(t, repo.notifications[2].ID, page[0].ID, "newest first")
+
+	assert.ErrorIs(t, service.MarkRead(other, page[0].ID), services.ErrNotificationNotFound)
+	require.NoError(t, service.MarkRead(user, page[0].ID))
+	require.NoError(t, service.MarkRead(user, page[0].ID), "marking twice is fine")
+	unread, _ := service.UnreadCount(user)
+	assert.Equal(t, int64(2), unread)
+
+	updated, err := service.MarkAllRead(user)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), updated)
+	unread, _ = service.UnreadCount(user)
+	assert.Zero(t, unread)
+	unread, _ = service.UnreadCount(other)
+	assert.Equal(t, int64(1), unread)
+}
+
+func TestNotification_PreferencesDefaultToEnabled(t *testing.T) {
+	repo := newFakeNotificationRepository()
+	service := services.NewNotificationService(repo, new(MockUserRepository))
+	user := uuid.New()
+
+	prefs, err := service.Preferences(user)
+	require.NoError(t, err)
+	assert.True(t, prefs.Assignments && prefs.Mentions && prefs.DueDates)
+
+	prefs, err = service.UpdatePreferences(user, services.UpdateNotificationPreferencesRequest{DueDates: new(bool)})
+	require.NoError(t, err)
+	assert.True(t, prefs.Assignments)
+	assert.False(t, prefs.DueDates)
+
+	stored, _ := service.Preferences(user)
+	assert.False(t, stored.Allows(models.NotificationTypeDueSoon))
+	assert.True(t, stored.Allows(models.NotificationTypeAssigned))
+}
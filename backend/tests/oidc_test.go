@@ -0,0 +1,278 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/jwtkeys"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/oidc"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// standInIdP is a minimal OpenID provider for tests
+type standInIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	mu     sync.Mutex
+	codes  map[string]issuedCode
+}
+
+type issuedCode struct {
+	challenge string
+	nonce     string
+	subject   string
+	email     string
+}
+
+func newStandInIdP(t *testing.T) *standInIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp := &standInIdP{key: key, codes: make(map[string]issuedCode)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "idp-1",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		idp.mu.Lock()
+		issued, ok := idp.codes[r.Form.Get("code")]
+		delete(idp.codes, r.Form.Get("code"))
+		idp.mu.Unlock()
+
+		if !ok || oidc.CodeChallengeS256(r.Form.Get("code_verifier")) != issued.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            idp.server.URL,
+			"aud":            "taskflow",
+			"sub":            issued.subject,
+			"email":          issued.email,
+			"email_verified": true,
+			"name":           "Company User",
+			"nonce":          issued.nonce,
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"iat":            time.Now().Unix(),
+		})
+		token.Header["kid"] = "idp-1"
+		idToken, _ := token.SignedString(key)
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "token_type": "Bearer"})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// authorize simulates the user signing in at the provider and returns the code
+func (idp *standInIdP) authorize(t *testing.T, authURL, subject, email string) (code, state string) {
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := parsed.Query()
+	require.Equal(t, "S256", query.Get("code_challenge_method"))
+
+	code = uuid.NewString()
+	idp.mu.Lock()
+	idp.codes[code] = issuedCode{
+		challenge: query.Get("code_challenge"),
+		nonce:     query.Get("nonce"),
+		subject:   subject,
+		email:     email,
+	}
+	idp.mu.Unlock()
+	return code, query.Get("state")
+}
+
+// fakeIdentityRepository is an in-memory IdentityRepository
+type fakeIdentityRepository struct {
+	identities []models.UserIdentity
+	requests   map[string]models.OIDCAuthRequest
+}
+
+func newFakeIdentityRepository() *fakeIdentityRepository {
+	return &fakeIdentityRepository{requests: make(map[string]models.OIDCAuthRequest)}
+}
+
+func (r *fakeIdentityRepository) FindIdentity(provider, subject string) (*models.UserIdentity, error) {
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			found := identity
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeIdentityRepository) CreateIdentity(identity *models.UserIdentity) error {
+	r.identities = append(r.identities, *identity)
+	return nil
+}
+
+func (r *fakeIdentityRepository) CreateAuthRequest(request *models.OIDCAuthRequest) error {
+	r.requests[request.State] = *request
+	return nil
+}
+
+func (r *fakeIdentityRepository) ConsumeAuthRequest(state string) (*models.OIDCAuthRequest, error) {
+	request, ok := r.requests[state]
+	if !ok {
+		return nil, nil
+	}
+	delete(r.requests, state)
+	return &request, nil
+}
+
+func newOIDCService(idp *standInIdP, userRepo *MockUserRepository, identityRepo *fakeIdentityRepository, autoProvision bool) *services.OIDCService {
+	cfg := &config.Config{
+		JWT:   config.JWTConfig{Secret: "test-secret", ExpirationHours: 1, RefreshExpirationHours: 1},
+		Admin: config.AdminConfig{Emails: []string{"boss@company.com"}},
+	}
+	authService := services.NewAuthService(userRepo, newFakeTwoFactorChallengeRepository(), jwtkeys.NewHMAC(cfg.JWT.Secret), nil, nil, cfg)
+	provider := oidc.NewProvider(config.OIDCProviderConfig{
+		Name:          "company",
+		Issuer:        idp.server.URL,
+		ClientID:      "taskflow",
+		RedirectURL:   "http://localhost:8080/api/v1/auth/oidc/company/callback",
+		Scopes:        "openid email profile",
+		AutoProvision: autoProvision,
+	}, idp.server.Client())
+	return services.NewOIDCService([]*oidc.Provider{provider}, identityRepo, userRepo, authService)
+}
+
+func TestOIDCLogin_ProvisionsUserAndLinksIdentity(t *testing.T) {
+	idp := newStandInIdP(t)
+	userRepo := new(MockUserRepository)
+	identityRepo := newFakeIdentityRepository()
+	service := newOIDCService(idp, userRepo, identityRepo, true)
+	ctx := context.Background()
+
+	userRepo.On("FindByEmail", "new@company.com").Return(nil, nil)
+	userRepo.On("Create", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).ID = uuid.New()
+	}).Return(nil)
+
+	authURL, err := service.AuthorizationURL(ctx, "company")
+	require.NoError(t, err)
+	code, state := idp.authorize(t, authURL, "subject-1", "new@company.com")
+
+	response, err := service.Callback(ctx, "company", code, state)
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.Token)
+	assert.Equal(t, "new@company.com", response.User.Email)
+	assert.Equal(t, "Company User", response.User.Name)
+	require.Len(t, identityRepo.identities, 1)
+	assert.Equal(t, "subject-1", identityRepo.identities[0].Subject)
+
+	// The state is single-use
+	_, err = service.Callback(ctx, "company", code, state)
+	assert.Error(t, err)
+}
+
+func TestOIDCLogin_ProvisionsAdminWithNormalizedEmail(t *testing.T) {
+	idp := newStandInIdP(t)
+	userRepo := new(MockUserRepository)
+	identityRepo := newFakeIdentityRepository()
+	service := newOIDCService(idp, userRepo, identityRepo, true)
+	ctx := context.Background()
+
+	var created *models.User
+	userRepo.On("FindByEmail", "Boss@Company.com").Return(nil, nil)
+	userRepo.On("Create", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		created = args.Get(0).(*models.User)
+		created.ID = uuid.New()
+	}).Return(nil)
+
+	authURL, err := service.AuthorizationURL(ctx, "company")
+	require.NoError(t, err)
+	code, state := idp.authorize(t, authURL, "subject-2", "Boss@Company.com")
+
+	_, err = service.Callback(ctx, "company", code, state)
+	require.NoError(t, err)
+	require.NotNil(t, created)
+	assert.Equal(t, "boss@company.com", created.Email)
+	assert.True(t, created.IsAdmin, "ADMIN_EMAILS applies to provisioned users as it does on Register")
+}
+
+func TestOIDCLogin_LinksExistingUserByEmail(t *testing.T) {
+	idp := newStandInIdP(t)
+	userRepo := new(MockUserRepository)
+	identityRepo := newFakeIdentityRepository()
+	service := newOIDCService(idp, userRepo, identityRepo, false)
+	ctx := context.Background()
+
+	existing := &models.User{ID: uuid.New(), Email: "existing@company.com", Name: "Existing"}
+	userRepo.On("FindByEmail", existing.Email).Return(existing, nil)
+	userRepo.On("FindByID", existing.ID).Return(existing, nil)
+
+	authURL, err := service.AuthorizationURL(ctx, "company")
+	require.NoError(t, err)
+	code, state := idp.authorize(t, authURL, "subject-2", existing.Email)
+
+	response, err := service.Callback(ctx, "company", code, state)
+	require.NoError(t, err)
+	assert.Equal(t, existing.ID, response.User.ID)
+
+	// Subsequent logins resolve through the linked identity
+	authURL, err = service.AuthorizationURL(ctx, "company")
+	require.NoError(t, err)
+	code, state = idp.authorize(t, authURL, "subject-2", existing.Email)
+
+	response, err = service.Callback(ctx, "company", code, state)
+	require.NoError(t, err)
+	assert.Equal(t, existing.ID, response.User.ID)
+	assert.Len(t, identityRepo.identities, 1)
+	userRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestOIDCLogin_RejectsUnknownUserWithoutProvisioning(t *testing.T) {
+	idp := newStandInIdP(t)
+	userRepo := new(MockUserRepository)
+	service := newOIDCService(idp, userRepo, newFakeIdentityRepository(), false)
+	ctx := context.Background()
+
+	userRepo.On("FindByEmail", "stranger@company.com").Return(nil, nil)
+
+	authURL, err := service.AuthorizationURL(ctx, "company")
+	require.NoError(t, err)
+	code, state := idp.authorize(t, authURL, "subject-3", "stranger@company.com")
+
+	_, err = service.Callback(ctx, "company", code, state)
+	assert.Error(t, err)
+	assert.Equal(t, "no account exists for this email", err.Error())
+}
@@ -0,0 +1,238 @@
+package tests
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/websocket"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOutboxRepository keeps outbox events in memory, in ID order
+type fakeOutboxRepository struct {
+	mu     sync.Mutex
+	events []*models.OutboxEvent
+}
+
+func (r *fakeOutboxRepository) add(eventType string, task *models.Task) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, &models.OutboxEvent{
+		ID:        uint64(len(r.events) + 1),
+		Type:      eventType,
+		TaskID:    task.ID,
+		ActorID:   task.CreatedBy,
+		Task:      task,
+		CreatedAt: time.Now(),
+	})
+}
+
+// DispatchNext passes the oldest pending event to fn, which records its
+// progress on the stored event directly, unless it is waiting for a retry
+func (r *fakeOutboxRepository) DispatchNext(now time.Time, fn func(event *models.OutboxEvent) error) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, event := range r.events {
+		if event.DispatchedAt != nil || event.FailedAt != nil {
+			continue
+		}
+		if event.NextAttemptAt != nil && event.NextAttemptAt.After(now) {
+			return false, nil
+		}
+		return true, fn(event)
+	}
+	return false, nil
+}
+
+func (r *fakeOutboxRepository) ListFailed(limit int) ([]models.OutboxEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []models.OutboxEvent
+	for _, event := range r.events {
+		if event.FailedAt != nil && len(result) < limit {
+			result = append(result, *event)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeOutboxRepository) Retry(id uint64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, event := range r.events {
+		if event.ID == id && event.FailedAt != nil {
+			event.FailedAt, event.Attempts, event.NextAttemptAt = nil, 0, nil
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeOutboxRepository) DeleteDispatchedBefore(cutoff time.Time) error {
+	return nil
+}
+
+// recordingSink records the IDs of the events it receives, failing while
+// failing is set and for the next failures calls
+type recordingSink struct {
+	name     string
+	ids      []uint64
+	failing  bool
+	failures int
+}
+
+func (s *recordingSink) SinkName() string {
+	return s.name
+}
+
+func (s *recordingSink) HandleTaskEvent(event *models.OutboxEvent) error {
+	if s.failing || s.failures > 0 {
+		s.failures--
+		return errors.New("sink unavailable")
+	}
+	s.ids = append(s.ids, event.ID)
+	return nil
+}
+
+func TestOutbox_DispatchesEachEventOnceInOrder(t *testing.T) {
+	repo := &fakeOutboxRepository{}
+	first, second := &recordingSink{name: "first"}, &recordingSink{name: "second"}
+	dispatcher := services.NewOutboxDispatcher(repo, config.OutboxConfig{}, first, second)
+
+	task := &models.Task{ID: uuid.New(), CreatedBy: uuid.New()}
+	repo.add(models.TaskEventCreated, task)
+	repo.add(models.TaskEventUpdated, task)
+	require.NoError(t, dispatcher.DispatchPending())
+
+	repo.add(models.TaskEventDeleted, task)
+	require.NoError(t, dispatcher.DispatchPending())
+	require.NoError(t, dispatcher.DispatchPending())
+
+	assert.Equal(t, []uint64{1, 2, 3}, first.ids)
+	assert.Equal(t, []uint64{1, 2, 3}, second.ids)
+}
+
+func TestOutbox_FailingSinkHoldsBackLaterEvents(t *testing.T) {
+	repo := &fakeOutboxRepository{}
+	sink := &recordingSink{name: "sink", failing: true}
+	dispatcher := services.NewOutboxDispatcher(repo, config.OutboxConfig{}, sink)
+
+	task := &models.Task{ID: uuid.New(), CreatedBy: uuid.New()}
+	repo.add(models.TaskEventCreated, task)
+	repo.add(models.TaskEventUpdated, task)
+
+	assert.Error(t, dispatcher.DispatchPending())
+	assert.Empty(t, sink.ids)
+	assert.Nil(t, repo.events[0].DispatchedAt, "event stays pending")
+
+	sink.failing = false
+	require.NoError(t, dispatcher.DispatchPending())
+	assert.Equal(t, []uint64{1, 2}, sink.ids)
+}
+
+func TestOutbox_RetryOnlyRunsSinksThatFailed(t *testing.T) {
+	repo := &fakeOutboxRepository{}
+	first, second := &recordingSink{name: "first"}, &recordingSink{name: "second", failures: 1}
+	dispatcher := services.NewOutboxDispatcher(repo, config.OutboxConfig{}, first, second)
+
+	task := &models.Task{ID: uuid.New(), CreatedBy: uuid.New()}
+	repo.add(models.TaskEventCreated, task)
+
+	assert.Error(t, dispatcher.DispatchPending())
+	assert.Equal(t, []string{"first"}, repo.events[0].HandledBy)
+	assert.Equal(t, 1, repo.events[0].Attempts)
+	assert.Contains(t, repo.events[0].LastError, "second")
+
+	require.NoError(t, dispatcher.DispatchPending())
+	assert.Equal(t, []uint64{1}, first.ids, "the first sink is not called again")
+	assert.Equal(t, []uint64{1}, second.ids)
+	assert.NotNil(t, repo.events[0].DispatchedAt)
+}
+
+func TestOutbox_GivesUpAfterMaxAttempts(t *testing.T) {
+	repo := &fakeOutboxRepository{}
+	broken := &recordingSink{name: "broken", failures: 2}
+	dispatcher := services.NewOutboxDispatcher(repo, config.OutboxConfig{MaxAttempts: 2}, broken)
+
+	task := &models.Task{ID: uuid.New(), CreatedBy: uuid.New()}
+	repo.add(models.TaskEventCreated, task)
+	repo.add(models.TaskEventUpdated, task)
+
+	assert.Error(t, dispatcher.DispatchPending())
+	assert.Nil(t, repo.events[0].FailedAt)
+	assert.Error(t, dispatcher.DispatchPending())
+	assert.NotNil(t, repo.events[0].FailedAt, "given up after two attempts")
+
+	require.NoError(t, dispatcher.DispatchPending())
+	assert.Equal(t, []uint64{2}, broken.ids, "later events are no longer held back")
+	assert.Nil(t, repo.events[0].DispatchedAt)
+
+	// Once the sink is fixed, an administrator re-drives the event
+	failed, err := dispatcher.FailedEvents()
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Contains(t, failed[0].LastError, "sink unavailable")
+	require.NoError(t, dispatcher.RetryFailed(1))
+	assert.ErrorIs(t, dispatcher.RetryFailed(2), services.ErrOutboxEventNotFound, "only failed events can be retried")
+	require.NoError(t, dispatcher.DispatchPending())
+	assert.Equal(t, []uint64{2, 1}, broken.ids)
+	assert.NotNil(t, repo.events[0].DispatchedAt)
+}
+
+func TestOutbox_BacksOffBetweenAttempts(t *testing.T) {
+	repo := &fakeOutboxRepository{}
+	sink := &recordingSink{name: "sink", failures: 2}
+	dispatcher := services.NewOutboxDispatcher(repo, config.OutboxConfig{MaxAttempts: 20, RetryBaseDelay: time.Minute}, sink)
+
+	task := &models.Task{ID: uuid.New(), CreatedBy: uuid.New()}
+	repo.add(models.TaskEventCreated, task)
+	repo.add(models.TaskEventUpdated, task)
+
+	start := time.Now()
+	assert.Error(t, dispatcher.DispatchPending())
+	require.NotNil(t, repo.events[0].NextAttemptAt)
+	assert.WithinDuration(t, start.Add(time.Minute), *repo.events[0].NextAttemptAt, 5*time.Second)
+
+	// Polls before the next attempt is due neither retry nor skip the event
+	require.NoError(t, dispatcher.DispatchPending())
+	assert.Equal(t, 1, sink.failures, "not retried yet")
+	assert.Empty(t, sink.ids)
+
+	past := time.Now().Add(-time.Second)
+	repo.events[0].NextAttemptAt = &past
+	assert.Error(t, dispatcher.DispatchPending())
+	assert.WithinDuration(t, start.Add(2*time.Minute), *repo.events[0].NextAttemptAt, 5*time.Second, "the delay doubles")
+	assert.Nil(t, repo.events[0].FailedAt)
+
+	repo.events[0].NextAttemptAt = &past
+	require.NoError(t, dispatcher.DispatchPending())
+	assert.Equal(t, []uint64{1, 2}, sink.ids)
+}
+
+func TestOutbox_HubPublishesDispatchedEvents(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{}, nil, nil)
+	go hub.Run()
+
+	creator := uuid.New()
+	client := registerClient(hub, creator)
+
+	repo := &fakeOutboxRepository{}
+	dispatcher := services.NewOutboxDispatcher(repo, config.OutboxConfig{}, hub)
+	task := &models.Task{ID: uuid.New(), Title: "Outbox", CreatedBy: creator}
+	repo.add(models.TaskEventCreated, task)
+	require.NoError(t, dispatcher.DispatchPending())
+
+	var event models.TaskEvent
+	require.True(t, receive(client, &event))
+	assert.Equal(t, models.TaskEventCreated, event.Type)
+	assert.Equal(t, task.ID, event.TaskID)
+	assert.Equal(t, creator, event.UserID)
+	assert.Equal(t, uint64(1), event.ID, "the event ID is the outbox ID")
+}
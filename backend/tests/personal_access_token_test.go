@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/jwtkeys"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/middleware"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPersonalAccessTokenRepository is a mock implementation of PersonalAccessTokenRepository
+type MockPersonalAccessTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockPersonalAccessTokenRepository) Create(token *models.PersonalAccessToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockPersonalAccessTokenRepository) FindByHash(hash string) (*models.PersonalAccessToken, error) {
+	args := m.Called(hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PersonalAccessToken), args.Error(1)
+}
+
+func (m *MockPersonalAccessTokenRepository) ListByUser(userID uuid.UUID) ([]models.PersonalAccessToken, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.PersonalAccessToken), args.Error(1)
+}
+
+func (m *MockPersonalAccessTokenRepository) Delete(id, userID uuid.UUID) (bool, error) {
+	args := m.Called(id, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPersonalAccessTokenRepository) UpdateLastUsed(id uuid.UUID, usedAt time.Time) error {
+	args := m.Called(id, usedAt)
+	return args.Error(0)
+}
+
+func (m *MockPersonalAccessTokenRepository) DeleteByUser(userID uuid.UUID) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func TestCreatePersonalAccessToken_StoresOnlyHash(t *testing.T) {
+	mockRepo := new(MockPersonalAccessTokenRepository)
+	service := services.NewPersonalAccessTokenService(mockRepo)
+	userID := uuid.New()
+
+	var stored *models.PersonalAccessToken
+	mockRepo.On("Create", mock.AnythingOfType("*models.PersonalAccessToken")).
+		Run(func(args mock.Arguments) { stored = args.Get(0).(*models.PersonalAccessToken) }).
+		Return(nil)
+
+	response, err := service.Create(userID, services.CreatePersonalAccessTokenRequest{
+		Name:  "seeder",
+		Scope: models.TokenScopeWrite,
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(response.Token, models.PersonalAccessTokenPrefix))
+	assert.NotEqual(t, response.Token, stored.TokenHash)
+	assert.True(t, strings.HasPrefix(response.Token, stored.TokenPrefix))
+	assert.Equal(t, userID, stored.UserID)
+}
+
+func TestCreatePersonalAccessToken_InvalidScope(t *testing.T) {
+	mockRepo := new(MockPersonalAccessTokenRepository)
+	service := services.NewPersonalAccessTokenService(mockRepo)
+
+	_, err := service.Create(uuid.New(), services.CreatePersonalAccessTokenRequest{
+		Name:  "admin",
+		Scope: "admin",
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, "invalid scope", err.Error())
+	mockRepo.AssertNotCalled(t, "Create")
+}
+
+func TestAuthMiddleware_PersonalAccessTokenScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockRepo := new(MockPersonalAccessTokenRepository)
+	service := services.NewPersonalAccessTokenService(mockRepo)
+
+	user := &models.User{ID: uuid.New(), Email: "script@example.com"}
+	var stored *models.PersonalAccessToken
+	mockRepo.On("Create", mock.AnythingOfType("*models.PersonalAccessToken")).
+		Run(func(args mock.Arguments) { stored = args.Get(0).(*models.PersonalAccessToken) }).
+		Return(nil)
+	created, err := service.Create(user.ID, services.CreatePersonalAccessTokenRequest{Name: "ci", Scope: models.TokenScopeRead})
+	assert.NoError(t, err)
+	plaintext := created.Token
+	stored.User = user
+
+	mockRepo.On("FindByHash", stored.TokenHash).Return(stored, nil)
+	mockRepo.On("FindByHash", mock.Anything).Return(nil, nil)
+	mockRepo.On("UpdateLastUsed", stored.ID, mock.AnythingOfType("time.Time")).Return(nil)
+
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(jwtkeys.NewHMAC("test-secret"), service, nil))
+	router.GET("/tasks", func(c *gin.Context) {
+		userID, _ := middleware.GetUserID(c)
+		c.String(http.StatusOK, userID.String())
+	})
+	router.POST("/tasks", func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	request := func(method, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/tasks", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodGet, plaintext)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, user.ID.String(), w.Body.String())
+	assert.NotNil(t, stored.LastUsedAt)
+
+	w = request(http.MethodPost, plaintext)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = request(http.MethodGet, models.PersonalAccessTokenPrefix+"unknown")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,428 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/push"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDeviceRepository keeps device tokens in memory, keyed by token
+type fakeDeviceRepository struct {
+	devices map[string]*models.DeviceToken
+}
+
+func newFakeDeviceRepository() *fakeDeviceRepository {
+	return &fakeDeviceRepository{devices: make(map[string]*models.DeviceToken)}
+}
+
+func (r *fakeDeviceRepository) Register(device *models.DeviceToken) error {
+	if existing, ok := r.devices[device.Token]; ok {
+		existing.UserID = device.UserID
+		existing.Platform = device.Platform
+		*device = *existing
+		return nil
+	}
+	device.ID = uuid.New()
+	stored := *device
+	r.devices[device.Token] = &stored
+	return nil
+}
+
+func (r *fakeDeviceRepository) ListByUser(userID uuid.UUID) ([]models.DeviceToken, error) {
+	var result []models.DeviceToken
+	for _, device := range r.devices {
+		if device.UserID == userID {
+			result = append(result, *device)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeDeviceRepository) Delete(userID uuid.UUID, token string) (bool, error) {
+	device, ok := r.devices[token]
+	if !ok || device.UserID != userID {
+		return false, nil
+	}
+	delete(r.devices, token)
+	return true, nil
+}
+
+func (r *fakeDeviceRepository) DeleteTokens(tokens []string) error {
+	for _, token := range tokens {
+		delete(r.devices, token)
+	}
+	return nil
+}
+
+// fakePushSender records sent batches. Messages to tokens listed in
+// rejected get that ticket error; others are accepted with a ticket ID equal
+// to their token. While sendErr is set, only the first sendLimit messages of
+// a batch are sent before it is returned. Receipts are served from receipts.
+type fakePushSender struct {
+	batches   [][]push.Message
+	rejected  map[string]string
+	receipts  map[string]push.Receipt
+	asked     [][]string
+	sendErr   error
+	sendLimit int
+}
+
+func newFakePushSender() *fakePushSender {
+	return &fakePushSender{rejected: make(map[string]string), receipts: make(map[string]push.Receipt)}
+}
+
+func (s *fakePushSender) Send(ctx context.Context, messages []push.Message) ([]push.Ticket, error) {
+	s.batches = append(s.batches, messages)
+	sent := messages
+	if s.sendErr != nil && len(sent) > s.sendLimit {
+		sent = sent[:s.sendLimit]
+	}
+	tickets := make([]push.Ticket, len(sent))
+	for i, msg := range sent {
+		if code, ok := s.rejected[msg.To]; ok {
+			tickets[i] = push.Ticket{Error: code}
+		} else {
+			tickets[i] = push.Ticket{ID: msg.To}
+		}
+	}
+	return tickets, s.sendErr
+}
+
+func (s *fakePushSender) Receipts(ctx context.Context, ticketIDs []string) (map[string]push.Receipt, error) {
+	s.asked = append(s.asked, ticketIDs)
+	result := make(map[string]push.Receipt)
+	for _, id := range ticketIDs {
+		if receipt, ok := s.receipts[id]; ok {
+			result[id] = receipt
+		}
+	}
+	return result, nil
+}
+
+// fakePresence reports the listed users as online
+type fakePresence map[uuid.UUID]bool
+
+func (p fakePresence) IsOnline(userID uuid.UUID) bool {
+	return p[userID]
+}
+
+func expoToken(name string) string {
+	return fmt.Sprintf("ExponentPushToken[%s]", name)
+}
+
+func TestPush_RegisterDevice(t *testing.T) {
+	devices := newFakeDeviceRepository()
+	service := services.NewPushService(devices, new(MockUserRepository), newFakePushSender(), nil, config.PushConfig{})
+	first, second := uuid.New(), uuid.New()
+
+	_, err := service.RegisterDevice(first, services.RegisterDeviceRequest{Token: "not-a-token"})
+	assert.Error(t, err)
+
+	device, err := service.RegisterDevice(first, services.RegisterDeviceRequest{Token: expoToken("phone"), Platform: "ios"})
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, device.ID)
+
+	// Signing in with another account on the same phone moves the token
+	moved, err := service.RegisterDevice(second, services.RegisterDeviceRequest{Token: expoToken("phone"), Platform: "ios"})
+	require.NoError(t, err)
+	assert.Equal(t, device.ID, moved.ID)
+	owned, _ := devices.ListByUser(first)
+	assert.Empty(t, owned)
+
+	assert.ErrorIs(t, service.UnregisterDevice(first, expoToken("phone")), services.ErrDeviceNotFound)
+	require.NoError(t, service.UnregisterDevice(second, expoToken("phone")))
+	assert.Empty(t, devices.devices)
+}
+
+func TestPush_BatchesNotificationsForOfflineUsers(t *testing.T) {
+	devices := newFakeDeviceRepository()
+	users := new(MockUserRepository)
+	sender := newFakePushSender()
+	offline, online := uuid.New(), uuid.New()
+	users.On("FindByID", offline).Return(&models.User{ID: offline, Locale: "en-US"}, nil)
+	service := services.NewPushService(devices, users, sender, fakePresence{online: true}, config.PushConfig{})
+
+	for _, registration := range []struct {
+		user  uuid.UUID
+		token string
+	}{{offline, expoToken("phone")}, {offline, expoToken("tablet")}, {online, expoToken("laptop")}} {
+		_, err := service.RegisterDevice(registration.user, services.RegisterDeviceRequest{Token: registration.token})
+		require.NoError(t, err)
+	}
+
+	taskID := uuid.New()
+	notifications := []*models.Notification{
+		{ID: uuid.New(), UserID: offline, Type: models.NotificationTypeAssigned, TaskID: &taskID, TaskTitle: "Budget"},
+		{ID: uuid.New(), UserID: offline, Type: models.NotificationTypeMentioned, TaskID: &taskID, TaskTitle: "Budget"},
+		{ID: uuid.New(), UserID: offline, Type: models.NotificationTypeOverdue, TaskID: &taskID, TaskTitle: "Budget"},
+		{ID: uuid.New(), UserID: online, Type: models.NotificationTypeAssigned, TaskID: &taskID, TaskTitle: "Budget"},
+	}
+	for _, n := range notifications {
+		require.NoError(t, service.SendNotification(n))
+	}
+	assert.Empty(t, sender.batches, "nothing is sent before the flush")
+
+	require.NoError(t, service.Flush(context.Background()))
+	require.Len(t, sender.batches, 1)
+	batch := sender.batches[0]
+	require.Len(t, batch, 4, "assignment and reminder to both devices of the offline user")
+	for _, msg := range batch {
+		assert.NotEqual(t, expoToken("laptop"), msg.To)
+		assert.Equal(t, "Budget", msg.Title)
+		assert.Equal(t, taskID.String(), msg.Data["task_id"])
+	}
+	assert.Equal(t, "This task was assigned to you", batch[0].Body)
+	assert.Equal(t, string(models.NotificationTypeOverdue), batch[3].Data["type"])
+
+	require.NoError(t, service.Flush(context.Background()))
+	assert.Len(t, sender.batches, 1, "the queue is empty after a flush")
+}
+
+func TestPush_RemovesInvalidTokens(t *testing.T) {
+	devices := newFakeDeviceRepository()
+	users := new(MockUserRepository)
+	sender := newFakePushSender()
+	user := uuid.New()
+	users.On("FindByID", user).Return(&models.User{ID: user}, nil)
+	service := services.NewPushService(devices, users, sender, nil, config.PushConfig{ReceiptDelay: 15 * time.Minute})
+
+	for _, name := range []string{"uninstalled", "stale", "fine"} {
+		_, err := service.RegisterDevice(user, services.RegisterDeviceRequest{Token: expoToken(name)})
+		require.NoError(t, err)
+	}
+	sender.rejected[expoToken("uninstalled")] = push.ErrorDeviceNotRegistered
+
+	require.NoError(t, service.SendNotification(&models.Notification{ID: uuid.New(), UserID: user, Type: models.NotificationTypeDueSoon}))
+	require.NoError(t, service.Flush(context.Background()))
+	assert.NotContains(t, devices.devices, expoToken("uninstalled"), "rejected right away")
+
+	// Receipts are only checked after the delay, and again until they are ready
+	require.NoError(t, service.CheckReceipts(context.Background(), time.Now()))
+	assert.Empty(t, sender.asked)
+	sender.receipts[expoToken("stale")] = push.Receipt{Error: push.ErrorDeviceNotRegistered}
+	require.NoError(t, service.CheckReceipts(context.Background(), time.Now().Add(16*time.Minute)))
+	assert.NotContains(t, devices.devices, expoToken("stale"))
+	assert.Contains(t, devices.devices, expoToken("fine"))
+
+	sender.receipts[expoToken("fine")] = push.Receipt{}
+	require.NoError(t, service.CheckReceipts(context.Background(), time.Now().Add(17*time.Minute)))
+	require.Len(t, sender.asked, 2)
+	assert.Equal(t, []string{expoToken("fine")}, sender.asked[1])
+	require.NoError(t, service.CheckReceipts(context.Background(), time.Now().Add(18*time.Minute)))
+	assert.Len(t, sender.asked, 2, "delivered receipts are not checked again")
+	assert.Contains(t, devices.devices, expoToken("fine"))
+}
+
+func TestPush_RequeuesUnsentPushesWhenSendingFails(t *testing.T) {
+	devices := newFakeDeviceRepository()
+	users := new(MockUserRepository)
+	sender := newFakePushSender()
+	user := uuid.New()
+	users.On("FindByID", user).Return(&models.User{ID: user}, nil)
+	service := services.NewPushService(devices, users, sender, nil, config.PushConfig{})
+
+	for _, name := range []string{"phone", "tablet", "watch"} {
+		_, err := service.RegisterDevice(user, services.RegisterDeviceRequest{Token: expoToken(name)})
+		require.NoError(t, err)
+	}
+	notify := func() {
+		require.NoError(t, service.SendNotification(&models.Notification{ID: uuid.New(), UserID: user, Type: models.NotificationTypeOverdue}))
+	}
+
+	// The request fails after the first message; the other two are retried
+	notify()
+	sender.sendErr, sender.sendLimit = errors.New("connection reset"), 1
+	assert.Error(t, service.Flush(context.Background()))
+	sender.sendErr = nil
+	require.NoError(t, service.Flush(context.Background()))
+	require.Len(t, sender.batches, 2)
+	assert.Len(t, sender.batches[0], 3)
+	assert.Equal(t, sender.batches[0][1:], sender.batches[1])
+
+	// Pushes that keep failing are dropped after five attempts
+	notify()
+	sender.sendErr, sender.sendLimit = errors.New("service unavailable"), 0
+	for i := 0; i < 5; i++ {
+		assert.Error(t, service.Flush(context.Background()))
+	}
+	sender.sendErr = nil
+	require.NoError(t, service.Flush(context.Background()))
+	assert.Len(t, sender.batches, 7, "the queue is empty after the last attempt")
+}
+
+func TestPush_DropsPushesBeyondQueueSize(t *testing.T) {
+	devices := newFakeDeviceRepository()
+	users := new(MockUserRepository)
+	sender := newFakePushSender()
+	user := uuid.New()
+	users.On("FindByID", user).Return(&models.User{ID: user}, nil)
+	service := services.NewPushService(devices, users, sender, nil, config.PushConfig{QueueSize: 2})
+
+	for _, name := range []string{"phone", "tablet", "watch"} {
+		_, err := service.RegisterDevice(user, services.RegisterDeviceRequest{Token: expoToken(name)})
+		require.NoError(t, err)
+	}
+	require.NoError(t, service.SendNotification(&models.Notification{ID: uuid.New(), UserID: user, Type: models.NotificationTypeOverdue}))
+	require.NoError(t, service.SendNotification(&models.Notification{ID: uuid.New(), UserID: user, Type: models.NotificationTypeDueSoon}))
+
+	require.NoError(t, service.Flush(context.Background()))
+	require.Len(t, sender.batches, 1)
+	assert.Len(t, sender.batches[0], 2)
+}
+
+// fakeLocalPresence is a fakePresence that also lists its online users
+type fakeLocalPresence struct {
+	fakePresence
+}
+
+func (p fakeLocalPresence) OnlineUsers() []uuid.UUID {
+	var users []uuid.UUID
+	for userID, online := range p.fakePresence {
+		if online {
+			users = append(users, userID)
+		}
+	}
+	return users
+}
+
+// fakePresenceRepository keeps the latest report of each instance in memory
+type fakePresenceRepository struct {
+	seen map[uuid.UUID]map[uuid.UUID]time.Time // instance ID -> user ID -> seen at
+	err  error
+}
+
+func (r *fakePresenceRepository) Sync(instanceID uuid.UUID, userIDs []uuid.UUID, now, staleBefore time.Time) error {
+	if r.seen == nil {
+		r.seen = make(map[uuid.UUID]map[uuid.UUID]time.Time)
+	}
+	r.seen[instanceID] = make(map[uuid.UUID]time.Time)
+	for _, userID := range userIDs {
+		r.seen[instanceID][userID] = now
+	}
+	return nil
+}
+
+func (r *fakePresenceRepository) IsOnline(userID uuid.UUID, since time.Time) (bool, error) {
+	if r.err != nil {
+		return false, r.err
+	}
+	for _, users := range r.seen {
+		if seenAt, ok := users[userID]; ok && !seenAt.Before(since) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestSharedPresence_CountsUsersOnOtherInstances(t *testing.T) {
+	repo := &fakePresenceRepository{}
+	here, there, nowhere := uuid.New(), uuid.New(), uuid.New()
+	local := services.NewSharedPresence(fakeLocalPresence{fakePresence{here: true}}, repo, time.Minute)
+	other := services.NewSharedPresence(fakeLocalPresence{fakePresence{there: true}}, repo, time.Minute)
+
+	require.NoError(t, other.Sync(time.Now()))
+	assert.True(t, local.IsOnline(here))
+	assert.True(t, local.IsOnline(there), "connected to the other instance")
+	assert.False(t, local.IsOnline(nowhere))
+
+	// Old reports no longer count
+	require.NoError(t, other.Sync(time.Now().Add(-3*time.Minute)))
+	assert.False(t, local.IsOnline(there))
+
+	// A user whose presence cannot be read gets the push
+	require.NoError(t, other.Sync(time.Now()))
+	repo.err = errors.New("connection refused")
+	assert.False(t, local.IsOnline(there))
+	assert.True(t, local.IsOnline(here))
+}
+
+func TestPush_GivesUpOnReceiptsThatNeverArrive(t *testing.T) {
+	devices := newFakeDeviceRepository()
+	users := new(MockUserRepository)
+	sender := newFakePushSender()
+	user := uuid.New()
+	users.On("FindByID", user).Return(&models.User{ID: user}, nil)
+	service := services.NewPushService(devices, users, sender, nil, config.PushConfig{ReceiptDelay: 15 * time.Minute})
+
+	_, err := service.RegisterDevice(user, services.RegisterDeviceRequest{Token: expoToken("phone")})
+	require.NoError(t, err)
+	require.NoError(t, service.SendNotification(&models.Notification{ID: uuid.New(), UserID: user, Type: models.NotificationTypeDueSoon}))
+	require.NoError(t, service.Flush(context.Background()))
+
+	sentAt := time.Now()
+	require.NoError(t, service.CheckReceipts(context.Background(), sentAt.Add(16*time.Minute)))
+	require.NoError(t, service.CheckReceipts(context.Background(), sentAt.Add(23*time.Hour)))
+	assert.Len(t, sender.asked, 2, "not ready yet, asked again")
+
+	require.NoError(t, service.CheckReceipts(context.Background(), sentAt.Add(25*time.Hour)))
+	require.NoError(t, service.CheckReceipts(context.Background(), sentAt.Add(26*time.Hour)))
+	assert.Len(t, sender.asked, 2, "receipts older than a day are no longer checked")
+	assert.Contains(t, devices.devices, expoToken("phone"))
+}
+
+func TestExpoSender_SendsInBatchesAndReadsReceipts(t *testing.T) {
+	var sizes []int
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/--/api/v2/push/send":
+			var messages []push.Message
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&messages))
+			sizes = append(sizes, len(messages))
+			data := make([]map[string]interface{}, len(messages))
+			for i, msg := range messages {
+				if msg.To == expoToken("gone") {
+					data[i] = map[string]interface{}{"status": "error", "message": "not registered", "details": map[string]string{"error": "DeviceNotRegistered"}}
+				} else {
+					data[i] = map[string]interface{}{"status": "ok", "id": "ticket-" + msg.To}
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+		case "/--/api/v2/push/getReceipts":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+				"a": map[string]string{"status": "ok"},
+				"b": map[string]interface{}{"status": "error", "details": map[string]string{"error": "DeviceNotRegistered"}},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	sender := push.NewExpoSender(server.URL, "secret-token", nil)
+	messages := make([]push.Message, 150)
+	for i := range messages {
+		messages[i] = push.Message{To: expoToken(fmt.Sprint(i))}
+	}
+	messages[120].To = expoToken("gone")
+
+	tickets, err := sender.Send(context.Background(), messages)
+	require.NoError(t, err)
+	assert.Equal(t, []int{100, 50}, sizes)
+	assert.Equal(t, "Bearer secret-token", authorization)
+	require.Len(t, tickets, 150)
+	assert.Equal(t, "ticket-"+expoToken("0"), tickets[0].ID)
+	assert.True(t, tickets[120].InvalidToken())
+	assert.Empty(t, tickets[120].ID)
+
+	receipts, err := sender.Receipts(context.Background(), []string{"a", "b", "c"})
+	require.NoError(t, err)
+	assert.False(t, receipts["a"].InvalidToken())
+	assert.Empty(t, receipts["a"].Error)
+	assert.True(t, receipts["b"].InvalidToken())
+	assert.NotContains(t, receipts, "c")
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRateLimitedRouter(rule config.RateLimitRule, userID *uuid.UUID) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if userID != nil {
+		router.Use(func(c *gin.Context) {
+			c.Set("user_id", *userID)
+			c.Next()
+		})
+	}
+	router.Use(middleware.RateLimitMiddleware(rule))
+	router.GET("/ping", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func doRequest(router *gin.Engine, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_ExhaustsBurst(t *testing.T) {
+	router := newRateLimitedRouter(config.RateLimitRule{RequestsPerMinute: 1, Burst: 2}, nil)
+
+	w := doRequest(router, "10.0.0.1:1234")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+
+	w = doRequest(router, "10.0.0.1:1234")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	w = doRequest(router, "10.0.0.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.NotEmpty(t, w.Header().Get("RateLimit-Reset"))
+
+	// A different client has its own bucket
+	w = doRequest(router, "10.0.0.2:1234")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRateLimit_KeysByAuthenticatedUser(t *testing.T) {
+	userID := uuid.New()
+	router := newRateLimitedRouter(config.RateLimitRule{RequestsPerMinute: 1, Burst: 1}, &userID)
+
+	w := doRequest(router, "10.0.0.1:1234")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Same user from another IP shares the bucket
+	w = doRequest(router, "10.0.0.2:1234")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestRateLimit_DisabledRule(t *testing.T) {
+	router := newRateLimitedRouter(config.RateLimitRule{}, nil)
+
+	for i := 0; i < 5; i++ {
+		w := doRequest(router, "10.0.0.1:1234")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	}
+}
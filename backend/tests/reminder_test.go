@@ -0,0 +1,230 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/websocket"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReminderRepository serves a fixed set of tasks and remembers claimed
+// reminders
+type fakeReminderRepository struct {
+	tasks   []*models.Task
+	claimed map[string]bool
+}
+
+func newFakeReminderRepository(tasks ...*models.Task) *fakeReminderRepository {
+	return &fakeReminderRepository{tasks: tasks, claimed: make(map[string]bool)}
+}
+
+func (r *fakeReminderRepository) ListOpenTasksDueBetween(from, to time.Time) ([]models.Task, error) {
+	var result []models.Task
+	for _, task := range r.tasks {
+		if task.DueDate != nil && !task.DueDate.Before(from) && !task.DueDate.After(to) &&
+			task.Status != models.TaskStatusCompleted && task.Status != models.TaskStatusCancelled {
+			// Like the preloads, with active users unless the test set them
+			loaded := *task
+			if loaded.Creator == nil {
+				loaded.Creator = &models.User{ID: loaded.CreatedBy}
+			}
+			if loaded.AssignedTo != nil && loaded.Assignee == nil {
+				loaded.Assignee = &models.User{ID: *loaded.AssignedTo}
+			}
+			result = append(result, loaded)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeReminderRepository) ClaimReminder(reminder *models.TaskReminder) (bool, error) {
+	key := fmt.Sprintf("%s/%s/%s/%d", reminder.TaskID, reminder.UserID, reminder.Threshold, reminder.DueDate.Unix())
+	if r.claimed[key] {
+		return false, nil
+	}
+	r.claimed[key] = true
+	return true, nil
+}
+
+func (r *fakeReminderRepository) ReleaseReminder(reminder *models.TaskReminder) error {
+	delete(r.claimed, fmt.Sprintf("%s/%s/%s/%d", reminder.TaskID, reminder.UserID, reminder.Threshold, reminder.DueDate.Unix()))
+	return nil
+}
+
+func (r *fakeReminderRepository) DeleteRemindersDueBefore(cutoff time.Time) error {
+	return nil
+}
+
+// notificationTypes returns the types of the notifications sent to a user,
+// oldest first
+func notificationTypes(repo *fakeNotificationRepository, userID uuid.UUID) []models.NotificationType {
+	var types []models.NotificationType
+	for _, n := range repo.forUser(userID) {
+		types = append(types, n.Type)
+	}
+	return types
+}
+
+func TestReminder_RemindsOncePerThreshold(t *testing.T) {
+	creator, assignee := uuid.New(), uuid.New()
+	due := time.Now().Add(48 * time.Hour)
+	task := &models.Task{ID: uuid.New(), Title: "Ship", CreatedBy: creator, AssignedTo: &assignee, DueDate: &due, Priority: models.PriorityMedium}
+
+	notifications := newFakeNotificationRepository()
+	notificationService := services.NewNotificationService(notifications, new(MockUserRepository))
+	reminders := services.NewReminderService(newFakeReminderRepository(task), notificationService, config.ReminderConfig{})
+
+	steps := []struct {
+		at   time.Duration // relative to the due date
+		want int           // notifications so far
+	}{
+		{-30 * time.Hour, 0},
+		{-23 * time.Hour, 1}, // 24h lead time reached
+		{-22 * time.Hour, 1},
+		{-30 * time.Minute, 2}, // 1h lead time reached
+		{time.Minute, 3},       // overdue
+		{time.Hour, 3},
+	}
+	for _, step := range steps {
+		require.NoError(t, reminders.SendDue(due.Add(step.at)))
+		assert.Len(t, notifications.forUser(assignee), step.want, "at %s", step.at)
+	}
+	assert.Equal(t, []models.NotificationType{models.NotificationTypeDueSoon, models.NotificationTypeDueSoon, models.NotificationTypeOverdue}, notificationTypes(notifications, assignee))
+	assert.Empty(t, notifications.forUser(creator), "unassigned tasks remind the creator; assigned ones the assignee")
+	assert.Nil(t, notifications.forUser(assignee)[0].ActorID)
+
+	// A new due date starts over
+	later := due.Add(72 * time.Hour)
+	task.DueDate = &later
+	require.NoError(t, reminders.SendDue(later.Add(-10*time.Minute)))
+	assert.Len(t, notifications.forUser(assignee), 4)
+}
+
+func TestReminder_UsesUserLeadTimes(t *testing.T) {
+	creator := uuid.New()
+	due := time.Now().Add(48 * time.Hour)
+	task := &models.Task{ID: uuid.New(), CreatedBy: creator, DueDate: &due}
+	done := &models.Task{ID: uuid.New(), CreatedBy: creator, DueDate: &due, Status: models.TaskStatusCompleted}
+
+	notifications := newFakeNotificationRepository()
+	notificationService := services.NewNotificationService(notifications, new(MockUserRepository))
+	_, err := notificationService.UpdatePreferences(creator, services.UpdateNotificationPreferencesRequest{ReminderLeadMinutes: []int{15, 3 * 24 * 60, 15}})
+	require.NoError(t, err)
+	reminders := services.NewReminderService(newFakeReminderRepository(task, done), notificationService, config.ReminderConfig{})
+
+	// Already within three days of the due date: reminded right away, and only
+	// for the shortest lead time reached
+	require.NoError(t, reminders.SendDue(due.Add(-47*time.Hour)))
+	require.NoError(t, reminders.SendDue(due.Add(-time.Hour)))
+	assert.Len(t, notifications.forUser(creator), 1)
+	require.NoError(t, reminders.SendDue(due.Add(-10*time.Minute)))
+	assert.Len(t, notifications.forUser(creator), 2)
+	for _, n := range notifications.forUser(creator) {
+		assert.Equal(t, task.ID, *n.TaskID, "completed tasks are not reminded")
+	}
+
+	_, err = notificationService.UpdatePreferences(creator, services.UpdateNotificationPreferencesRequest{ReminderLeadMinutes: []int{0}})
+	assert.Error(t, err)
+}
+
+func TestReminder_EscalatesOverdueUrgentTasks(t *testing.T) {
+	creator, assignee := uuid.New(), uuid.New()
+	due := time.Now().Add(-time.Hour)
+	urgent := &models.Task{ID: uuid.New(), CreatedBy: creator, AssignedTo: &assignee, DueDate: &due, Priority: models.PriorityUrgent}
+	high := &models.Task{ID: uuid.New(), CreatedBy: creator, AssignedTo: &assignee, DueDate: &due, Priority: models.PriorityHigh}
+	own := &models.Task{ID: uuid.New(), CreatedBy: creator, DueDate: &due, Priority: models.PriorityUrgent}
+
+	notifications := newFakeNotificationRepository()
+	notificationService := services.NewNotificationService(notifications, new(MockUserRepository))
+	reminders := services.NewReminderService(newFakeReminderRepository(urgent, high, own), notificationService, config.ReminderConfig{EscalateAfter: 2 * time.Hour})
+
+	require.NoError(t, reminders.SendDue(due.Add(time.Hour)))
+	assert.Equal(t, []models.NotificationType{models.NotificationTypeOverdue}, notificationTypes(notifications, creator), "own task is only overdue")
+
+	require.NoError(t, reminders.SendDue(due.Add(3*time.Hour)))
+	require.NoError(t, reminders.SendDue(due.Add(4*time.Hour)))
+	types := notificationTypes(notifications, creator)
+	require.Len(t, types, 2)
+	assert.Equal(t, models.NotificationTypeEscalated, types[1])
+	assert.Equal(t, urgent.ID, *notifications.forUser(creator)[1].TaskID)
+	assert.Len(t, notifications.forUser(assignee), 2, "overdue reminders for both assigned tasks")
+}
+
+func TestReminder_SkipsDeactivatedUsers(t *testing.T) {
+	creator, assignee, colleague := uuid.New(), uuid.New(), uuid.New()
+	due := time.Now().Add(-time.Hour)
+	deactivatedAt := time.Now()
+	inactiveAssignee := &models.User{ID: assignee, DeactivatedAt: &deactivatedAt}
+	inactiveCreator := &models.User{ID: creator, DeactivatedAt: &deactivatedAt}
+	handedBack := &models.Task{ID: uuid.New(), CreatedBy: creator, AssignedTo: &assignee, Assignee: inactiveAssignee, DueDate: &due, Priority: models.PriorityUrgent}
+	orphaned := &models.Task{ID: uuid.New(), CreatedBy: creator, Creator: inactiveCreator, AssignedTo: &assignee, Assignee: inactiveAssignee, DueDate: &due}
+	noEscalation := &models.Task{ID: uuid.New(), CreatedBy: creator, Creator: inactiveCreator, AssignedTo: &colleague, DueDate: &due, Priority: models.PriorityUrgent}
+
+	notifications := newFakeNotificationRepository()
+	notificationService := services.NewNotificationService(notifications, new(MockUserRepository))
+	reminders := services.NewReminderService(newFakeReminderRepository(handedBack, orphaned, noEscalation), notificationService, config.ReminderConfig{EscalateAfter: time.Hour})
+
+	require.NoError(t, reminders.SendDue(due.Add(2*time.Hour)))
+
+	creatorNotifications := notifications.forUser(creator)
+	require.Len(t, creatorNotifications, 1, "only the task whose assignee is deactivated falls back to the creator")
+	assert.Equal(t, handedBack.ID, *creatorNotifications[0].TaskID)
+	assert.Equal(t, models.NotificationTypeOverdue, creatorNotifications[0].Type)
+	assert.Empty(t, notifications.forUser(assignee))
+	colleagueNotifications := notifications.forUser(colleague)
+	require.Len(t, colleagueNotifications, 1, "the active assignee is reminded, the deactivated creator is not escalated to")
+	assert.Equal(t, noEscalation.ID, *colleagueNotifications[0].TaskID)
+}
+
+func TestReminder_FailedNotificationIsRetried(t *testing.T) {
+	failing, working := uuid.New(), uuid.New()
+	due := time.Now().Add(-time.Hour)
+	first := &models.Task{ID: uuid.New(), CreatedBy: failing, DueDate: &due}
+	second := &models.Task{ID: uuid.New(), CreatedBy: working, DueDate: &due}
+
+	notifications := newFakeNotificationRepository()
+	notifications.failures = map[uuid.UUID]error{failing: errors.New("database unavailable")}
+	notificationService := services.NewNotificationService(notifications, new(MockUserRepository))
+	reminders := services.NewReminderService(newFakeReminderRepository(first, second), notificationService, config.ReminderConfig{})
+
+	// The first task fails; the second is still reminded
+	require.NoError(t, reminders.SendDue(time.Now()))
+	assert.Empty(t, notifications.forUser(failing))
+	assert.Len(t, notifications.forUser(working), 1)
+
+	// The failed reminder was not recorded as sent
+	delete(notifications.failures, failing)
+	require.NoError(t, reminders.SendDue(time.Now()))
+	assert.Equal(t, []models.NotificationType{models.NotificationTypeOverdue}, notificationTypes(notifications, failing))
+	assert.Len(t, notifications.forUser(working), 1)
+}
+
+func TestReminder_SendsNotificationOverWebSocket(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{}, nil, nil)
+	go hub.Run()
+	creator := uuid.New()
+	client := registerClient(hub, creator)
+
+	due := time.Now().Add(30 * time.Minute)
+	task := &models.Task{ID: uuid.New(), Title: "Call", CreatedBy: creator, DueDate: &due}
+	notifications := newFakeNotificationRepository()
+	notificationService := services.NewNotificationService(notifications, new(MockUserRepository), hub)
+	reminders := services.NewReminderService(newFakeReminderRepository(task), notificationService, config.ReminderConfig{})
+	require.NoError(t, reminders.SendDue(time.Now()))
+
+	var event models.TaskEvent
+	require.True(t, receive(client, &event))
+	assert.Equal(t, models.TaskEventNotification, event.Type)
+	assert.Equal(t, task.ID, event.TaskID)
+	require.NotNil(t, event.Notification)
+	assert.Equal(t, models.NotificationTypeDueSoon, event.Notification.Type)
+	assert.Equal(t, "Call", event.Notification.TaskTitle)
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
@@ -8,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockTaskRepository is a mock implementation of TaskRepository
@@ -15,8 +17,8 @@ type MockTaskRepository struct {
 	mock.Mock
 }
 
-func (m *MockTaskRepository) Create(task *models.Task) error {
-	args := m.Called(task)
+func (m *MockTaskRepository) Create(task *models.Task, actorID uuid.UUID) error {
+	args := m.Called(task, actorID)
 	return args.Error(0)
 }
 
@@ -28,13 +30,13 @@ func (m *MockTaskRepository) FindByID(id uuid.UUID) (*models.Task, error) {
 	return args.Get(0).(*models.Task), args.Error(1)
 }
 
-func (m *MockTaskRepository) Update(task *models.Task) error {
-	args := m.Called(task)
+func (m *MockTaskRepository) Update(task *models.Task, actorID uuid.UUID) error {
+	args := m.Called(task, actorID)
 	return args.Error(0)
 }
 
-func (m *MockTaskRepository) Delete(id uuid.UUID) error {
-	args := m.Called(id)
+func (m *MockTaskRepository) Delete(id, actorID uuid.UUID) error {
+	args := m.Called(id, actorID)
 	return args.Error(0)
 }
 
@@ -43,16 +45,24 @@ func (m *MockTaskRepository) List(filter models.TaskFilter) ([]models.Task, int6
 	return args.Get(0).([]models.Task), args.Get(1).(int64), args.Error(2)
 }
 
-func (m *MockTaskRepository) UpdateStatus(id uuid.UUID, status models.TaskStatus) error {
-	args := m.Called(id, status)
+func (m *MockTaskRepository) UpdateStatus(id uuid.UUID, status models.TaskStatus, actorID uuid.UUID) error {
+	args := m.Called(id, status, actorID)
 	return args.Error(0)
 }
 
-func (m *MockTaskRepository) AssignTask(taskID, userID uuid.UUID) error {
-	args := m.Called(taskID, userID)
+func (m *MockTaskRepository) AssignTask(taskID, userID, actorID uuid.UUID) error {
+	args := m.Called(taskID, userID, actorID)
 	return args.Error(0)
 }
 
+func (m *MockTaskRepository) ListCollaborators(userID uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
 func TestCreateTask_Success(t *testing.T) {
 	mockTaskRepo := new(MockTaskRepository)
 	mockUserRepo := new(MockUserRepository)
@@ -74,7 +84,7 @@ func TestCreateTask_Success(t *testing.T) {
 		CreatedBy:   userID,
 	}
 
-	mockTaskRepo.On("Create", mock.AnythingOfType("*models.Task")).Return(nil)
+	mockTaskRepo.On("Create", mock.AnythingOfType("*models.Task"), userID).Return(nil)
 	mockTaskRepo.On("FindByID", mock.AnythingOfType("uuid.UUID")).Return(expectedTask, nil)
 
 	task, err := service.Create(userID, req)
@@ -106,7 +116,7 @@ func TestUpdateTask_Success(t *testing.T) {
 	}
 
 	mockTaskRepo.On("FindByID", taskID).Return(existingTask, nil).Times(2)
-	mockTaskRepo.On("Update", mock.AnythingOfType("*models.Task")).Return(nil)
+	mockTaskRepo.On("Update", mock.AnythingOfType("*models.Task"), userID).Return(nil)
 
 	task, err := service.Update(taskID, userID, req)
 
@@ -159,7 +169,7 @@ func TestDeleteTask_Success(t *testing.T) {
 	}
 
 	mockTaskRepo.On("FindByID", taskID).Return(existingTask, nil)
-	mockTaskRepo.On("Delete", taskID).Return(nil)
+	mockTaskRepo.On("Delete", taskID, userID).Return(nil)
 
 	err := service.Delete(taskID, userID)
 
@@ -183,7 +193,7 @@ func TestUpdateStatus_Success(t *testing.T) {
 	}
 
 	mockTaskRepo.On("FindByID", taskID).Return(existingTask, nil).Times(2)
-	mockTaskRepo.On("UpdateStatus", taskID, newStatus).Return(nil)
+	mockTaskRepo.On("UpdateStatus", taskID, newStatus, userID).Return(nil)
 
 	task, err := service.UpdateStatus(taskID, userID, newStatus)
 
@@ -191,3 +201,48 @@ func TestUpdateStatus_Success(t *testing.T) {
 	assert.NotNil(t, task)
 	mockTaskRepo.AssertExpectations(t)
 }
+
+func TestUpdateTask_TracksCompletion(t *testing.T) {
+	mockTaskRepo := new(MockTaskRepository)
+	service := services.NewTaskService(mockTaskRepo, new(MockUserRepository))
+
+	userID := uuid.New()
+	taskID := uuid.New()
+	existingTask := &models.Task{ID: taskID, CreatedBy: userID, Status: models.TaskStatusInProgress}
+
+	mockTaskRepo.On("FindByID", taskID).Return(existingTask, nil)
+	mockTaskRepo.On("Update", existingTask, userID).Return(nil)
+
+	completed := models.TaskStatusCompleted
+	task, err := service.Update(taskID, userID, services.UpdateTaskRequest{Status: &completed})
+	assert.NoError(t, err)
+	if assert.NotNil(t, task.CompletedAt) {
+		completedAt := *task.CompletedAt
+
+		// Saving a completed task again keeps the original completion time
+		task, err = service.Update(taskID, userID, services.UpdateTaskRequest{Status: &completed})
+		assert.NoError(t, err)
+		assert.Equal(t, completedAt, *task.CompletedAt)
+	}
+
+	reopened := models.TaskStatusPending
+	task, err = service.Update(taskID, userID, services.UpdateTaskRequest{Status: &reopened})
+	assert.NoError(t, err)
+	assert.Nil(t, task.CompletedAt)
+}
+
+func TestTask_SerializedUsersHideAccountFlags(t *testing.T) {
+	admin := &models.User{ID: uuid.New(), Name: "Admin", IsAdmin: true, TOTPEnabled: true}
+	task := models.Task{ID: uuid.New(), Title: "Shared", CreatedBy: admin.ID, Creator: admin, AssignedTo: &admin.ID, Assignee: admin}
+
+	body, err := json.Marshal(task)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "is_admin")
+	assert.NotContains(t, string(body), "two_factor_enabled")
+
+	// The user's own profile and the admin views still show them
+	body, err = json.Marshal(admin.ToResponse(nil))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"is_admin":true`)
+	assert.Contains(t, string(body), `"two_factor_enabled":true`)
+}
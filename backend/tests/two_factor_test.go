@@ -0,0 +1,326 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/jwtkeys"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/totp"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeTwoFactorChallengeRepository keeps login challenges in memory
+type fakeTwoFactorChallengeRepository struct {
+	challenges map[uuid.UUID]*models.TwoFactorChallenge
+}
+
+func newFakeTwoFactorChallengeRepository() *fakeTwoFactorChallengeRepository {
+	return &fakeTwoFactorChallengeRepository{challenges: make(map[uuid.UUID]*models.TwoFactorChallenge)}
+}
+
+func (r *fakeTwoFactorChallengeRepository) Create(challenge *models.TwoFactorChallenge) error {
+	challenge.ID = uuid.New()
+	challenge.CreatedAt = time.Now()
+	stored := *challenge
+	r.challenges[challenge.ID] = &stored
+	return nil
+}
+
+func (r *fakeTwoFactorChallengeRepository) FindByID(id uuid.UUID) (*models.TwoFactorChallenge, error) {
+	challenge, ok := r.challenges[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *challenge
+	return &copied, nil
+}
+
+func (r *fakeTwoFactorChallengeRepository) RecordFailure(id uuid.UUID) error {
+	if challenge, ok := r.challenges[id]; ok {
+		challenge.Attempts++
+	}
+	return nil
+}
+
+func (r *fakeTwoFactorChallengeRepository) Consume(id uuid.UUID, maxAttempts int, now time.Time) (bool, error) {
+	challenge, ok := r.challenges[id]
+	if !ok || challenge.UsedAt != nil || challenge.Attempts >= maxAttempts || !now.Before(challenge.ExpiresAt) {
+		return false, nil
+	}
+	challenge.UsedAt = &now
+	return true, nil
+}
+
+func (r *fakeTwoFactorChallengeRepository) CountFailures(userID uuid.UUID, since time.Time) (int, error) {
+	failures := 0
+	for _, challenge := range r.challenges {
+		if challenge.UserID == userID && !challenge.CreatedAt.Before(since) {
+			failures += challenge.Attempts
+		}
+	}
+	return failures, nil
+}
+
+func (r *fakeTwoFactorChallengeRepository) DeleteExpiredByUser(userID uuid.UUID, before time.Time) error {
+	for id, challenge := range r.challenges {
+		if challenge.UserID == userID && challenge.ExpiresAt.Before(before) {
+			delete(r.challenges, id)
+		}
+	}
+	return nil
+}
+
+func newTwoFactorConfig() *config.Config {
+	return &config.Config{
+		JWT: config.JWTConfig{
+			Secret:                 "test-secret",
+			ExpirationHours:        24,
+			RefreshExpirationHours: 168,
+		},
+		TwoFactor: config.TwoFactorConfig{
+			Issuer:           "TaskFlow",
+			ChallengeMinutes: 5,
+		},
+	}
+}
+
+func newTwoFactorAuthService(repo *MockUserRepository) *services.AuthService {
+	cfg := newTwoFactorConfig()
+	return services.NewAuthService(repo, newFakeTwoFactorChallengeRepository(), jwtkeys.NewHMAC(cfg.JWT.Secret), nil, nil, cfg)
+}
+
+func newTwoFactorUser(t *testing.T) *models.User {
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+
+	user := &models.User{
+		ID:          uuid.New(),
+		Email:       "admin@example.com",
+		Name:        "Admin",
+		Password:    "password123",
+		TOTPSecret:  secret,
+		TOTPEnabled: true,
+	}
+	user.HashPassword()
+	return user
+}
+
+func TestTOTP_RFC6238Vector(t *testing.T) {
+	// Secret "12345678901234567890" from RFC 6238 appendix B, truncated to 6 digits
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	code, err := totp.GenerateCode(secret, time.Unix(59, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, "287082", code)
+
+	code, err = totp.GenerateCode(secret, time.Unix(1111111109, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, "081804", code)
+
+	_, ok := totp.Validate("081804", secret, time.Unix(1111111109+30, 0))
+	assert.True(t, ok, "previous period is accepted within skew")
+	_, ok = totp.Validate("081804", secret, time.Unix(1111111109+120, 0))
+	assert.False(t, ok)
+}
+
+func TestLogin_TwoFactorReturnsChallenge(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newTwoFactorAuthService(mockRepo)
+	user := newTwoFactorUser(t)
+
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil)
+
+	response, err := service.Login(services.LoginRequest{Email: user.Email, Password: "password123"})
+
+	assert.NoError(t, err)
+	assert.True(t, response.TwoFactorRequired)
+	assert.NotEmpty(t, response.ChallengeToken)
+	assert.Empty(t, response.Token)
+	assert.Nil(t, response.User)
+
+	// The challenge cannot be used as a refresh token
+	_, err = service.RefreshToken(response.ChallengeToken)
+	assert.Error(t, err)
+}
+
+func TestVerifyTwoFactor_Success(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newTwoFactorAuthService(mockRepo)
+	user := newTwoFactorUser(t)
+
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil)
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	mockRepo.On("Update", mock.AnythingOfType("*models.User")).Return(nil)
+
+	login, err := service.Login(services.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.NoError(t, err)
+
+	code, err := totp.GenerateCode(user.TOTPSecret, time.Now())
+	assert.NoError(t, err)
+
+	response, err := service.VerifyTwoFactor(services.VerifyTwoFactorRequest{
+		ChallengeToken: login.ChallengeToken,
+		Code:           code,
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.Token)
+	assert.NotEmpty(t, response.RefreshToken)
+
+	// The same code cannot be replayed, even with a new challenge
+	login, err = service.Login(services.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.NoError(t, err)
+	_, err = service.VerifyTwoFactor(services.VerifyTwoFactorRequest{
+		ChallengeToken: login.ChallengeToken,
+		Code:           code,
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "invalid two-factor code", err.Error())
+}
+
+func TestVerifyTwoFactor_ChallengeSingleUse(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newTwoFactorAuthService(mockRepo)
+	user := newTwoFactorUser(t)
+
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil)
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	mockRepo.On("Update", mock.AnythingOfType("*models.User")).Return(nil)
+
+	login, err := service.Login(services.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.NoError(t, err)
+	code, err := totp.GenerateCode(user.TOTPSecret, time.Now())
+	assert.NoError(t, err)
+	_, err = service.VerifyTwoFactor(services.VerifyTwoFactorRequest{ChallengeToken: login.ChallengeToken, Code: code})
+	assert.NoError(t, err)
+
+	// A used challenge is rejected even if the code itself were accepted again
+	user.TOTPLastStep = 0
+	_, err = service.VerifyTwoFactor(services.VerifyTwoFactorRequest{ChallengeToken: login.ChallengeToken, Code: code})
+	assert.EqualError(t, err, "invalid or expired challenge")
+}
+
+func TestVerifyTwoFactor_CapsWrongCodes(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newTwoFactorAuthService(mockRepo)
+	user := newTwoFactorUser(t)
+
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil)
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	mockRepo.On("Update", mock.AnythingOfType("*models.User")).Return(nil)
+
+	code, err := totp.GenerateCode(user.TOTPSecret, time.Now())
+	assert.NoError(t, err)
+	login, err := service.Login(services.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.NoError(t, err)
+
+	// Five wrong codes discard the challenge, even for the right code
+	for i := 0; i < 5; i++ {
+		_, err = service.VerifyTwoFactor(services.VerifyTwoFactorRequest{ChallengeToken: login.ChallengeToken, Code: "000000"})
+		assert.EqualError(t, err, "invalid two-factor code")
+	}
+	_, err = service.VerifyTwoFactor(services.VerifyTwoFactorRequest{ChallengeToken: login.ChallengeToken, Code: code})
+	assert.EqualError(t, err, "invalid or expired challenge")
+
+	// Logging in again does not reset the per-user limit
+	login, err = service.Login(services.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err = service.VerifyTwoFactor(services.VerifyTwoFactorRequest{ChallengeToken: login.ChallengeToken, Code: "000000"})
+		assert.Error(t, err)
+	}
+	login, err = service.Login(services.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.NoError(t, err)
+	_, err = service.VerifyTwoFactor(services.VerifyTwoFactorRequest{ChallengeToken: login.ChallengeToken, Code: code})
+	assert.ErrorIs(t, err, services.ErrTooManyTwoFactorAttempts)
+}
+
+func TestVerifyTwoFactor_RejectsDeactivatedUser(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newTwoFactorAuthService(mockRepo)
+	user := newTwoFactorUser(t)
+
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil)
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	mockRepo.On("Update", mock.AnythingOfType("*models.User")).Return(nil)
+
+	login, err := service.Login(services.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.NoError(t, err)
+
+	deactivatedAt := time.Now()
+	user.DeactivatedAt = &deactivatedAt
+	code, err := totp.GenerateCode(user.TOTPSecret, time.Now())
+	assert.NoError(t, err)
+	_, err = service.VerifyTwoFactor(services.VerifyTwoFactorRequest{ChallengeToken: login.ChallengeToken, Code: code})
+	assert.ErrorIs(t, err, services.ErrAccountDeactivated)
+	assert.Zero(t, user.TOTPLastStep, "the code is not spent")
+}
+
+func TestVerifyTwoFactor_RecoveryCodeSingleUse(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newTwoFactorAuthService(mockRepo)
+	user := newTwoFactorUser(t)
+	user.TOTPEnabled = false
+
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil)
+	mockRepo.On("Update", mock.AnythingOfType("*models.User")).Return(nil)
+
+	code, err := totp.GenerateCode(user.TOTPSecret, time.Now())
+	assert.NoError(t, err)
+	codes, err := service.ConfirmTwoFactor(user.ID, services.TwoFactorCodeRequest{Code: code})
+	assert.NoError(t, err)
+	assert.Len(t, codes.RecoveryCodes, 10)
+	assert.True(t, user.TOTPEnabled)
+
+	login, err := service.Login(services.LoginRequest{Email: user.Email, Password: "password123"})
+	assert.NoError(t, err)
+
+	request := services.VerifyTwoFactorRequest{
+		ChallengeToken: login.ChallengeToken,
+		Code:           codes.RecoveryCodes[0],
+	}
+	_, err = service.VerifyTwoFactor(request)
+	assert.NoError(t, err)
+
+	_, err = service.VerifyTwoFactor(request)
+	assert.Error(t, err)
+}
+
+func TestLogin_TemporaryPasswordWaitsForTwoFactor(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newTwoFactorAuthService(mockRepo)
+	user := newTwoFactorUser(t)
+	user.Password = "temporary-password"
+	user.HashPassword()
+	user.MustChangePassword = true
+
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil)
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	mockRepo.On("Update", mock.AnythingOfType("*models.User")).Return(nil)
+
+	// The password alone never changes the password
+	login, err := service.Login(services.LoginRequest{Email: user.Email, Password: "temporary-password", NewPassword: "attacker-password"})
+	assert.NoError(t, err)
+	assert.True(t, login.TwoFactorRequired)
+	assert.True(t, login.PasswordChangeRequired)
+	assert.True(t, user.MustChangePassword)
+	assert.True(t, user.CheckPassword("temporary-password"))
+
+	code, err := totp.GenerateCode(user.TOTPSecret, time.Now())
+	assert.NoError(t, err)
+	request := services.VerifyTwoFactorRequest{ChallengeToken: login.ChallengeToken, Code: code}
+	_, err = service.VerifyTwoFactor(request)
+	assert.ErrorIs(t, err, services.ErrPasswordChangeRequired)
+
+	request.NewPassword = "chosen-password"
+	response, err := service.VerifyTwoFactor(request)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.Token)
+	assert.False(t, user.MustChangePassword)
+	assert.True(t, user.CheckPassword("chosen-password"))
+}
@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/mailer"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMailer records sent messages, or fails with err while it is set
+type fakeMailer struct {
+	sent []mailer.Message
+	err  error
+}
+
+func (m *fakeMailer) Send(ctx context.Context, msg mailer.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func newProfileUser() *models.User {
+	user := &models.User{
+		ID:       uuid.New(),
+		Email:    "old@example.com",
+		Name:     "Old Name",
+		Password: "password123",
+		Timezone: "UTC",
+		Locale:   "es",
+	}
+	user.HashPassword()
+	return user
+}
+
+func newUserService(repo *MockUserRepository, mail *fakeMailer) *services.UserService {
+	cfg := &config.Config{Server: config.ServerConfig{PublicURL: "https://api.taskflow.test"}}
+	return services.NewUserService(repo, mail, nil, cfg)
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestUpdateProfile_NameTimezoneLocale(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mail := &fakeMailer{}
+	service := newUserService(mockRepo, mail)
+	user := newProfileUser()
+
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	mockRepo.On("Update", user).Return(nil)
+
+	updated, err := service.UpdateProfile(context.Background(), user.ID, services.UpdateProfileRequest{
+		Name:     strPtr("New Name"),
+		Timezone: strPtr("America/Argentina/Buenos_Aires"),
+		Locale:   strPtr("en-US"),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "New Name", updated.Name)
+	assert.Equal(t, "America/Argentina/Buenos_Aires", updated.Timezone)
+	assert.Equal(t, "en-US", updated.Locale)
+	assert.Empty(t, mail.sent)
+}
+
+func TestUpdateProfile_RejectsInvalidTimezone(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newUserService(mockRepo, &fakeMailer{})
+	user := newProfileUser()
+
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+
+	_, err := service.UpdateProfile(context.Background(), user.ID, services.UpdateProfileRequest{
+		Timezone: strPtr("Mars/Olympus_Mons"),
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, "invalid timezone", err.Error())
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestUpdateProfile_PasswordChangeRequiresCurrentPassword(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newUserService(mockRepo, &fakeMailer{})
+	user := newProfileUser()
+
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+
+	_, err := service.UpdateProfile(context.Background(), user.ID, services.UpdateProfileRequest{
+		CurrentPassword: "wrong",
+		NewPassword:     strPtr("newpassword"),
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, "current password is incorrect", err.Error())
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestUpdateProfile_EmailChangeRequiresVerification(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mail := &fakeMailer{}
+	service := newUserService(mockRepo, mail)
+	user := newProfileUser()
+
+	mockRepo.On("FindByID", user.ID).Return(user, nil)
+	mockRepo.On("EmailExists", "new@example.com").Return(false, nil)
+	mockRepo.On("Update", user).Return(nil)
+
+	updated, err := service.UpdateProfile(context.Background(), user.ID, services.UpdateProfileRequest{
+		Email:           strPtr("new@example.com"),
+		CurrentPassword: "password123",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "old@example.com", updated.Email, "email only changes once verified")
+	assert.Equal(t, "new@example.com", updated.PendingEmail)
+	require.Len(t, mail.sent, 1)
+	assert.Equal(t, "new@example.com", mail.sent[0].To)
+
+	// Extract the token from the link sent to the new address
+	start := strings.Index(mail.sent[0].Text, "https://api.taskflow.test/api/v1/auth/verify-email?")
+	require.GreaterOrEqual(t, start, 0)
+	link, err := url.Parse(strings.Fields(mail.sent[0].Text[start:])[0])
+	require.NoError(t, err)
+	token := link.Query().Get("token")
+	require.NotEmpty(t, token)
+	assert.NotEqual(t, token, user.EmailVerificationTokenHash, "only the hash is stored")
+
+	mockRepo.On("FindByEmailVerificationToken", user.EmailVerificationTokenHash).Return(user, nil)
+
+	verified, err := service.VerifyEmail(token)
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", verified.Email)
+	assert.Empty(t, verified.PendingEmail)
+	assert.Empty(t, verified.EmailVerificationTokenHash)
+}
+
+func TestVerifyEmail_InvalidToken(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newUserService(mockRepo, &fakeMailer{})
+
+	mockRepo.On("FindByEmailVerificationToken", mock.Anything).Return(nil, nil)
+
+	_, err := service.VerifyEmail("bogus")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid or expired verification token", err.Error())
+}
+
+func TestListUsers_NormalizesPagination(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := newUserService(mockRepo, &fakeMailer{})
+
+	users := []models.User{{ID: uuid.New(), Name: "Ana", Email: "ana@example.com"}}
+	mockRepo.On("List", models.UserFilter{Query: "an", Page: 1, PageSize: 20}).Return(users, int64(1), nil)
+
+	filter := models.UserFilter{Query: "an", Page: 0, PageSize: 1000}
+	result, total, err := service.List(&filter)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, filter.Page, "the caller sees the page actually served")
+	assert.Equal(t, 20, filter.PageSize)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, result, 1)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,373 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWebhookRepository is an in-memory WebhookRepository
+type fakeWebhookRepository struct {
+	mu         sync.Mutex
+	webhooks   map[uuid.UUID]*models.Webhook
+	deliveries []*models.WebhookDelivery
+}
+
+func newFakeWebhookRepository() *fakeWebhookRepository {
+	return &fakeWebhookRepository{webhooks: make(map[uuid.UUID]*models.Webhook)}
+}
+
+func (r *fakeWebhookRepository) Create(webhook *models.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhook.ID = uuid.New()
+	stored := *webhook
+	r.webhooks[webhook.ID] = &stored
+	return nil
+}
+
+func (r *fakeWebhookRepository) FindByID(id uuid.UUID) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.webhooks[id], nil
+}
+
+func (r *fakeWebhookRepository) ListByUser(userID uuid.UUID) ([]models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []models.Webhook
+	for _, webhook := range r.webhooks {
+		if webhook.UserID == userID {
+			result = append(result, *webhook)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeWebhookRepository) ListActiveByUsers(userIDs []uuid.UUID) ([]models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []models.Webhook
+	for _, webhook := range r.webhooks {
+		for _, id := range userIDs {
+			if webhook.UserID == id && webhook.Active {
+				result = append(result, *webhook)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeWebhookRepository) SetActiveByUser(userID uuid.UUID, active bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, webhook := range r.webhooks {
+		if webhook.UserID == userID {
+			webhook.Active = active
+		}
+	}
+	return nil
+}
+
+func (r *fakeWebhookRepository) Delete(id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.webhooks, id)
+	return nil
+}
+
+func (r *fakeWebhookRepository) CreateDelivery(delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery.ID = uuid.New()
+	delivery.CreatedAt = time.Now()
+	stored := *delivery
+	r.deliveries = append(r.deliveries, &stored)
+	return nil
+}
+
+func (r *fakeWebhookRepository) FindDelivery(id uuid.UUID) (*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, delivery := range r.deliveries {
+		if delivery.ID == id {
+			copied := *delivery
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeWebhookRepository) ListDeliveries(webhookID uuid.UUID, limit int) ([]models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []models.WebhookDelivery
+	for i := len(r.deliveries) - 1; i >= 0 && len(result) < limit; i-- {
+		if r.deliveries[i].WebhookID == webhookID {
+			result = append(result, *r.deliveries[i])
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeWebhookRepository) ClaimDueDeliveries(now time.Time, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []models.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if len(result) == limit {
+			break
+		}
+		if delivery.Status != models.WebhookDeliveryPending || delivery.NextAttemptAt == nil || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		leaseUntil := now.Add(lease)
+		delivery.NextAttemptAt = &leaseUntil
+		claimed := *delivery
+		claimed.Webhook = r.webhooks[delivery.WebhookID]
+		result = append(result, claimed)
+	}
+	return result, nil
+}
+
+func (r *fakeWebhookRepository) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, stored := range r.deliveries {
+		if stored.ID == delivery.ID {
+			updated := *delivery
+			updated.Webhook = nil
+			r.deliveries[i] = &updated
+		}
+	}
+	return nil
+}
+
+// makeDue moves every pending delivery's next attempt to now
+func (r *fakeWebhookRepository) makeDue() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, delivery := range r.deliveries {
+		if delivery.Status == models.WebhookDeliveryPending {
+			delivery.NextAttemptAt = &now
+		}
+	}
+}
+
+var testWebhookConfig = config.WebhookConfig{
+	Timeout:        time.Second,
+	MaxAttempts:    3,
+	RetryBaseDelay: time.Minute,
+	PollInterval:   time.Second,
+	AllowPrivate:   true, // Receivers are httptest servers on loopback
+}
+
+// webhookReceiver records the requests it receives and answers with status
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func startWebhookReceiver(t *testing.T, status int) (*webhookReceiver, string) {
+	receiver := &webhookReceiver{status: status}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		receiver.requests = append(receiver.requests, r)
+		receiver.bodies = append(receiver.bodies, body)
+		status := receiver.status
+		receiver.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return receiver, server.URL
+}
+
+func TestWebhook_CreateValidatesInput(t *testing.T) {
+	service := services.NewWebhookService(newFakeWebhookRepository(), nil, testWebhookConfig)
+	user := uuid.New()
+
+	_, err := service.Create(user, services.CreateWebhookRequest{URL: "ftp://example.com/hook"})
+	assert.Error(t, err)
+	_, err = service.Create(user, services.CreateWebhookRequest{URL: "https://example.com/hook", EventTypes: []string{"archived"}})
+	assert.Error(t, err)
+	_, err = service.Create(user, services.CreateWebhookRequest{URL: "https://example.com/hook", Secret: "short"})
+	assert.Error(t, err)
+
+	created, err := service.Create(user, services.CreateWebhookRequest{URL: "https://example.com/hook"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.Secret, "a secret is generated when none is given")
+	assert.True(t, created.Active)
+}
+
+func TestWebhook_RefusesPrivateAddresses(t *testing.T) {
+	cfg := testWebhookConfig
+	cfg.AllowPrivate = false
+	repo := newFakeWebhookRepository()
+	service := services.NewWebhookService(repo, nil, cfg)
+	user := uuid.New()
+
+	for _, target := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://[::1]/hook",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.10/hook",
+		"http://169.254.169.254/latest/meta-data",
+	} {
+		_, err := service.Create(user, services.CreateWebhookRequest{URL: target})
+		assert.Error(t, err, target)
+	}
+	assert.Empty(t, repo.webhooks)
+
+	// A webhook saved before its host pointed to loopback is refused when
+	// connecting
+	receiver, url := startWebhookReceiver(t, http.StatusOK)
+	require.NoError(t, repo.Create(&models.Webhook{UserID: user, URL: url, Secret: "whsec_0123456789abcdef", Active: true}))
+	task := &models.Task{ID: uuid.New(), CreatedBy: user}
+	require.NoError(t, service.HandleTaskEvent(&models.OutboxEvent{ID: 1, Type: models.TaskEventCreated, TaskID: task.ID, Task: task, ActorID: user}))
+	require.NoError(t, service.DeliverDue(context.Background()))
+	assert.Empty(t, receiver.requests)
+	require.Len(t, repo.deliveries, 1)
+	assert.Contains(t, repo.deliveries[0].Error, "non-public address")
+}
+
+func TestWebhook_DoesNotFollowRedirects(t *testing.T) {
+	repo := newFakeWebhookRepository()
+	service := services.NewWebhookService(repo, nil, testWebhookConfig)
+	receiver, target := startWebhookReceiver(t, http.StatusOK)
+	redirect := httptest.NewServer(http.RedirectHandler(target, http.StatusFound))
+	t.Cleanup(redirect.Close)
+
+	user := uuid.New()
+	_, err := service.Create(user, services.CreateWebhookRequest{URL: redirect.URL})
+	require.NoError(t, err)
+	task := &models.Task{ID: uuid.New(), CreatedBy: user}
+	require.NoError(t, service.HandleTaskEvent(&models.OutboxEvent{ID: 1, Type: models.TaskEventCreated, TaskID: task.ID, Task: task, ActorID: user}))
+	require.NoError(t, service.DeliverDue(context.Background()))
+
+	assert.Empty(t, receiver.requests)
+	require.Len(t, repo.deliveries, 1)
+	assert.Equal(t, http.StatusFound, repo.deliveries[0].ResponseStatus)
+	assert.NotEqual(t, models.WebhookDeliverySucceeded, repo.deliveries[0].Status)
+}
+
+func TestWebhook_DeliversSignedEventsToInvolvedUsers(t *testing.T) {
+	repo := newFakeWebhookRepository()
+	service := services.NewWebhookService(repo, nil, testWebhookConfig)
+	receiver, url := startWebhookReceiver(t, http.StatusOK)
+
+	creator, assignee, stranger := uuid.New(), uuid.New(), uuid.New()
+	hook, err := service.Create(assignee, services.CreateWebhookRequest{URL: url, EventTypes: []string{models.TaskEventAssigned}})
+	require.NoError(t, err)
+	_, err = service.Create(stranger, services.CreateWebhookRequest{URL: url})
+	require.NoError(t, err)
+
+	task := &models.Task{ID: uuid.New(), CreatedBy: creator, AssignedTo: &assignee}
+	require.NoError(t, service.HandleTaskEvent(&models.OutboxEvent{ID: 1, Type: models.TaskEventUpdated, TaskID: task.ID, Task: task, ActorID: creator}))
+	require.NoError(t, service.HandleTaskEvent(&models.OutboxEvent{ID: 2, Type: models.TaskEventAssigned, TaskID: task.ID, Task: task, ActorID: creator}))
+	require.NoError(t, service.DeliverDue(context.Background()))
+
+	// Only the assignee's webhook follows the task, and only assignments
+	require.Len(t, receiver.requests, 1)
+	req, body := receiver.requests[0], receiver.bodies[0]
+	assert.Equal(t, models.TaskEventAssigned, req.Header.Get(services.WebhookEventHeader))
+	timestamp, err := strconv.ParseInt(req.Header.Get(services.WebhookTimestampHeader), 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, services.SignWebhookPayload(hook.Secret, timestamp, body), req.Header.Get(services.WebhookSignatureHeader))
+
+	var payload services.WebhookPayload
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, task.ID, payload.TaskID)
+	assert.Equal(t, models.TaskEventAssigned, payload.Type)
+	assert.Equal(t, uint64(2), payload.EventID)
+
+	deliveries, err := service.Deliveries(assignee, hook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.WebhookDeliverySucceeded, deliveries[0].Status)
+	assert.Equal(t, http.StatusOK, deliveries[0].ResponseStatus)
+}
+
+func TestWebhook_RetriesWithBackoffThenFails(t *testing.T) {
+	repo := newFakeWebhookRepository()
+	service := services.NewWebhookService(repo, nil, testWebhookConfig)
+	receiver, url := startWebhookReceiver(t, http.StatusInternalServerError)
+
+	user := uuid.New()
+	hook, err := service.Create(user, services.CreateWebhookRequest{URL: url})
+	require.NoError(t, err)
+	task := &models.Task{ID: uuid.New(), CreatedBy: user}
+	require.NoError(t, service.HandleTaskEvent(&models.OutboxEvent{ID: 1, Type: models.TaskEventCreated, TaskID: task.ID, Task: task, ActorID: user}))
+
+	before := time.Now()
+	require.NoError(t, service.DeliverDue(context.Background()))
+	deliveries, _ := service.Deliveries(user, hook.ID)
+	require.Len(t, deliveries, 1)
+	first := deliveries[0]
+	assert.Equal(t, models.WebhookDeliveryPending, first.Status)
+	assert.Equal(t, 1, first.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, first.ResponseStatus)
+	require.NotNil(t, first.NextAttemptAt)
+	assert.WithinDuration(t, before.Add(time.Minute), *first.NextAttemptAt, 5*time.Second)
+
+	// Not due yet
+	require.NoError(t, service.DeliverDue(context.Background()))
+	assert.Len(t, receiver.requests, 1)
+
+	repo.makeDue()
+	require.NoError(t, service.DeliverDue(context.Background()))
+	deliveries, _ = service.Deliveries(user, hook.ID)
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), *deliveries[0].NextAttemptAt, 5*time.Second, "the delay doubles")
+
+	repo.makeDue()
+	require.NoError(t, service.DeliverDue(context.Background()))
+	deliveries, _ = service.Deliveries(user, hook.ID)
+	assert.Equal(t, models.WebhookDeliveryFailed, deliveries[0].Status)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Nil(t, deliveries[0].NextAttemptAt)
+	assert.Len(t, receiver.requests, 3)
+}
+
+func TestWebhook_RedeliverSendsSamePayload(t *testing.T) {
+	repo := newFakeWebhookRepository()
+	service := services.NewWebhookService(repo, nil, testWebhookConfig)
+	receiver, url := startWebhookReceiver(t, http.StatusOK)
+
+	user := uuid.New()
+	hook, err := service.Create(user, services.CreateWebhookRequest{URL: url})
+	require.NoError(t, err)
+	task := &models.Task{ID: uuid.New(), CreatedBy: user}
+	require.NoError(t, service.HandleTaskEvent(&models.OutboxEvent{ID: 1, Type: models.TaskEventDeleted, TaskID: task.ID, ActorID: user, Previous: task}))
+	require.NoError(t, service.DeliverDue(context.Background()))
+	deliveries, _ := service.Deliveries(user, hook.ID)
+	require.Len(t, deliveries, 1)
+
+	_, err = service.Redeliver(uuid.New(), hook.ID, deliveries[0].ID)
+	assert.ErrorIs(t, err, services.ErrWebhookNotFound, "other users cannot redeliver")
+
+	redelivery, err := service.Redeliver(user, hook.ID, deliveries[0].ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, deliveries[0].ID, redelivery.ID)
+	require.NoError(t, service.DeliverDue(context.Background()))
+
+	require.Len(t, receiver.requests, 2)
+	assert.Equal(t, receiver.bodies[0], receiver.bodies[1])
+	assert.Equal(t, redelivery.ID.String(), receiver.requests[1].Header.Get(services.WebhookDeliveryHeader))
+}
@@ -0,0 +1,543 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/config"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/eventbus"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/handlers"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/models"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/services"
+	"github.com/IgnacioIbaigorria/taskflow/backend/internal/websocket"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	gorilla "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTaskAuthorizer allows users to view the tasks listed for them
+type fakeTaskAuthorizer map[uuid.UUID][]uuid.UUID
+
+func (a fakeTaskAuthorizer) CanViewTask(userID, taskID uuid.UUID) (bool, error) {
+	for _, id := range a[userID] {
+		if id == taskID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Collaborators lists the users who may view one of the user's tasks
+func (a fakeTaskAuthorizer) Collaborators(userID uuid.UUID) ([]uuid.UUID, error) {
+	var result []uuid.UUID
+	for other, tasks := range a {
+		if other == userID {
+			continue
+		}
+		for _, taskID := range tasks {
+			if shared, _ := a.CanViewTask(userID, taskID); shared {
+				result = append(result, other)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// registerClient adds a client without a network connection to a running hub
+func registerClient(hub *websocket.Hub, userID uuid.UUID) *websocket.Client {
+	client := websocket.NewClient(hub, userID, nil)
+	hub.Register <- client
+	return client
+}
+
+// receive waits briefly for a message queued for the client and decodes it
+// into v
+func receive(client *websocket.Client, v interface{}) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	message, err := client.Next(ctx)
+	return err == nil && json.Unmarshal(message, v) == nil
+}
+
+// subscribe sends a protocol message and returns the server reply
+func subscribe(t *testing.T, client *websocket.Client, action, channel string) websocket.ServerReply {
+	data, _ := json.Marshal(websocket.ClientMessage{ID: "1", Action: action, Channel: channel})
+	client.HandleMessage(data)
+
+	var reply websocket.ServerReply
+	require.True(t, receive(client, &reply))
+	return reply
+}
+
+func TestHub_TaskEventsOnlyReachInvolvedUsers(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{}, nil, nil)
+	go hub.Run()
+
+	creator, assignee, outsider := uuid.New(), uuid.New(), uuid.New()
+	creatorPhone := registerClient(hub, creator)
+	creatorWeb := registerClient(hub, creator)
+	assigneeClient := registerClient(hub, assignee)
+	outsiderClient := registerClient(hub, outsider)
+
+	task := &models.Task{ID: uuid.New(), Title: "Private", CreatedBy: creator, AssignedTo: &assignee}
+	hub.BroadcastTaskEvent(models.TaskEvent{Type: "updated", TaskID: task.ID, Task: task, UserID: creator}, nil)
+
+	for _, client := range []*websocket.Client{creatorPhone, creatorWeb, assigneeClient} {
+		var event models.TaskEvent
+		require.True(t, receive(client, &event))
+		assert.Equal(t, task.ID, event.TaskID)
+	}
+	assert.False(t, receive(outsiderClient, &models.TaskEvent{}))
+}
+
+func TestHub_DeletedTaskReachesPreviousAudience(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{}, nil, nil)
+	go hub.Run()
+
+	creator, assignee, outsider := uuid.New(), uuid.New(), uuid.New()
+	assigneeClient := registerClient(hub, assignee)
+	outsiderClient := registerClient(hub, outsider)
+
+	task := &models.Task{ID: uuid.New(), CreatedBy: creator, AssignedTo: &assignee}
+	hub.BroadcastTaskEvent(models.TaskEvent{Type: "deleted", TaskID: task.ID, UserID: creator}, task)
+
+	var event models.TaskEvent
+	require.True(t, receive(assigneeClient, &event))
+	assert.Equal(t, "deleted", event.Type)
+	assert.False(t, receive(outsiderClient, &models.TaskEvent{}))
+}
+
+func TestHub_SubscriptionsFilterEvents(t *testing.T) {
+	user := uuid.New()
+	followed := &models.Task{ID: uuid.New(), CreatedBy: user}
+	other := &models.Task{ID: uuid.New(), CreatedBy: user}
+	hub := websocket.NewHub(config.WebSocketConfig{}, fakeTaskAuthorizer{user: {followed.ID, other.ID}}, nil)
+	go hub.Run()
+
+	client := registerClient(hub, user)
+	reply := subscribe(t, client, websocket.ActionSubscribe, websocket.TaskChannel(followed.ID))
+	assert.Equal(t, websocket.MessageTypeAck, reply.Type)
+	assert.Equal(t, "1", reply.ID)
+
+	hub.BroadcastTaskEvent(models.TaskEvent{Type: "updated", TaskID: other.ID, Task: other, UserID: user}, nil)
+	hub.BroadcastTaskEvent(models.TaskEvent{Type: "updated", TaskID: followed.ID, Task: followed, UserID: user}, nil)
+
+	var event models.TaskEvent
+	require.True(t, receive(client, &event))
+	assert.Equal(t, followed.ID, event.TaskID)
+	assert.False(t, receive(client, &event))
+}
+
+func TestHub_SubscribeRequiresAccess(t *testing.T) {
+	user := uuid.New()
+	hub := websocket.NewHub(config.WebSocketConfig{}, fakeTaskAuthorizer{}, nil)
+	go hub.Run()
+	client := registerClient(hub, user)
+
+	reply := subscribe(t, client, websocket.ActionSubscribe, websocket.TaskChannel(uuid.New()))
+	assert.Equal(t, websocket.MessageTypeError, reply.Type)
+	assert.Equal(t, "forbidden", reply.Error)
+
+	reply = subscribe(t, client, websocket.ActionSubscribe, "project:"+uuid.NewString())
+	assert.Equal(t, websocket.MessageTypeError, reply.Type)
+
+	reply = subscribe(t, client, websocket.ActionSubscribe, websocket.ChannelAssignments)
+	assert.Equal(t, websocket.MessageTypeAck, reply.Type)
+}
+
+// startWebSocketServer serves the task WebSocket endpoint for a fixed user
+func startWebSocketServer(t *testing.T, hub *websocket.Hub, userID uuid.UUID) string {
+	gin.SetMode(gin.TestMode)
+	handler := handlers.NewTaskHandler(services.NewTaskService(nil, nil), hub)
+	router := gin.New()
+	router.GET("/ws", func(c *gin.Context) { c.Set("user_id", userID) }, handler.WebSocket)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+}
+
+func TestHub_DropsClientsThatStopAnsweringPings(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{
+		PingInterval: 50 * time.Millisecond,
+		PongTimeout:  200 * time.Millisecond,
+		WriteTimeout: time.Second,
+	}, nil, nil)
+	go hub.Run()
+	url := startWebSocketServer(t, hub, uuid.New())
+
+	// A client that keeps reading answers pings automatically
+	alive, _, err := gorilla.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer alive.Close()
+	go func() {
+		for {
+			if _, _, err := alive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// A client that never reads never sends pongs
+	dead, _, err := gorilla.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer dead.Close()
+
+	assert.Eventually(t, func() bool { return hub.ClientCount() == 2 }, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return hub.ClientCount() == 1 }, 2*time.Second, 20*time.Millisecond)
+
+	// The live client outlasts several pong timeouts
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(t, 1, hub.ClientCount())
+}
+
+func TestHub_OversizedMessageClosesConnection(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{PongTimeout: time.Minute, MaxMessageBytes: 64}, nil, nil)
+	go hub.Run()
+	conn, _, err := gorilla.DefaultDialer.Dial(startWebSocketServer(t, hub, uuid.New()), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Eventually(t, func() bool { return hub.ClientCount() == 1 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, conn.WriteMessage(gorilla.TextMessage, []byte(strings.Repeat("x", 1024))))
+
+	assert.Eventually(t, func() bool { return hub.ClientCount() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestHub_ReplaysMissedEventsOnReconnect(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{BacklogSize: 10}, nil, nil)
+	go hub.Run()
+	user := uuid.New()
+	task := &models.Task{ID: uuid.New(), CreatedBy: user}
+
+	client := registerClient(hub, user)
+	hub.BroadcastTaskEvent(models.TaskEvent{ID: 1, Type: "created", TaskID: task.ID, Task: task, UserID: user}, nil)
+	var seen models.TaskEvent
+	require.True(t, receive(client, &seen))
+	hub.Unregister <- client
+
+	// Another device stays online, so the events are known to be delivered
+	other := registerClient(hub, user)
+	hub.BroadcastTaskEvent(models.TaskEvent{ID: 2, Type: "updated", TaskID: task.ID, Task: task, UserID: user}, nil)
+	hub.BroadcastTaskEvent(models.TaskEvent{ID: 3, Type: "deleted", TaskID: task.ID, UserID: user}, task)
+	require.True(t, receive(other, &models.TaskEvent{}))
+	require.True(t, receive(other, &models.TaskEvent{}))
+
+	reconnected := websocket.NewClient(hub, user, nil)
+	reconnected.LastEventID = &seen.ID
+	hub.Register <- reconnected
+
+	var first, second models.TaskEvent
+	require.True(t, receive(reconnected, &first))
+	require.True(t, receive(reconnected, &second))
+	assert.Equal(t, "updated", first.Type)
+	assert.Equal(t, "deleted", second.Type)
+	assert.Greater(t, first.ID, seen.ID)
+	assert.Greater(t, second.ID, first.ID)
+	assert.False(t, receive(reconnected, &models.TaskEvent{}))
+}
+
+func TestHub_ResyncRequiredWhenGapTooLarge(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{BacklogSize: 2}, nil, nil)
+	go hub.Run()
+	user := uuid.New()
+	task := &models.Task{ID: uuid.New(), CreatedBy: user}
+
+	client := registerClient(hub, user)
+	hub.BroadcastTaskEvent(models.TaskEvent{ID: 1, Type: "created", TaskID: task.ID, Task: task, UserID: user}, nil)
+	var seen models.TaskEvent
+	require.True(t, receive(client, &seen))
+	hub.Unregister <- client
+
+	other := registerClient(hub, user)
+	for i := 0; i < 3; i++ {
+		hub.BroadcastTaskEvent(models.TaskEvent{ID: uint64(i + 2), Type: "updated", TaskID: task.ID, Task: task, UserID: user}, nil)
+		require.True(t, receive(other, &models.TaskEvent{}))
+	}
+
+	// An evicted event, and one this instance has not received
+	for _, lastID := range []uint64{seen.ID, 99} {
+		lastID := lastID
+		reconnected := websocket.NewClient(hub, user, nil)
+		reconnected.LastEventID = &lastID
+		hub.Register <- reconnected
+
+		var reply websocket.ServerReply
+		require.True(t, receive(reconnected, &reply))
+		assert.Equal(t, websocket.MessageTypeResyncRequired, reply.Type)
+		assert.False(t, receive(reconnected, &reply))
+		hub.Unregister <- reconnected
+	}
+}
+
+func TestHub_DropsIdleBacklogs(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{BacklogSize: 10, BacklogTTL: 20 * time.Millisecond}, nil, nil)
+	go hub.Run()
+	user := uuid.New()
+	task := &models.Task{ID: uuid.New(), CreatedBy: user}
+
+	observer := registerClient(hub, user)
+	for _, id := range []uint64{1, 2} {
+		hub.BroadcastTaskEvent(models.TaskEvent{ID: id, Type: "updated", TaskID: task.ID, Task: task, UserID: user}, nil)
+		require.True(t, receive(observer, &models.TaskEvent{}))
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// Event 2 was dropped with the backlog, so it cannot be replayed
+	lastID := uint64(1)
+	reconnected := websocket.NewClient(hub, user, nil)
+	reconnected.LastEventID = &lastID
+	hub.Register <- reconnected
+	var reply websocket.ServerReply
+	require.True(t, receive(reconnected, &reply))
+	assert.Equal(t, websocket.MessageTypeResyncRequired, reply.Type)
+}
+
+func TestHub_EventsReachClientsOnOtherInstances(t *testing.T) {
+	// Two hubs sharing a bus stand in for two backend replicas
+	bus := eventbus.NewLocalBus()
+	instanceA := websocket.NewHub(config.WebSocketConfig{}, nil, bus)
+	instanceB := websocket.NewHub(config.WebSocketConfig{}, nil, bus)
+	go instanceA.Run()
+	go instanceB.Run()
+
+	creator, assignee := uuid.New(), uuid.New()
+	creatorClient := registerClient(instanceA, creator)
+	assigneeClient := registerClient(instanceB, assignee)
+
+	task := &models.Task{ID: uuid.New(), CreatedBy: creator, AssignedTo: &assignee}
+	instanceA.BroadcastTaskEvent(models.TaskEvent{Type: "assigned", TaskID: task.ID, Task: task, UserID: creator}, nil)
+
+	for _, client := range []*websocket.Client{creatorClient, assigneeClient} {
+		var event models.TaskEvent
+		require.True(t, receive(client, &event))
+		assert.Equal(t, "assigned", event.Type)
+	}
+	assert.False(t, receive(assigneeClient, &models.TaskEvent{}))
+}
+
+func TestHub_InstancesGiveEventsTheSameID(t *testing.T) {
+	bus := eventbus.NewLocalBus()
+	instanceA := websocket.NewHub(config.WebSocketConfig{BacklogSize: 10}, nil, bus)
+	instanceB := websocket.NewHub(config.WebSocketConfig{BacklogSize: 10}, nil, bus)
+	go instanceA.Run()
+	go instanceB.Run()
+
+	user := uuid.New()
+	onA, onB := registerClient(instanceA, user), registerClient(instanceB, user)
+	task := &models.Task{ID: uuid.New(), CreatedBy: user}
+	for _, sent := range []models.TaskEvent{{ID: 41, Type: "created"}, {ID: 42, Type: "updated"}} {
+		sent.TaskID, sent.Task, sent.UserID = task.ID, task, user
+		instanceA.BroadcastTaskEvent(sent, nil)
+		var fromA, fromB models.TaskEvent
+		require.True(t, receive(onA, &fromA))
+		require.True(t, receive(onB, &fromB))
+		assert.Equal(t, sent.ID, fromA.ID)
+		assert.Equal(t, fromA.ID, fromB.ID)
+	}
+
+	// An event published again after a failed dispatch is not delivered twice
+	instanceB.BroadcastTaskEvent(models.TaskEvent{ID: 42, Type: "updated", TaskID: task.ID, Task: task, UserID: user}, nil)
+	assert.False(t, receive(onA, &models.TaskEvent{}))
+	assert.False(t, receive(onB, &models.TaskEvent{}))
+
+	// A client that saw event 41 on instance A resumes on instance B
+	lastID := uint64(41)
+	reconnected := websocket.NewClient(instanceB, user, nil)
+	reconnected.LastEventID = &lastID
+	instanceB.Register <- reconnected
+	var missed models.TaskEvent
+	require.True(t, receive(reconnected, &missed))
+	assert.Equal(t, uint64(42), missed.ID)
+	assert.Equal(t, "updated", missed.Type)
+}
+
+// viewTask reports the task a client is viewing and consumes the ack and the
+// client's own viewing event, which may arrive in either order
+func viewTask(t *testing.T, client *websocket.Client, taskID uuid.UUID) {
+	data, _ := json.Marshal(websocket.ClientMessage{Action: websocket.ActionView, Channel: websocket.TaskChannel(taskID)})
+	client.HandleMessage(data)
+
+	var types []string
+	for i := 0; i < 2; i++ {
+		var reply websocket.ServerReply
+		require.True(t, receive(client, &reply))
+		types = append(types, reply.Type)
+	}
+	assert.ElementsMatch(t, []string{websocket.MessageTypeAck, websocket.MessageTypeTaskViewing}, types)
+}
+
+func TestHub_PresenceTracksViewers(t *testing.T) {
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+	task := uuid.New()
+	hub := websocket.NewHub(config.WebSocketConfig{}, fakeTaskAuthorizer{alice: {task}, bob: {task}, carol: {uuid.New()}}, nil)
+	go hub.Run()
+
+	aliceClient := registerClient(hub, alice)
+	assert.Equal(t, websocket.MessageTypeAck, subscribe(t, aliceClient, websocket.ActionSubscribe, websocket.ChannelPresence).Type)
+
+	// Carol shares no task with Alice, so Alice does not hear about her
+	registerClient(hub, carol)
+	bobClient := registerClient(hub, bob)
+	var presence websocket.PresenceEvent
+	require.True(t, receive(aliceClient, &presence))
+	assert.Equal(t, websocket.MessageTypeUserOnline, presence.Type)
+	assert.Equal(t, bob, presence.UserID)
+
+	// Both open the task; Alice learns that Bob is there too
+	viewTask(t, aliceClient, task)
+	viewTask(t, bobClient, task)
+	require.True(t, receive(aliceClient, &presence))
+	assert.Equal(t, websocket.MessageTypeTaskViewing, presence.Type)
+	assert.Equal(t, bob, presence.UserID)
+
+	snapshot := hub.Presence(alice)
+	assert.ElementsMatch(t, []uuid.UUID{alice, bob}, snapshot.Online)
+	assert.False(t, snapshot.Partial, "a hub without a shared bus sees every client")
+	assert.Equal(t, []uuid.UUID{carol}, hub.Presence(carol).Online)
+	assert.ElementsMatch(t, []uuid.UUID{alice, bob}, snapshot.Viewers[task])
+	assert.Empty(t, hub.Presence(uuid.New()).Viewers, "viewers are hidden from users who cannot see the task")
+
+	// Bob disconnects: he leaves the task and goes offline
+	hub.Unregister <- bobClient
+	require.True(t, receive(aliceClient, &presence))
+	assert.Equal(t, websocket.MessageTypeTaskLeft, presence.Type)
+	require.True(t, receive(aliceClient, &presence))
+	assert.Equal(t, websocket.MessageTypeUserOffline, presence.Type)
+	assert.Equal(t, []uuid.UUID{alice}, hub.Presence(alice).Viewers[task])
+}
+
+func TestHub_ViewingRequiresAccess(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{}, fakeTaskAuthorizer{}, nil)
+	go hub.Run()
+	client := registerClient(hub, uuid.New())
+
+	reply := subscribe(t, client, websocket.ActionView, websocket.TaskChannel(uuid.New()))
+
+	assert.Equal(t, websocket.MessageTypeError, reply.Type)
+	assert.Empty(t, hub.Presence(client.UserID).Viewers)
+}
+
+func TestHub_CoalescesPendingUpdates(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{}, nil, nil)
+	go hub.Run()
+	user := uuid.New()
+	client := registerClient(hub, user)
+
+	task := &models.Task{ID: uuid.New(), CreatedBy: user}
+	other := &models.Task{ID: uuid.New(), CreatedBy: user}
+	for i, title := range []string{"v1", "v2", "v3"} {
+		updated := *task
+		updated.Title = title
+		hub.BroadcastTaskEvent(models.TaskEvent{Type: "updated", TaskID: task.ID, Task: &updated, UserID: user}, nil)
+		if i == 0 {
+			hub.BroadcastTaskEvent(models.TaskEvent{Type: "created", TaskID: other.ID, Task: other, UserID: user}, nil)
+		}
+	}
+	require.Eventually(t, func() bool { return hub.Metrics().MessagesCoalesced == 2 }, time.Second, 10*time.Millisecond)
+
+	// Only the latest update is left, after the event it was queued behind
+	var first, second models.TaskEvent
+	require.True(t, receive(client, &first))
+	require.True(t, receive(client, &second))
+	assert.Equal(t, "created", first.Type)
+	assert.Equal(t, "updated", second.Type)
+	assert.Equal(t, "v3", second.Task.Title)
+	assert.False(t, receive(client, &models.TaskEvent{}))
+}
+
+func TestHub_DisconnectsSlowClientWithReason(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{QueueSize: 2}, nil, nil)
+	go hub.Run()
+	user := uuid.New()
+	slow := registerClient(hub, user)
+
+	for i := 0; i < 3; i++ {
+		task := &models.Task{ID: uuid.New(), CreatedBy: user}
+		hub.BroadcastTaskEvent(models.TaskEvent{Type: "created", TaskID: task.ID, Task: task, UserID: user}, nil)
+	}
+	require.Eventually(t, func() bool { return hub.ClientCount() == 0 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, uint64(1), hub.Metrics().SlowClientsDropped)
+
+	// Queued events are still delivered before the close
+	for i := 0; i < 2; i++ {
+		require.True(t, receive(slow, &models.TaskEvent{}))
+	}
+	_, err := slow.Next(context.Background())
+	assert.ErrorIs(t, err, websocket.ErrClientClosed)
+	code, reason := slow.CloseReason()
+	assert.Equal(t, gorilla.CloseTryAgainLater, code)
+	assert.Equal(t, websocket.CloseReasonTooSlow, reason)
+}
+
+func TestHub_DisconnectUserSendsCloseReason(t *testing.T) {
+	hub := websocket.NewHub(config.WebSocketConfig{PongTimeout: time.Minute}, nil, nil)
+	go hub.Run()
+	user := uuid.New()
+	conn, _, err := gorilla.DefaultDialer.Dial(startWebSocketServer(t, hub, user), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Eventually(t, func() bool { return hub.ClientCount() == 1 }, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, 1, hub.DisconnectUser(user))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	var closeErr *gorilla.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, gorilla.ClosePolicyViolation, closeErr.Code)
+	assert.Equal(t, websocket.CloseReasonSessionRevoked, closeErr.Text)
+}
+
+// Run with -race: events, protocol messages and connection churn hit the hub
+// from many goroutines while clients drain their queues
+func TestHub_ConcurrentDeliveryIsRaceFree(t *testing.T) {
+	user := uuid.New()
+	task := &models.Task{ID: uuid.New(), CreatedBy: user}
+	hub := websocket.NewHub(config.WebSocketConfig{QueueSize: 8, BacklogSize: 10}, fakeTaskAuthorizer{user: {task.ID}}, nil)
+	go hub.Run()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		client := registerClient(hub, user)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for {
+				if _, err := client.Next(ctx); err != nil {
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				updated := *task
+				hub.BroadcastTaskEvent(models.TaskEvent{Type: "updated", TaskID: task.ID, Task: &updated, UserID: user}, nil)
+				data, _ := json.Marshal(websocket.ClientMessage{Action: websocket.ActionView, Channel: websocket.TaskChannel(task.ID)})
+				client.HandleMessage(data)
+				hub.Presence(user)
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		churn := registerClient(hub, user)
+		hub.Metrics()
+		hub.Unregister <- churn
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	hub.DisconnectUser(user)
+	wg.Wait()
+	assert.Equal(t, 0, hub.ClientCount())
+}
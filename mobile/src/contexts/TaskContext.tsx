@@ -102,7 +102,14 @@ export function TaskProvider({ children }: { children: ReactNode }) {
     useEffect(() => {
         if (isAuthenticated) {
             loadTasks();
-            const unsubscribe = websocketService.subscribe(handleTaskEvent);
+            const unsubscribe = websocketService.subscribe((event) => {
+                if (event.type === 'resync_required') {
+                    // Missed events are no longer available, reload everything
+                    loadTasks();
+                    return;
+                }
+                handleTaskEvent(event);
+            });
 
             return () => unsubscribe();
         } else {
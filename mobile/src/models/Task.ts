@@ -56,7 +56,8 @@ export interface TaskListResponse {
 }
 
 export interface TaskEvent {
-    type: 'created' | 'updated' | 'deleted' | 'assigned';
+    id?: number;
+    type: 'created' | 'updated' | 'deleted' | 'assigned' | 'resync_required';
     task_id: string;
     task?: Task;
     user_id: string;
@@ -2,18 +2,30 @@ import { api } from './api';
 
 import { storageService } from './storageService';
 
+export interface AvatarUrls {
+    small: string;
+    medium: string;
+    large: string;
+}
+
 export interface User {
     id: string;
     name: string;
     email: string;
+    avatar?: AvatarUrls | null;
 }
 
 export const userService = {
-    getUsers: async (): Promise<User[]> => {
+    getUsers: async (query?: string): Promise<User[]> => {
         try {
-            const response = await api.get('/users');
-            await storageService.saveUsers(response.data);
-            return response.data;
+            const response = await api.get('/users', {
+                params: { q: query || undefined, page_size: 100 },
+            });
+            const users: User[] = response.data.users;
+            if (!query) {
+                await storageService.saveUsers(users);
+            }
+            return users;
         } catch (error) {
             const cachedUsers = await storageService.getUsers();
             if (cachedUsers && cachedUsers.length > 0) {
@@ -12,6 +12,7 @@ class WebSocketService {
     private reconnectAttempts = 0;
     private maxReconnectAttempts = 5;
     private reconnectTimer: NodeJS.Timeout | null = null;
+    private lastEventId: number | null = null;
 
     async connect(): Promise<void> {
         if (this.socket?.readyState === WebSocket.OPEN) {
@@ -31,7 +32,11 @@ class WebSocketService {
 
             // Convert http:// or https:// to ws:// or wss://
             const wsProtocol = WS_URL.replace('http://', 'ws://').replace('https://', 'wss://');
-            const wsUrl = `${wsProtocol}/api/v1/ws?token=${token}`;
+            let wsUrl = `${wsProtocol}/api/v1/ws?token=${token}`;
+            // Ask the server to replay the events missed while disconnected
+            if (this.lastEventId !== null) {
+                wsUrl += `&last_event_id=${this.lastEventId}`;
+            }
 
             this.socket = new WebSocket(wsUrl);
 
@@ -44,12 +49,20 @@ class WebSocketService {
             };
 
             this.socket.onclose = (event) => {
+                // 1008: the session was revoked, reconnecting would be refused.
+                // 1013 (client too slow) reconnects and replays what was missed.
+                if (event.code === 1008) {
+                    return;
+                }
                 this.attemptReconnect();
             };
 
             this.socket.onmessage = (event) => {
                 try {
                     const taskEvent: TaskEvent = JSON.parse(event.data);
+                    if (taskEvent.id) {
+                        this.lastEventId = taskEvent.id;
+                    }
                     this.notifyListeners(taskEvent);
                 } catch (error) {
                     // Error parsing WebSocket message
@@ -94,6 +107,7 @@ class WebSocketService {
             this.socket = null;
         }
         this.reconnectAttempts = 0;
+        this.lastEventId = null;
     }
 
     subscribe(callback: EventCallback): () => void {